@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -113,7 +114,7 @@ type recipient struct {
 }
 
 func (w *worker) processBatch(ctx context.Context) {
-	rows, err := sq.Select("id", "type", "request_id").
+	rows, err := sq.Select("id", "type", "COALESCE(request_id, '')").
 		From("email_logs").
 		Where(sq.And{sq.Eq{"status": "created"}, sq.Eq{"sent_at": nil}}).
 		OrderBy("created_at ASC").
@@ -145,7 +146,18 @@ func (w *worker) processBatch(ctx context.Context) {
 }
 
 func (w *worker) processOne(ctx context.Context, el emailLog) {
-	recipients, err := w.fetchRecipients(ctx, el.requestID, el.emailType)
+	var recipients []recipient
+	var err error
+	switch {
+	case el.emailType == "saved_search_match":
+		recipients, err = w.fetchSavedSearchRecipients(ctx, el.id)
+	case applicantEmails[el.emailType]:
+		recipients, err = w.fetchApplicantRecipients(ctx, el.requestID)
+	case el.emailType == "passenger_left":
+		recipients, err = w.fetchDriverRecipients(ctx, el.requestID)
+	default:
+		recipients, err = w.fetchRecipients(ctx, el.requestID, el.emailType)
+	}
 	if err != nil {
 		w.log.Error("fetch recipients", slog.String("email_log_id", el.id), slog.Any("error", err))
 		return
@@ -156,6 +168,16 @@ func (w *worker) processOne(ctx context.Context, el emailLog) {
 	}
 
 	subject, body := emailContent(el.emailType)
+	if el.emailType == "passenger_left" || el.emailType == "passenger_removed" {
+		reason, err := w.fetchLeaveReason(ctx, el.requestID)
+		if err != nil {
+			w.log.Error("fetch leave reason", slog.String("email_log_id", el.id), slog.Any("error", err))
+			return
+		}
+		if reason != "" {
+			body += "\n\nPriežastis: " + reason
+		}
+	}
 
 	var messages mailjet.MessagesV31
 	for _, r := range recipients {
@@ -212,6 +234,86 @@ func (w *worker) fetchRecipients(ctx context.Context, requestID, emailType strin
 	return out, rows.Err()
 }
 
+// applicantEmails are the types that only concern the applicant whose request
+// queued them, not everyone on the route.
+var applicantEmails = map[string]bool{
+	"waitlist_promoted":   true,
+	"application_expired": true,
+	"stop_change_expired": true,
+	"passenger_removed":   true,
+}
+
+// fetchApplicantRecipients returns the user who submitted the request.
+func (w *worker) fetchApplicantRecipients(ctx context.Context, requestID string) ([]recipient, error) {
+	var r recipient
+	err := sq.Select("u.email", "COALESCE(u.name, u.email)").
+		From("requests req").
+		Join("participants p ON p.id = req.participant_id").
+		Join("users u ON u.id = p.user_id").
+		Where(sq.Eq{"req.id": requestID}).
+		RunWith(w.db).QueryRowContext(ctx).Scan(&r.email, &r.name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get applicant recipient: %w", err)
+	}
+	return []recipient{r}, nil
+}
+
+// fetchDriverRecipients returns the driver of the route the request is for.
+func (w *worker) fetchDriverRecipients(ctx context.Context, requestID string) ([]recipient, error) {
+	var r recipient
+	err := sq.Select("u.email", "COALESCE(u.name, u.email)").
+		From("requests req").
+		Join("participants p ON p.id = req.participant_id").
+		Join("routes ro ON ro.id = p.route_id").
+		Join("users u ON u.id = ro.creator_user_id").
+		Where(sq.Eq{"req.id": requestID, "ro.deleted_at": nil}).
+		RunWith(w.db).QueryRowContext(ctx).Scan(&r.email, &r.name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get driver recipient: %w", err)
+	}
+	return []recipient{r}, nil
+}
+
+// fetchLeaveReason returns why the passenger behind the request left or was
+// removed from the route.
+func (w *worker) fetchLeaveReason(ctx context.Context, requestID string) (string, error) {
+	var reason sql.NullString
+	err := sq.Select("p.leave_reason").
+		From("requests req").
+		Join("participants p ON p.id = req.participant_id").
+		Where(sq.Eq{"req.id": requestID}).
+		RunWith(w.db).QueryRowContext(ctx).Scan(&reason)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("get leave reason: %w", err)
+	}
+	return reason.String, nil
+}
+
+// fetchSavedSearchRecipients returns the owner of the saved search whose match
+// queued the email. Such emails are not linked to a request.
+func (w *worker) fetchSavedSearchRecipients(ctx context.Context, emailLogID string) ([]recipient, error) {
+	var r recipient
+	err := sq.Select("u.email", "COALESCE(u.name, u.email)").
+		From("saved_search_matches m").
+		Join("saved_searches s ON s.id = m.saved_search_id").
+		Join("users u ON u.id = s.user_id").
+		Where(sq.Eq{"m.email_log_id": emailLogID, "s.deleted_at": nil}).
+		RunWith(w.db).QueryRowContext(ctx).Scan(&r.email, &r.name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get saved search recipient: %w", err)
+	}
+	return []recipient{r}, nil
+}
+
 func (w *worker) markSent(ctx context.Context, id string) {
 	if _, err := sq.Update("email_logs").
 		Set("status", "sent").
@@ -234,6 +336,18 @@ func emailContent(emailType string) (subject, body string) {
 		return "Prašymas patvirtintas", "Jūsų prašymas prisijungti prie maršruto buvo patvirtintas."
 	case "stop_change_approved":
 		return "Stotelės keitimas patvirtintas", "Jūsų stotelės keitimo prašymas buvo patvirtintas."
+	case "waitlist_promoted":
+		return "Atsilaisvino vieta", "Maršrute atsilaisvino vieta: jūsų prašymas perkeltas iš laukiančiųjų sąrašo ir laukia vairuotojo sprendimo."
+	case "application_expired":
+		return "Prašymas nebegalioja", "Vairuotojas laiku neatsakė į jūsų prašymą prisijungti prie maršruto, todėl jis atmestas automatiškai."
+	case "stop_change_expired":
+		return "Stotelės keitimas nebegalioja", "Vairuotojas laiku neatsakė į jūsų stotelės keitimo prašymą, todėl jis atmestas automatiškai."
+	case "passenger_left":
+		return "Keleivis paliko maršrutą", "Patvirtintas keleivis paliko jūsų maršrutą, jo vieta atsilaisvino."
+	case "passenger_removed":
+		return "Pašalinta iš maršruto", "Vairuotojas pašalino jus iš maršruto."
+	case "saved_search_match":
+		return "Rastas tinkamas maršrutas", "Paskelbtas maršrutas, atitinkantis jūsų išsaugotą paiešką."
 	default:
 		return "Pranešimas", "Turite naują pranešimą."
 	}
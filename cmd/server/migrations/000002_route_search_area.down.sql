@@ -0,0 +1,3 @@
+ALTER TABLE routes
+  DROP INDEX routes_search_area,
+  DROP COLUMN search_area;
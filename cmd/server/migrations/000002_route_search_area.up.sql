@@ -0,0 +1,40 @@
+-- ── Route search area ─────────────────────────────────────────────────────────
+-- Bounding box (X = lng, Y = lat) of a route's start, stops and end, widened by
+-- its max_deviation. Route search only loads routes whose area covers every
+-- point of the query, which the spatial index answers without a full scan.
+ALTER TABLE routes ADD COLUMN search_area POLYGON NULL SRID 0 AFTER max_deviation;
+
+UPDATE routes r
+JOIN (
+  SELECT
+    b.id,
+    b.min_lat - b.pad_lat AS min_lat,
+    b.max_lat + b.pad_lat AS max_lat,
+    b.min_lng - b.max_deviation / (111.320 * GREATEST(COS(RADIANS(LEAST(GREATEST(ABS(b.min_lat), ABS(b.max_lat)) + b.pad_lat, 89))), 0.01)) AS min_lng,
+    b.max_lng + b.max_deviation / (111.320 * GREATEST(COS(RADIANS(LEAST(GREATEST(ABS(b.min_lat), ABS(b.max_lat)) + b.pad_lat, 89))), 0.01)) AS max_lng
+  FROM (
+    SELECT
+      ro.id,
+      ro.max_deviation,
+      ro.max_deviation / 110.574 AS pad_lat,
+      LEAST(ro.start_lat, ro.end_lat, COALESCE(MIN(s.lat), ro.start_lat)) AS min_lat,
+      GREATEST(ro.start_lat, ro.end_lat, COALESCE(MAX(s.lat), ro.start_lat)) AS max_lat,
+      LEAST(ro.start_lng, ro.end_lng, COALESCE(MIN(s.lng), ro.start_lng)) AS min_lng,
+      GREATEST(ro.start_lng, ro.end_lng, COALESCE(MAX(s.lng), ro.start_lng)) AS max_lng
+    FROM routes ro
+    LEFT JOIN route_stops s ON s.route_id = ro.id
+    GROUP BY ro.id
+  ) b
+) area ON area.id = r.id
+SET r.search_area = ST_GeomFromText(CONCAT(
+  'POLYGON((',
+  area.min_lng, ' ', area.min_lat, ',',
+  area.max_lng, ' ', area.min_lat, ',',
+  area.max_lng, ' ', area.max_lat, ',',
+  area.min_lng, ' ', area.max_lat, ',',
+  area.min_lng, ' ', area.min_lat,
+  '))'), 0);
+
+ALTER TABLE routes
+  MODIFY COLUMN search_area POLYGON NOT NULL SRID 0,
+  ADD SPATIAL INDEX routes_search_area (search_area);
@@ -0,0 +1,2 @@
+ALTER TABLE routes
+  ADD COLUMN polyline MEDIUMTEXT NULL AFTER end_formatted_address;
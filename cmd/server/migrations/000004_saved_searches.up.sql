@@ -0,0 +1,29 @@
+CREATE TABLE saved_searches (
+  id             CHAR(36)   NOT NULL PRIMARY KEY,
+  user_id        CHAR(36)   NOT NULL,
+  query          JSON       NOT NULL,
+  -- Every point of the query (start, end, stops) as X=lng, Y=lat.
+  points         MULTIPOINT NOT NULL SRID 0,
+  leaving_after  TIMESTAMP  NULL DEFAULT NULL,
+  leaving_before TIMESTAMP  NOT NULL,
+  created_at     TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  deleted_at     TIMESTAMP  NULL DEFAULT NULL,
+  KEY saved_searches_user_id (user_id),
+  KEY saved_searches_leaving_before (leaving_before),
+  SPATIAL INDEX saved_searches_points (points),
+  CONSTRAINT saved_searches_user_fk FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE saved_search_matches (
+  id              CHAR(36)  NOT NULL PRIMARY KEY,
+  saved_search_id CHAR(36)  NOT NULL,
+  route_id        CHAR(36)  NOT NULL,
+  email_log_id    CHAR(36)  DEFAULT NULL,
+  created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY saved_search_matches_search_route (saved_search_id, route_id),
+  KEY saved_search_matches_route_id (route_id),
+  KEY saved_search_matches_email_log_id (email_log_id),
+  CONSTRAINT saved_search_matches_search_fk FOREIGN KEY (saved_search_id) REFERENCES saved_searches (id) ON DELETE CASCADE,
+  CONSTRAINT saved_search_matches_route_fk FOREIGN KEY (route_id) REFERENCES routes (id) ON DELETE CASCADE,
+  CONSTRAINT saved_search_matches_email_log_fk FOREIGN KEY (email_log_id) REFERENCES email_logs (id) ON DELETE SET NULL
+);
@@ -0,0 +1,6 @@
+ALTER TABLE routes
+  DROP FOREIGN KEY routes_schedule_fk,
+  DROP INDEX routes_schedule_leaving_at,
+  DROP COLUMN schedule_id;
+
+DROP TABLE IF EXISTS route_schedules;
@@ -0,0 +1,20 @@
+CREATE TABLE route_schedules (
+  id                 CHAR(36)     NOT NULL PRIMARY KEY,
+  creator_user_id    CHAR(36)     NOT NULL,
+  recurrence         VARCHAR(255) NOT NULL,
+  timezone           VARCHAR(64)  NOT NULL,
+  template           JSON         NOT NULL,
+  ends_at            TIMESTAMP    NOT NULL,
+  materialized_until TIMESTAMP    NULL DEFAULT NULL,
+  created_at         TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  deleted_at         TIMESTAMP    NULL DEFAULT NULL,
+  KEY route_schedules_creator_user_id (creator_user_id),
+  KEY route_schedules_materialized_until (materialized_until),
+  CONSTRAINT route_schedules_creator_fk FOREIGN KEY (creator_user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+ALTER TABLE routes
+  ADD COLUMN schedule_id CHAR(36) NULL DEFAULT NULL AFTER vehicle_id,
+  ADD UNIQUE KEY routes_schedule_leaving_at (schedule_id, leaving_at),
+  ADD CONSTRAINT routes_schedule_fk FOREIGN KEY (schedule_id) REFERENCES route_schedules (id) ON DELETE SET NULL;
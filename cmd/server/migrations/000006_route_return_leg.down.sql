@@ -0,0 +1,4 @@
+ALTER TABLE routes
+  DROP FOREIGN KEY routes_return_of_fk,
+  DROP INDEX routes_return_of_route_id,
+  DROP COLUMN return_of_route_id;
@@ -0,0 +1,4 @@
+ALTER TABLE routes
+  ADD COLUMN return_of_route_id CHAR(36) NULL DEFAULT NULL AFTER schedule_id,
+  ADD KEY routes_return_of_route_id (return_of_route_id),
+  ADD CONSTRAINT routes_return_of_fk FOREIGN KEY (return_of_route_id) REFERENCES routes (id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE route_stops
+  DROP COLUMN eta;
+ALTER TABLE routes
+  DROP COLUMN arrival_eta;
@@ -0,0 +1,4 @@
+ALTER TABLE routes
+  ADD COLUMN arrival_eta TIMESTAMP NULL AFTER leaving_at;
+ALTER TABLE route_stops
+  ADD COLUMN eta TIMESTAMP NULL AFTER formatted_address;
@@ -0,0 +1,2 @@
+ALTER TABLE participants
+  DROP COLUMN detour_km;
@@ -0,0 +1,2 @@
+ALTER TABLE participants
+  ADD COLUMN detour_km DECIMAL(8,3) NOT NULL DEFAULT 0 AFTER status;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+  DROP INDEX users_calendar_token_unique,
+  DROP COLUMN calendar_token;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+  ADD COLUMN calendar_token CHAR(64) NULL AFTER status,
+  ADD UNIQUE KEY users_calendar_token_unique (calendar_token);
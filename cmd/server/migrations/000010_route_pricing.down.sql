@@ -0,0 +1,5 @@
+ALTER TABLE participants
+  DROP COLUMN price_share;
+ALTER TABLE routes
+  DROP COLUMN price_per_km,
+  DROP COLUMN pricing_mode;
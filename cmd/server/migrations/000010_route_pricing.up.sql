@@ -0,0 +1,5 @@
+ALTER TABLE routes
+  ADD COLUMN pricing_mode ENUM('flat','per_km') NOT NULL DEFAULT 'flat' AFTER price,
+  ADD COLUMN price_per_km DECIMAL(10,2) NULL AFTER pricing_mode;
+ALTER TABLE participants
+  ADD COLUMN price_share DECIMAL(10,2) NULL AFTER detour_km;
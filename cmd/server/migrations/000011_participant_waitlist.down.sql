@@ -0,0 +1,5 @@
+DELETE FROM participants WHERE status = 'waitlisted';
+
+ALTER TABLE participants
+  DROP KEY participants_route_status,
+  MODIFY COLUMN status ENUM('driver','pending','approved','rejected','left') NOT NULL DEFAULT 'pending';
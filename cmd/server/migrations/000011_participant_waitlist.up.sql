@@ -0,0 +1,3 @@
+ALTER TABLE participants
+  MODIFY COLUMN status ENUM('driver','pending','approved','rejected','left','waitlisted') NOT NULL DEFAULT 'pending',
+  ADD KEY participants_route_status (route_id, status, created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE routes
+  DROP COLUMN auto_approve_min_rides,
+  DROP COLUMN auto_approve_min_rating,
+  DROP COLUMN auto_approve;
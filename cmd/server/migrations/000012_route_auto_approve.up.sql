@@ -0,0 +1,4 @@
+ALTER TABLE routes
+  ADD COLUMN auto_approve            TINYINT(1)    NOT NULL DEFAULT 0 AFTER price_per_km,
+  ADD COLUMN auto_approve_min_rating DECIMAL(3,2)  NULL AFTER auto_approve,
+  ADD COLUMN auto_approve_min_rides  INT UNSIGNED  NULL AFTER auto_approve_min_rating;
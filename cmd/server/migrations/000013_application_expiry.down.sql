@@ -0,0 +1,4 @@
+ALTER TABLE participants
+  DROP KEY participants_awaiting_since,
+  DROP COLUMN rejection_reason,
+  DROP COLUMN awaiting_since;
@@ -0,0 +1,8 @@
+ALTER TABLE participants
+  ADD COLUMN awaiting_since TIMESTAMP NULL DEFAULT NULL AFTER pending_stop_change,
+  ADD COLUMN rejection_reason VARCHAR(64) NULL DEFAULT NULL AFTER awaiting_since,
+  ADD KEY participants_awaiting_since (awaiting_since);
+
+UPDATE participants
+SET awaiting_since = updated_at
+WHERE deleted_at IS NULL AND (status = 'pending' OR pending_stop_change = 1);
@@ -0,0 +1,5 @@
+ALTER TABLE participants
+  DROP COLUMN luggage,
+  DROP COLUMN seats;
+ALTER TABLE routes
+  DROP COLUMN luggage_capacity;
@@ -0,0 +1,7 @@
+ALTER TABLE routes
+  ADD COLUMN luggage_capacity INT UNSIGNED NULL DEFAULT NULL AFTER max_passengers;
+ALTER TABLE participants
+  ADD COLUMN seats   TINYINT UNSIGNED NOT NULL DEFAULT 1 AFTER status,
+  ADD COLUMN luggage TINYINT UNSIGNED NOT NULL DEFAULT 0 AFTER seats;
+
+UPDATE participants SET seats = 0 WHERE status = 'driver';
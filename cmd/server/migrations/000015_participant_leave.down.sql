@@ -0,0 +1,6 @@
+ALTER TABLE private_chats
+  DROP COLUMN closed_at;
+UPDATE participants SET status = 'left' WHERE status = 'removed';
+ALTER TABLE participants
+  DROP COLUMN leave_reason,
+  MODIFY COLUMN status ENUM('driver','pending','approved','rejected','left','waitlisted') NOT NULL DEFAULT 'pending';
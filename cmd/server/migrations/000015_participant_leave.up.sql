@@ -0,0 +1,5 @@
+ALTER TABLE participants
+  MODIFY COLUMN status ENUM('driver','pending','approved','rejected','left','waitlisted','removed') NOT NULL DEFAULT 'pending',
+  ADD COLUMN leave_reason VARCHAR(500) NULL DEFAULT NULL AFTER rejection_reason;
+ALTER TABLE private_chats
+  ADD COLUMN closed_at TIMESTAMP NULL DEFAULT NULL AFTER created_at;
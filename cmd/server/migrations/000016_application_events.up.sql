@@ -0,0 +1,18 @@
+-- Append-only history of application and stop-change transitions. It keeps no
+-- foreign key to participants: cancelled applications are deleted but their
+-- history stays.
+CREATE TABLE application_events (
+  id             CHAR(36)     NOT NULL PRIMARY KEY,
+  participant_id CHAR(36)     NOT NULL,
+  route_id       CHAR(36)     NOT NULL,
+  user_id        CHAR(36)     NOT NULL,
+  actor_user_id  CHAR(36)     DEFAULT NULL,
+  type           VARCHAR(64)  NOT NULL,
+  old_status     VARCHAR(16)  DEFAULT NULL,
+  new_status     VARCHAR(16)  DEFAULT NULL,
+  stops_before   JSON         DEFAULT NULL,
+  stops_after    JSON         DEFAULT NULL,
+  created_at     TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  KEY application_events_participant (participant_id, created_at),
+  CONSTRAINT application_events_route_fk FOREIGN KEY (route_id) REFERENCES routes (id) ON DELETE CASCADE
+);
@@ -1,8 +1,11 @@
 import { get, post, patch, del } from './client'
-import type { Application, ApplyInput } from '../types'
+import type { Application, ApplicationEvent, ApplicationPreview, ApplicationPreviewInput, ApplicationStopInput, ApplyInput } from '../types'
 
 export const applyToRoute = (routeId: string, input: ApplyInput) =>
-  post<{ id: string }>(`/routes/${routeId}/applications`, input)
+  post<Application>(`/routes/${routeId}/applications`, input)
+
+export const previewApplication = (routeId: string, input: ApplicationPreviewInput) =>
+  post<ApplicationPreview>(`/routes/${routeId}/applications/preview`, input)
 
 export const getRouteApplications = (routeId: string) =>
   get<Application[]>(`/routes/${routeId}/applications`)
@@ -13,16 +16,25 @@ export const reviewApplication = (
   status: 'approved' | 'rejected'
 ) => patch<void>(`/routes/${routeId}/applications/${appId}`, { status })
 
+export const getApplicationHistory = (routeId: string, appId: string) =>
+  get<ApplicationEvent[]>(`/routes/${routeId}/applications/${appId}/history`)
+
 export const cancelApplication = (routeId: string, appId: string) =>
   del<void>(`/routes/${routeId}/applications/${appId}`)
 
+export const leaveRoute = (routeId: string, appId: string, reason: string) =>
+  post<void>(`/routes/${routeId}/applications/${appId}/leave`, { reason })
+
+export const removeParticipant = (routeId: string, userId: string, reason: string) =>
+  post<void>(`/routes/${routeId}/participants/${userId}/remove`, { reason })
+
 export const getMyApplicationForRoute = (routeId: string) =>
   get<Application>(`/routes/${routeId}/applications/my`)
 
 export const updateMyApplication = (
   routeId: string,
   appId: string,
-  stops: Array<{ position: number; lat: number; lng: number; place_id?: string; formatted_address?: string; route_stop_id?: string }>,
+  stops: ApplicationStopInput[],
   comment?: string
 ) => patch<void>(`/routes/${routeId}/applications/${appId}/stops`, { stops, comment: comment ?? null })
 
@@ -31,7 +43,7 @@ export const getMyApplications = () => get<Application[]>('/applications/my')
 export const requestStopChange = (
   routeId: string,
   appId: string,
-  stops: Array<{ position: number; lat: number; lng: number; place_id?: string; formatted_address?: string; route_stop_id?: string }>,
+  stops: ApplicationStopInput[],
   comment?: string
 ) => post<void>(`/routes/${routeId}/applications/${appId}/stop-change`, { stops, comment: comment || undefined })
 
@@ -12,6 +12,12 @@ export const getUserProfile = (id: string) =>
 export const disableMyAccount = () =>
   post<void>('/users/me/disable')
 
+// Issues a new ICS feed token; the previous feed URL stops working.
+export const rotateCalendarToken = () =>
+  post<{ token: string; path: string }>('/users/me/calendar-token')
+
+export const calendarFeedUrl = (token: string) => `/calendar/${token}.ics`
+
 export const refreshSession = () => post<void>('/auth/refresh')
 
 export const logout = () => get<void>('/auth/logout')
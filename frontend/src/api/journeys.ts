@@ -0,0 +1,5 @@
+import { post } from './client'
+import type { Journey, JourneySearchInput } from '../types'
+
+export const searchJourneys = (input: JourneySearchInput) =>
+  post<Journey[]>('/journeys/search', input)
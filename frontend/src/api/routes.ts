@@ -4,12 +4,13 @@ import type {
   CreateRouteInput,
   UpdateRouteInput,
   SearchRouteInput,
+  SearchRoutePage,
 } from '../types'
 
 export const getRoute = (id: string) => get<Route>(`/routes/${id}`)
 
 export const searchRoutes = (input: SearchRouteInput) =>
-  post<Route[]>('/routes/search', input)
+  post<SearchRoutePage>('/routes/search', input)
 
 export const createRoute = (input: CreateRouteInput) =>
   post<{ id: string }>('/routes', input)
@@ -28,3 +29,7 @@ export const getParticipatedRoutes = (filter?: 'active' | 'past') => {
   const q = filter ? `?filter=${filter}` : ''
   return get<Route[]>(`/routes/participated${q}`)
 }
+
+// Cookie-authenticated download link; only the driver and approved passengers can open it.
+export const routeExportUrl = (id: string, format: 'geojson' | 'gpx') =>
+  `/routes/${id}/export?format=${format}`
@@ -0,0 +1,11 @@
+import { get, post, del } from './client'
+import type { SavedSearch, SearchRouteInput } from '../types'
+
+export const listMySavedSearches = (): Promise<SavedSearch[]> =>
+  get<SavedSearch[]>('/saved-searches/my')
+
+export const createSavedSearch = (input: SearchRouteInput) =>
+  post<{ id: string }>('/saved-searches', input)
+
+export const deleteSavedSearch = (id: string): Promise<void> =>
+  del<void>(`/saved-searches/${id}`)
@@ -0,0 +1,14 @@
+import { get, post, patch, del } from './client'
+import type { CreateScheduleInput, RouteSchedule, UpdateScheduleInput } from '../types'
+
+export const listMySchedules = (): Promise<RouteSchedule[]> =>
+  get<RouteSchedule[]>('/schedules/my')
+
+export const createSchedule = (input: CreateScheduleInput) =>
+  post<{ id: string }>('/schedules', input)
+
+export const updateSchedule = (id: string, input: UpdateScheduleInput) =>
+  patch<RouteSchedule>(`/schedules/${id}`, input)
+
+export const deleteSchedule = (id: string): Promise<void> =>
+  del<void>(`/schedules/${id}`)
@@ -1,12 +1,36 @@
+import { useState } from 'react'
 import { Link } from 'react-router-dom'
-import type { Application } from '../types'
+import type { Application, ApplicationEvent, StopSnapshot } from '../types'
+import { getApplicationHistory } from '../api/applications'
 
 const statusColors: Record<string, string> = {
   pending: 'bg-yellow-100 text-yellow-700',
+  waitlisted: 'bg-gray-100 text-gray-600',
   approved: 'bg-green-100 text-green-700',
   rejected: 'bg-red-100 text-red-600',
 }
 
+const eventLabels: Record<string, string> = {
+  submitted:             'Pateiktas prašymas',
+  stops_updated:         'Pakeistos stotelės',
+  approved:              'Patvirtinta',
+  rejected:              'Atmesta',
+  promoted:              'Perkelta iš laukiančiųjų sąrašo',
+  cancelled:             'Atšaukta',
+  left:                  'Paliko maršrutą',
+  removed:               'Pašalinta iš maršruto',
+  expired:               'Nebegalioja',
+  stop_change_requested: 'Prašoma keisti stoteles',
+  stop_change_approved:  'Stotelių pakeitimas patvirtintas',
+  stop_change_rejected:  'Stotelių pakeitimas atmestas',
+  stop_change_cancelled: 'Stotelių pakeitimas atšauktas',
+  stop_change_expired:   'Stotelių pakeitimas nebegalioja',
+}
+
+function stopsText(stops: StopSnapshot[]) {
+  return stops.map(s => s.formatted_address ?? `${s.lat.toFixed(4)}, ${s.lng.toFixed(4)}`).join(' → ')
+}
+
 interface Props {
   app: Application
   onApprove?: (appId: string) => void
@@ -17,6 +41,8 @@ interface Props {
   onToggleExpand?: (appId: string) => void
   expanded?: boolean
   loading?: boolean
+  // The history is only readable by the applicant and the driver.
+  canViewHistory?: boolean
 }
 
 export default function ApplicationCard({
@@ -29,8 +55,22 @@ export default function ApplicationCard({
   onToggleExpand,
   expanded,
   loading,
+  canViewHistory,
 }: Props) {
   const canExpand = !!onToggleExpand
+  const [history, setHistory] = useState<ApplicationEvent[] | null>(null)
+  const [showHistory, setShowHistory] = useState(false)
+
+  const toggleHistory = async () => {
+    if (!showHistory && history === null) {
+      try {
+        setHistory(await getApplicationHistory(app.route_id, app.id))
+      } catch {
+        setHistory([])
+      }
+    }
+    setShowHistory(v => !v)
+  }
 
   return (
     <div className="bg-white rounded-xl border border-gray-200 p-4">
@@ -109,7 +149,16 @@ export default function ApplicationCard({
             </button>
           )}
 
-          {app.status === 'pending' && onCancel && (
+          {canViewHistory && (
+            <button
+              onClick={toggleHistory}
+              className="text-sm text-gray-500 hover:text-gray-700"
+            >
+              {showHistory ? 'Slėpti istoriją' : 'Istorija'}
+            </button>
+          )}
+
+          {(app.status === 'pending' || app.status === 'waitlisted') && onCancel && (
             <button
               onClick={() => onCancel(app.id)}
               disabled={loading}
@@ -120,6 +169,29 @@ export default function ApplicationCard({
           )}
         </div>
       </div>
+
+      {showHistory && history && (
+        <ol className="mt-3 pt-3 border-t border-gray-100 space-y-2">
+          {history.length === 0 && <li className="text-xs text-gray-400">Istorijos nėra.</li>}
+          {history.map(e => (
+            <li key={e.id} className="text-xs text-gray-600">
+              <div>
+                <span className="text-gray-400">{new Date(e.created_at).toLocaleString('lt-LT', { dateStyle: 'short', timeStyle: 'short' })}</span>
+                {' · '}
+                <span className="font-medium">{eventLabels[e.type] ?? e.type}</span>
+                {' · '}
+                {e.actor_name ?? 'automatiškai'}
+              </div>
+              {e.stops_before && e.stops_before.length > 0 && (
+                <div className="text-gray-400">Prieš: {stopsText(e.stops_before)}</div>
+              )}
+              {e.stops_after && e.stops_after.length > 0 && (
+                <div className="text-gray-400">Po: {stopsText(e.stops_after)}</div>
+              )}
+            </li>
+          ))}
+        </ol>
+      )}
     </div>
   )
 }
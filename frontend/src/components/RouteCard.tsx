@@ -57,7 +57,15 @@ export default function RouteCard({ route, showBadge, linkState }: RouteCardProp
         </div>
 
         <div className="text-right flex-shrink-0">
-          {route.price != null ? (
+          {route.estimated_price != null ? (
+            <div className="text-lg font-semibold text-indigo-600">
+              ~€{route.estimated_price.toFixed(2)}
+            </div>
+          ) : route.pricing_mode === 'per_km' && route.price_per_km != null ? (
+            <div className="text-lg font-semibold text-indigo-600">
+              €{route.price_per_km.toFixed(2)}/km
+            </div>
+          ) : route.price != null ? (
             <div className="text-lg font-semibold text-indigo-600">
               €{route.price.toFixed(2)}
             </div>
@@ -67,7 +75,11 @@ export default function RouteCard({ route, showBadge, linkState }: RouteCardProp
           <div className="text-xs text-gray-400 mt-0.5">
             {route.available_passengers}/{route.max_passengers} laisvos vietos
           </div>
-          {showBadge && route.available_passengers === 0 && (
+          {showBadge && route.waitlist_only ? (
+            <span className="inline-block mt-1 text-xs bg-amber-100 text-amber-700 px-2 py-0.5 rounded-full">
+              Laukiančiųjų sąrašas
+            </span>
+          ) : showBadge && route.available_passengers === 0 && (
             <span className="inline-block mt-1 text-xs bg-red-100 text-red-600 px-2 py-0.5 rounded-full">
               Pilna
             </span>
@@ -9,7 +9,7 @@ import type { PrivateChat, GroupChat, ChatMessage } from '../types'
 
 type Tab = 'private' | 'group'
 type ActiveChat =
-  | { kind: 'private'; id: string; name: string }
+  | { kind: 'private'; id: string; name: string; closed: boolean }
   | { kind: 'group'; id: string; name: string }
 
 function fmtTime(iso: string) {
@@ -147,10 +147,11 @@ export default function ChatsPage() {
                 return (
                   <button
                     key={c.ID}
-                    onClick={() => openChat({ kind: 'private', id: c.ID, name: c.OtherName })}
+                    onClick={() => openChat({ kind: 'private', id: c.ID, name: c.OtherName, closed: !!c.ClosedAt })}
                     className={`w-full text-left px-4 py-3 border-b border-gray-100 hover:bg-gray-50 transition-colors ${isActive ? 'bg-indigo-50' : ''}`}
                   >
                     <div className="font-medium text-sm text-gray-800 truncate">{c.OtherName}</div>
+                    {c.ClosedAt && <div className="text-xs text-gray-400">Uždarytas</div>}
                   </button>
                 )
               })
@@ -215,6 +216,11 @@ export default function ChatsPage() {
               </div>
 
               {/* Input */}
+              {activeChat.kind === 'private' && activeChat.closed ? (
+                <div className="px-4 py-3 border-t border-gray-200 text-sm text-gray-400 text-center">
+                  Pokalbis uždarytas: keleivis nebedalyvauja maršrute.
+                </div>
+              ) : (
               <div className="px-4 py-3 border-t border-gray-200 flex gap-2">
                 <textarea
                   className="flex-1 border border-gray-300 rounded-xl px-3 py-2 text-sm resize-none focus:outline-none focus:ring-2 focus:ring-indigo-400"
@@ -232,6 +238,7 @@ export default function ChatsPage() {
                   Siųsti
                 </button>
               </div>
+              )}
             </>
           )}
         </div>
@@ -1,6 +1,6 @@
 import { useEffect, useState } from 'react'
 import { Link } from 'react-router-dom'
-import { getMyApplications, cancelApplication } from '../api/applications'
+import { getMyApplications, cancelApplication, leaveRoute } from '../api/applications'
 import type { Application } from '../types'
 import { ApiError } from '../api/client'
 
@@ -15,9 +15,9 @@ const TABS: { id: Tab; label: string }[] = [
 ]
 
 function tabForApp(app: Application): Tab {
-  if (app.status === 'pending') return 'pending'
+  if (app.status === 'pending' || app.status === 'waitlisted') return 'pending'
   if (app.status === 'rejected') return 'rejected'
-  if (app.status === 'left') return 'left'
+  if (app.status === 'left' || app.status === 'removed') return 'left'
   // approved — check if ride has already departed
   if (app.route_leaving_at && new Date(app.route_leaving_at) < new Date()) return 'finished'
   return 'participating'
@@ -33,6 +33,7 @@ const statusBadge: Record<string, string> = {
   approved:      'bg-green-100 text-green-700',
   rejected:      'bg-red-100 text-red-600',
   left:          'bg-gray-100 text-gray-500',
+  removed:       'bg-red-100 text-red-600',
 }
 
 const statusLabel: Record<string, string> = {
@@ -40,6 +41,12 @@ const statusLabel: Record<string, string> = {
   approved: 'Patvirtinta',
   rejected: 'Atmesta',
   left:     'Palikta',
+  removed:  'Pašalinta',
+}
+
+const rejectionReasonLabel: Record<string, string> = {
+  expired_before_departure: 'Vairuotojas neatsakė iki išvykimo',
+  expired_unanswered:       'Vairuotojas neatsakė laiku',
 }
 
 type TimeFilter = 'upcoming' | 'past'
@@ -70,12 +77,7 @@ export default function MyApplicationsPage() {
     setActionLoading(true)
     try {
       await cancelApplication(app.route_id, appId)
-      // After cancelling an approved app it becomes 'left'; pending/rejected are removed
-      if (app.status === 'approved') {
-        setApps(prev => prev.map(a => a.id === appId ? { ...a, status: 'left' as const } : a))
-      } else {
-        setApps(prev => prev.filter(a => a.id !== appId))
-      }
+      setApps(prev => prev.filter(a => a.id !== appId))
     } catch (e) {
       alert(e instanceof ApiError ? e.message : 'Failed to cancel')
     } finally {
@@ -83,6 +85,27 @@ export default function MyApplicationsPage() {
     }
   }
 
+  const handleLeave = async (appId: string) => {
+    const app = apps.find((a) => a.id === appId)
+    if (!app) return
+    const reason = prompt('Kodėl paliekate maršrutą? Priežastis bus perduota vairuotojui.')
+    if (reason === null) return
+    if (!reason.trim()) {
+      alert('Nurodykite priežastį')
+      return
+    }
+    setActionLoading(true)
+    try {
+      await leaveRoute(app.route_id, appId, reason.trim())
+      setApps(prev => prev.map(a => a.id === appId ? { ...a, status: 'left' as const, leave_reason: reason.trim() } : a))
+    } catch (e) {
+      alert(e instanceof ApiError ? e.message : 'Failed to leave')
+    } finally {
+      setActionLoading(false)
+    }
+  }
+  }
+
   const timeFiltered = apps.filter(app =>
     timeFilter === 'upcoming' ? isUpcoming(app) : !isUpcoming(app)
   )
@@ -174,6 +197,16 @@ export default function MyApplicationsPage() {
                   {app.comment && (
                     <p className="text-sm text-gray-500 mt-1 italic">"{app.comment}"</p>
                   )}
+                  {app.rejection_reason && (
+                    <p className="text-xs text-red-600 mt-1">
+                      {app.status === 'rejected' ? 'Prašymas atmestas' : 'Stotelių pakeitimas atmestas'}: {rejectionReasonLabel[app.rejection_reason] ?? app.rejection_reason}
+                    </p>
+                  )}
+                  {app.leave_reason && (
+                    <p className="text-xs text-gray-500 mt-1">
+                      {app.status === 'removed' ? 'Vairuotojas pašalino' : 'Palikote'}: {app.leave_reason}
+                    </p>
+                  )}
                 </div>
                 <span className={`text-xs px-2 py-0.5 rounded-full font-medium flex-shrink-0 ${statusBadge[app.status] ?? 'bg-gray-100 text-gray-600'}`}>
                   {statusLabel[app.status] ?? app.status}
@@ -203,9 +236,9 @@ export default function MyApplicationsPage() {
                 >
                   Peržiūrėti
                 </Link>
-                {(app.status === 'pending' || app.status === 'approved') && !(app.route_leaving_at && new Date(app.route_leaving_at) <= new Date()) && (
+                {(app.status === 'pending' || app.status === 'waitlisted' || app.status === 'approved') && !(app.route_leaving_at && new Date(app.route_leaving_at) <= new Date()) && (
                   <button
-                    onClick={() => handleCancel(app.id)}
+                    onClick={() => app.status === 'approved' ? handleLeave(app.id) : handleCancel(app.id)}
                     disabled={actionLoading}
                     className="text-xs bg-red-50 text-red-600 border border-red-200 px-3 py-1.5 rounded hover:bg-red-100 font-medium disabled:opacity-50"
                   >
@@ -13,6 +13,7 @@ import {
   requestStopChange,
   reviewStopChange,
   cancelStopChange,
+  removeParticipant,
 } from '../api/applications'
 import { createReview, getMyReviewsForRoute } from '../api/reviews'
 import type { Route, Application } from '../types'
@@ -62,6 +63,8 @@ export default function RouteDetailPage() {
   const [actionLoading, setActionLoading] = useState(false)
 
   const [applyComment, setApplyComment] = useState('')
+  const [applySeats, setApplySeats] = useState(1)
+  const [applyLuggage, setApplyLuggage] = useState(0)
 
   const [expandedAppId, setExpandedAppId] = useState<string | null>(null)
   const [changingStops, setChangingStops] = useState(false)
@@ -232,8 +235,10 @@ const isCreator = user && route && user.id === route.creator_id
     if (!id) return
     setActionLoading(true)
     try {
-      await applyToRoute(id, {
+      const app = await applyToRoute(id, {
         comment: applyComment || undefined,
+        seats: applySeats,
+        luggage: applyLuggage,
         stops: mapStops.map((s, i) => ({
           position: i,
           lat: s.lat,
@@ -243,9 +248,10 @@ const isCreator = user && route && user.id === route.creator_id
           route_stop_id: s.routeStopId,
         })),
       })
-      const app = await getMyApplicationForRoute(id)
       setMyApplication(app)
       setApplyComment('')
+      // Instant booking approves right away and takes a seat on the route.
+      if (app.status === 'approved') setRoute(await getRoute(id))
     } catch (e) {
       alert(e instanceof ApiError ? e.message : 'Failed to apply')
     } finally {
@@ -324,6 +330,26 @@ const handleCancelMyApplication = async () => {
     }
   }
 
+  const handleRemoveParticipant = async (userId: string, name: string) => {
+    if (!id) return
+    const reason = prompt(`Kodėl šalinate keleivį ${name}? Priežastis bus perduota keleiviui.`)
+    if (reason === null) return
+    if (!reason.trim()) {
+      alert('Nurodykite priežastį')
+      return
+    }
+    setActionLoading(true)
+    try {
+      await removeParticipant(id, userId, reason.trim())
+      setRoute(await getRoute(id))
+      setApplications(await getRouteApplications(id))
+    } catch (e) {
+      alert(e instanceof ApiError ? e.message : 'Failed')
+    } finally {
+      setActionLoading(false)
+    }
+  }
+
   const handleRequestStopChange = async () => {
     if (!id || !myApplication) return
     setActionLoading(true)
@@ -419,7 +445,7 @@ const handleCancelMyApplication = async () => {
   const hasStarted = !!route.leaving_at && new Date(route.leaving_at) <= new Date()
 
   // Show join button only when: logged in, not creator, seats available, no existing application, and not started
-  const canApply = user && !isCreator && route.available_passengers > 0 && myApplication === null && !hasStarted
+  const canApply = user && !isCreator && myApplication === null && !hasStarted
 
   return (
     <div className="max-w-3xl mx-auto px-4 py-8">
@@ -455,6 +481,14 @@ const handleCancelMyApplication = async () => {
             <div className="text-sm text-gray-500">
               {route.available_passengers}/{route.max_passengers} laisvos vietos
             </div>
+            {route.waitlisted > 0 && (
+              <div className="text-xs text-gray-400">{route.waitlisted} laukiančiųjų sąraše</div>
+            )}
+            {route.luggage_capacity != null && (
+              <div className="text-xs text-gray-400">
+                Bagažas: {route.available_luggage ?? 0}/{route.luggage_capacity} laisvos vietos
+              </div>
+            )}
             {isCreator && !editingRoute && !hasStarted && (
               <button
                 onClick={openEdit}
@@ -576,9 +610,21 @@ const handleCancelMyApplication = async () => {
             <div className="text-xs font-medium text-gray-500 mb-2">Keleiviai</div>
             <div className="flex flex-wrap gap-2">
               {route.participants.map((p) => (
-                <Link key={p.user_id} to={`/users/${p.user_id}`} className="text-sm bg-indigo-50 text-indigo-700 px-2 py-0.5 rounded-full hover:bg-indigo-100">
-                  {p.name}
-                </Link>
+                <span key={p.user_id} className="inline-flex items-center gap-1 text-sm bg-indigo-50 text-indigo-700 px-2 py-0.5 rounded-full">
+                  <Link to={`/users/${p.user_id}`} className="hover:underline">
+                    {p.name}
+                  </Link>
+                  {isCreator && !hasStarted && p.status === 'approved' && (
+                    <button
+                      onClick={() => handleRemoveParticipant(p.user_id, p.name)}
+                      disabled={actionLoading}
+                      title="Pašalinti keleivį"
+                      className="text-indigo-400 hover:text-red-600 disabled:opacity-50"
+                    >
+                      ×
+                    </button>
+                  )}
+                </span>
               ))}
             </div>
           </div>
@@ -605,6 +651,30 @@ const handleCancelMyApplication = async () => {
               value={applyComment}
               onChange={(e) => setApplyComment(e.target.value)}
             />
+            <div className="mt-2 flex gap-4 text-sm text-gray-700">
+              <label className="flex items-center gap-2">
+                Vietos
+                <input
+                  type="number"
+                  min={1}
+                  max={route.max_passengers}
+                  value={applySeats}
+                  onChange={(e) => setApplySeats(Math.max(1, Number(e.target.value)))}
+                  className="w-16 border border-gray-300 rounded-lg px-2 py-1"
+                />
+              </label>
+              <label className="flex items-center gap-2">
+                Bagažas
+                <input
+                  type="number"
+                  min={0}
+                  max={route.luggage_capacity}
+                  value={applyLuggage}
+                  onChange={(e) => setApplyLuggage(Math.max(0, Number(e.target.value)))}
+                  className="w-16 border border-gray-300 rounded-lg px-2 py-1"
+                />
+              </label>
+            </div>
             <div className="mt-3">
               <button
                 onClick={handleApply}
@@ -635,7 +705,7 @@ const handleCancelMyApplication = async () => {
               <span className={`text-xs font-semibold px-2.5 py-1 rounded-full ${
                 myApplication.status === 'rejected' ? 'bg-red-100 text-red-600' : 'bg-amber-100 text-amber-700'
               }`}>
-                {myApplication.status === 'rejected' ? 'Atmesta' : 'Laukiama'}
+                {myApplication.status === 'rejected' ? 'Atmesta' : myApplication.status === 'waitlisted' ? 'Laukiančiųjų sąraše' : 'Laukiama'}
               </span>
             </div>
           </div>
@@ -856,6 +926,7 @@ const handleCancelMyApplication = async () => {
                     onRejectStopChange={isCreator && !hasStarted ? (appId) => handleReviewStopChange(appId, false) : undefined}
                     onToggleExpand={(appId) => setExpandedAppId(prev => prev === appId ? null : appId)}
                     expanded={expandedAppId === app.id}
+                    canViewHistory={!!isCreator || app.user_id === user?.id}
                   />
                   {expandedAppId === app.id && (
                     <div className={`mt-2 bg-white rounded-xl border p-4 ${app.pending_stop_change ? 'border-amber-200' : 'border-gray-200'}`}>
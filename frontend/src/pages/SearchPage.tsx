@@ -61,7 +61,7 @@ export default function SearchPage() {
       end_lng: endLng,
       stops: stops.map(s => ({ lat: s.lat, lng: s.lng })),
     })
-      .then(r => {
+      .then(({ items: r }) => {
         const filtered = user
           ? r.filter(rt => !rt.participants.some(p => p.user_id === user.id))
           : r
@@ -6,14 +6,20 @@ export interface Stop {
   place_id?: string
   formatted_address?: string
   participant_id?: string
+  eta?: string
 }
 
 export interface Participant {
   user_id: string
   name: string
   status: string
+  seats: number
+  luggage: number
+  price_share?: number
 }
 
+export type PricingMode = 'flat' | 'per_km'
+
 export interface Route {
   id: string
   creator_id: string
@@ -29,13 +35,33 @@ export interface Route {
   end_formatted_address?: string
   max_passengers: number
   max_deviation: number
+  remaining_deviation: number
   available_passengers: number
+  waitlisted: number
+  luggage_capacity?: number
+  available_luggage?: number
   price?: number
+  pricing_mode: PricingMode
+  price_per_km?: number
+  auto_approve: boolean
+  auto_approve_min_rating?: number
+  auto_approve_min_rides?: number
   leaving_at?: string
+  arrival_eta?: string
+  polyline?: string
+  schedule_id?: string
+  return_of_route_id?: string
+  return_route_id?: string
+  cancelled_at?: string
+  matched_return_route_id?: string
+  score?: RouteScore
   stops: Stop[]
   participants: Participant[]
   creator_rating?: number
   creator_review_count: number
+  matched_segments?: number[]
+  estimated_price?: number
+  waitlist_only?: boolean
 }
 
 export interface ApplicationStop {
@@ -46,6 +72,7 @@ export interface ApplicationStop {
   place_id?: string
   formatted_address?: string
   route_stop_id?: string
+  eta?: string
 }
 
 export interface Application {
@@ -53,14 +80,19 @@ export interface Application {
   user_id: string
   user_name: string
   route_id: string
-  status: 'pending' | 'approved' | 'rejected' | 'left'
+  status: 'pending' | 'waitlisted' | 'approved' | 'rejected' | 'left' | 'removed'
   comment?: string
   created_at: string
   stops: ApplicationStop[]
   pending_stop_change: boolean
+  seats: number
+  luggage: number
+  rejection_reason?: 'expired_before_departure' | 'expired_unanswered'
+  leave_reason?: string
   route_leaving_at?: string
   route_start_address?: string
   route_end_address?: string
+  price_share?: number
 }
 
 export interface User {
@@ -71,6 +103,7 @@ export interface User {
   status: string
   created_at: string
   updated_at: string
+  calendar_token?: string
 }
 
 export interface Review {
@@ -91,12 +124,65 @@ export interface UserProfile {
   reviews: Review[]
 }
 
+export interface RouteScore {
+  total: number
+  deviation: number
+  departure: number
+  price: number
+  rating: number
+  seats: number
+}
+
 export interface SearchRouteInput {
   start_lat: number
   start_lng: number
   end_lat: number
   end_lng: number
   stops: Array<{ lat: number; lng: number }>
+  leaving_after?: string
+  leaving_before?: string
+  return_leaving_after?: string
+  return_leaving_before?: string
+  max_price?: number
+  min_seats?: number
+  limit?: number
+  cursor?: string
+}
+
+export interface SearchRoutePage {
+  items: Route[]
+  next_cursor: string | null
+}
+
+export interface JourneySearchInput {
+  start_lat: number
+  start_lng: number
+  end_lat: number
+  end_lng: number
+  leaving_after?: string
+  leaving_before?: string
+  max_price?: number
+  min_seats?: number
+  max_transfer_km?: number
+  max_wait_min?: number
+  limit?: number
+}
+
+export interface Journey {
+  legs: Route[]
+  deviation: number
+  price: number
+  transfer_from?: { lat: number; lng: number }
+  transfer_to?: { lat: number; lng: number }
+  transfer_km?: number
+  transfer_wait_min?: number
+}
+
+export interface SavedSearch {
+  id: string
+  user_id: string
+  query: SearchRouteInput
+  created_at: string
 }
 
 export interface Vehicle {
@@ -121,22 +207,45 @@ export interface CreateRouteInput {
   end_place_id?: string
   end_formatted_address?: string
   max_passengers: number
+  luggage_capacity?: number
   max_deviation: number
   price?: number
+  pricing_mode?: PricingMode
+  price_per_km?: number
+  auto_approve?: boolean
+  auto_approve_min_rating?: number
+  auto_approve_min_rides?: number
   leaving_at?: string
+  polyline?: string
   stops: Array<{
     lat: number
     lng: number
     place_id?: string
     formatted_address?: string
   }>
+  return?: {
+    leaving_at: string
+    polyline?: string
+    stops: Array<{
+      lat: number
+      lng: number
+      place_id?: string
+      formatted_address?: string
+    }>
+  }
 }
 
 export interface UpdateRouteInput {
   description?: string
   max_passengers?: number
+  luggage_capacity?: number
   max_deviation?: number
   price?: number
+  pricing_mode?: PricingMode
+  price_per_km?: number
+  auto_approve?: boolean
+  auto_approve_min_rating?: number
+  auto_approve_min_rides?: number
   leaving_at?: string
   start_lat?: number
   start_lng?: number
@@ -147,6 +256,32 @@ export interface UpdateRouteInput {
   end_place_id?: string
   end_formatted_address?: string
   stops?: Array<{ lat: number; lng: number; place_id?: string; formatted_address?: string }>
+  polyline?: string
+}
+
+export interface RouteSchedule {
+  id: string
+  creator_id: string
+  recurrence: string
+  timezone: string
+  template: CreateRouteInput
+  ends_at: string
+  materialized_until: string | null
+  created_at: string
+}
+
+export interface CreateScheduleInput {
+  recurrence: string
+  timezone?: string
+  template: CreateRouteInput
+  ends_at: string
+}
+
+export interface UpdateScheduleInput {
+  recurrence?: string
+  timezone?: string
+  template?: CreateRouteInput
+  ends_at?: string
 }
 
 export interface PrivateChat {
@@ -155,6 +290,7 @@ export interface PrivateChat {
   OtherName: string
   RouteID: string
   CreatedAt: string
+  ClosedAt?: string | null
 }
 
 export interface GroupChat {
@@ -177,14 +313,54 @@ export interface CreateReviewInput {
   comment?: string
 }
 
+export interface ApplicationStopInput {
+  // Omit on every stop to have the server insert the pickup/dropoff.
+  position?: number
+  lat: number
+  lng: number
+  place_id?: string
+  formatted_address?: string
+  route_stop_id?: string
+}
+
 export interface ApplyInput {
   comment?: string
-  stops: Array<{
-    position: number
-    lat: number
-    lng: number
-    place_id?: string
-    formatted_address?: string
-    route_stop_id?: string
-  }>
+  stops: ApplicationStopInput[]
+  seats?: number
+  luggage?: number
+}
+
+export interface ApplicationPreviewInput {
+  pickup?: ApplicationStopInput
+  dropoff?: ApplicationStopInput
+}
+
+export interface StopSnapshot {
+  position: number
+  lat: number
+  lng: number
+  formatted_address?: string
+}
+
+export interface ApplicationEvent {
+  id: string
+  application_id: string
+  route_id: string
+  user_id: string
+  type: string
+  actor_user_id?: string
+  actor_name?: string
+  old_status?: string
+  new_status?: string
+  stops_before?: StopSnapshot[]
+  stops_after?: StopSnapshot[]
+  created_at: string
+}
+
+export interface ApplicationPreview {
+  pickup_position?: number
+  dropoff_position?: number
+  stops: ApplicationStopInput[]
+  detour: number
+  within_budget: boolean
 }
@@ -11,20 +11,62 @@ import (
 )
 
 var (
-	ErrMySQLRequired   = errors.New("config: MySQL required (set MYSQL_DSN or MYSQL_HOST)")
-	ErrOAuthIncomplete = errors.New("config: OAuth requires OAUTH_BASE_URL, OAUTH_JWT_SECRET, and at least one provider with CLIENT_ID and CLIENT_SECRET")
-	ErrJWTSecretLength = errors.New("config: OAUTH_JWT_SECRET must be at least 32 characters")
-	ErrInvalidLogLevel = errors.New("config: LOG_LEVEL must be debug, info, warn, or error")
+	ErrMySQLRequired     = errors.New("config: MySQL required (set MYSQL_DSN or MYSQL_HOST)")
+	ErrOAuthIncomplete   = errors.New("config: OAuth requires OAUTH_BASE_URL, OAUTH_JWT_SECRET, and at least one provider with CLIENT_ID and CLIENT_SECRET")
+	ErrJWTSecretLength   = errors.New("config: OAUTH_JWT_SECRET must be at least 32 characters")
+	ErrInvalidLogLevel   = errors.New("config: LOG_LEVEL must be debug, info, warn, or error")
+	ErrInvalidSpeed      = errors.New("config: ROUTING_AVG_SPEED_KMH must be positive")
+	ErrInvalidRankWeight = errors.New("config: RANK_WEIGHT_* must not be negative")
+	ErrInvalidExpiry     = errors.New("config: APPLICATION_EXPIRY_* hours must not be negative")
 )
 
 type Config struct {
 	Server   ServerConfig
 	MySQL    MySQLConfig
 	OAuth    OAuthConfig
+	Routing  RoutingConfig
+	Schedule ScheduleConfig
+	Ranking  RankingConfig
+	Expiry   ExpiryConfig
 	NatsURL  string
 	LogLevel string
 }
 
+// RoutingConfig selects the routing engine used to measure detours.
+// Without OSRMURL search falls back to straight-line distances.
+type RoutingConfig struct {
+	OSRMURL         string
+	OSRMProfile     string
+	AverageSpeedKmh int
+	TimeoutSec      int
+}
+
+// ScheduleConfig controls the job that materialises recurring routes.
+// IntervalMin <= 0 disables the job.
+type ScheduleConfig struct {
+	HorizonDays int
+	IntervalMin int
+}
+
+// ExpiryConfig controls the job that rejects applications and stop changes
+// the driver left unanswered. They expire BeforeDepartureHours ahead of the
+// route's departure and, when AfterSubmitHours > 0, that long after they were
+// submitted. IntervalMin <= 0 disables the job.
+type ExpiryConfig struct {
+	BeforeDepartureHours int
+	AfterSubmitHours     int
+	IntervalMin          int
+}
+
+// RankingConfig weighs the factors that order route search results.
+type RankingConfig struct {
+	WeightDeviation float64
+	WeightDeparture float64
+	WeightPrice     float64
+	WeightRating    float64
+	WeightSeats     float64
+}
+
 type OAuthConfig struct {
 	BaseURL    string
 	JWTSecret  string
@@ -71,6 +113,16 @@ func (c *Config) Validate(requireMySQL, requireOAuth bool) error {
 			return ErrJWTSecretLength
 		}
 	}
+	if c.Routing.OSRMURL != "" && c.Routing.AverageSpeedKmh <= 0 {
+		return ErrInvalidSpeed
+	}
+	rk := c.Ranking
+	if rk.WeightDeviation < 0 || rk.WeightDeparture < 0 || rk.WeightPrice < 0 || rk.WeightRating < 0 || rk.WeightSeats < 0 {
+		return ErrInvalidRankWeight
+	}
+	if c.Expiry.BeforeDepartureHours < 0 || c.Expiry.AfterSubmitHours < 0 {
+		return ErrInvalidExpiry
+	}
 	switch c.LogLevel {
 	case "debug", "info", "warn", "error":
 	default:
@@ -103,6 +155,28 @@ func Load() *Config {
 			ConnMaxLifetimeSec: getEnvInt("MYSQL_CONN_MAX_LIFETIME_SEC", 300),
 		},
 		OAuth:    loadOAuthConfig(),
+		Routing: RoutingConfig{
+			OSRMURL:         getEnv("OSRM_URL", ""),
+			OSRMProfile:     getEnv("OSRM_PROFILE", "driving"),
+			AverageSpeedKmh: getEnvInt("ROUTING_AVG_SPEED_KMH", 60),
+			TimeoutSec:      getEnvInt("ROUTING_TIMEOUT_SEC", 5),
+		},
+		Schedule: ScheduleConfig{
+			HorizonDays: getEnvInt("SCHEDULE_HORIZON_DAYS", 14),
+			IntervalMin: getEnvInt("SCHEDULE_INTERVAL_MIN", 60),
+		},
+		Ranking: RankingConfig{
+			WeightDeviation: getEnvFloat("RANK_WEIGHT_DEVIATION", 0.5),
+			WeightDeparture: getEnvFloat("RANK_WEIGHT_DEPARTURE", 0.2),
+			WeightPrice:     getEnvFloat("RANK_WEIGHT_PRICE", 0.1),
+			WeightRating:    getEnvFloat("RANK_WEIGHT_RATING", 0.15),
+			WeightSeats:     getEnvFloat("RANK_WEIGHT_SEATS", 0.05),
+		},
+		Expiry: ExpiryConfig{
+			BeforeDepartureHours: getEnvInt("APPLICATION_EXPIRY_BEFORE_DEPARTURE_HOURS", 1),
+			AfterSubmitHours:     getEnvInt("APPLICATION_EXPIRY_AFTER_SUBMIT_HOURS", 0),
+			IntervalMin:          getEnvInt("APPLICATION_EXPIRY_INTERVAL_MIN", 15),
+		},
 		NatsURL:  getEnv("NATS_URL", "nats://localhost:4222"),
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 	}
@@ -141,6 +215,15 @@ func getEnvInt(key string, defaultVal int) int {
 	return defaultVal
 }
 
+func getEnvFloat(key string, defaultVal float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return defaultVal
+}
+
 func loadOAuthConfig() OAuthConfig {
 	providers := make(map[string]auth.ProviderConfig)
 	for name := range auth.Registry {
@@ -86,6 +86,27 @@ func TestConfig_Validate(t *testing.T) {
 			}
 		}
 	})
+	t.Run("OSRM without average speed", func(t *testing.T) {
+		cfg := &Config{MySQL: validMySQL, LogLevel: "info", Routing: RoutingConfig{OSRMURL: "http://osrm:5000"}}
+		err := cfg.Validate(true, false)
+		if !errors.Is(err, ErrInvalidSpeed) {
+			t.Errorf("Validate(OSRM, speed 0) = %v, want ErrInvalidSpeed", err)
+		}
+	})
+	t.Run("negative rank weight", func(t *testing.T) {
+		cfg := &Config{MySQL: validMySQL, LogLevel: "info", Ranking: RankingConfig{WeightPrice: -1}}
+		err := cfg.Validate(true, false)
+		if !errors.Is(err, ErrInvalidRankWeight) {
+			t.Errorf("Validate(negative weight) = %v, want ErrInvalidRankWeight", err)
+		}
+	})
+	t.Run("negative expiry", func(t *testing.T) {
+		cfg := &Config{MySQL: validMySQL, LogLevel: "info", Expiry: ExpiryConfig{AfterSubmitHours: -1}}
+		err := cfg.Validate(true, false)
+		if !errors.Is(err, ErrInvalidExpiry) {
+			t.Errorf("Validate(negative expiry) = %v, want ErrInvalidExpiry", err)
+		}
+	})
 	t.Run("require OAuth incomplete", func(t *testing.T) {
 		cfg := &Config{MySQL: validMySQL, LogLevel: "info", OAuth: OAuthConfig{}}
 		err := cfg.Validate(false, true)
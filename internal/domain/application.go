@@ -16,6 +16,8 @@ type ApplicationStop struct {
 	PlaceID          *string   `json:"place_id,omitempty"`
 	FormattedAddress *string   `json:"formatted_address,omitempty"`
 	RouteStopID      *string   `json:"route_stop_id,omitempty"`
+	// ETA is the estimated pickup/dropoff time, known once the application is approved.
+	ETA *time.Time `json:"eta,omitempty"`
 }
 
 // Application is a passenger's request to join a route.
@@ -29,16 +31,29 @@ type Application struct {
 	CreatedAt          time.Time         `json:"created_at"`
 	Stops              []ApplicationStop `json:"stops"`
 	PendingStopChange  bool              `json:"pending_stop_change"`
+	Seats              uint              `json:"seats"`
+	Luggage            uint              `json:"luggage"`
+	// RejectionReason is set when the application, or its last stop change,
+	// was rejected automatically (one of the ExpiryReason values).
+	RejectionReason *string `json:"rejection_reason,omitempty"`
+	// LeaveReason is why the passenger left ("left") or why the driver
+	// removed them ("removed").
+	LeaveReason *string `json:"leave_reason,omitempty"`
 
 	// Route summary fields — populated only in ListByUser responses.
 	RouteLeavingAt    *time.Time `json:"route_leaving_at,omitempty"`
 	RouteStartAddress *string    `json:"route_start_address,omitempty"`
 	RouteEndAddress   *string    `json:"route_end_address,omitempty"`
+	// PriceShare is the applicant's fare once approved on a per-km route.
+	PriceShare *float64 `json:"price_share,omitempty"`
 }
 
 // ApplicationStopInput is a stop submitted with an application.
 type ApplicationStopInput struct {
-	Position         uint    `json:"position"`
+	// Position orders the stop within the route's full stop list. When every
+	// stop omits it, the stops are the passenger's own pickup and dropoff and
+	// the server inserts them where they add the least distance.
+	Position         *uint   `json:"position"`
 	Lat              float64 `json:"lat"`
 	Lng              float64 `json:"lng"`
 	PlaceID          *string `json:"place_id"`
@@ -50,12 +65,111 @@ type ApplicationStopInput struct {
 type ApplyInput struct {
 	Comment *string                `json:"comment"`
 	Stops   []ApplicationStopInput `json:"stops"`
+	// Seats booked for the applicant and anyone travelling with them
+	// (default 1), and their pieces of luggage.
+	Seats   uint `json:"seats"`
+	Luggage uint `json:"luggage"`
+}
+
+// ApplicationPreviewInput is the body for POST /routes/{id}/applications/preview.
+// Either stop may be omitted: the passenger then boards at the route's start
+// or rides to its end.
+type ApplicationPreviewInput struct {
+	Pickup  *ApplicationStopInput `json:"pickup"`
+	Dropoff *ApplicationStopInput `json:"dropoff"`
+}
+
+// ApplicationPreview is where a passenger's stops fit best into a route.
+type ApplicationPreview struct {
+	PickupPosition  *uint `json:"pickup_position,omitempty"`
+	DropoffPosition *uint `json:"dropoff_position,omitempty"`
+	// Stops is the route's full stop order with the passenger's stops
+	// inserted, ready to submit as ApplyInput.Stops.
+	Stops []ApplicationStopInput `json:"stops"`
+	// Detour is the distance (km) the stops add to the route.
+	Detour       float64 `json:"detour"`
+	WithinBudget bool    `json:"within_budget"`
+}
+
+// Reasons recorded when an application or stop change expires unanswered.
+const (
+	// ExpiryReasonDeparture: the route departs before the driver decided.
+	ExpiryReasonDeparture = "expired_before_departure"
+	// ExpiryReasonUnanswered: the driver did not decide in time.
+	ExpiryReasonUnanswered = "expired_unanswered"
+)
+
+// StaleApplication is an application, or an approved application's stop
+// change, still waiting for the driver past its deadline.
+type StaleApplication struct {
+	ID             uuid.UUID
+	RouteID        uuid.UUID
+	Status         string
+	StopChange     bool
+	AwaitingSince  *time.Time
+	RouteLeavingAt *time.Time
+}
+
+// Types of the events recorded in an application's history.
+const (
+	EventSubmitted           = "submitted"
+	EventStopsUpdated        = "stops_updated"
+	EventApproved            = "approved"
+	EventRejected            = "rejected"
+	EventPromoted            = "promoted"
+	EventCancelled           = "cancelled"
+	EventLeft                = "left"
+	EventRemoved             = "removed"
+	EventExpired             = "expired"
+	EventStopChangeRequested = "stop_change_requested"
+	EventStopChangeApproved  = "stop_change_approved"
+	EventStopChangeRejected  = "stop_change_rejected"
+	EventStopChangeCancelled = "stop_change_cancelled"
+	EventStopChangeExpired   = "stop_change_expired"
+)
+
+// StopSnapshot is a stop as it stood when an application event was recorded.
+type StopSnapshot struct {
+	Position         uint    `json:"position"`
+	Lat              float64 `json:"lat"`
+	Lng              float64 `json:"lng"`
+	FormattedAddress *string `json:"formatted_address,omitempty"`
+}
+
+// ApplicationEvent is one recorded transition of an application or its stop
+// change. Events are append-only and outlive the application itself.
+type ApplicationEvent struct {
+	ID            uuid.UUID `json:"id"`
+	ApplicationID uuid.UUID `json:"application_id"`
+	RouteID       uuid.UUID `json:"route_id"`
+	// UserID is the applicant.
+	UserID uuid.UUID `json:"user_id"`
+	Type   string    `json:"type"`
+	// ActorUserID is who caused the transition; nil when it was automatic
+	// (waitlist promotion, expiry).
+	ActorUserID *uuid.UUID `json:"actor_user_id,omitempty"`
+	ActorName   *string    `json:"actor_name,omitempty"`
+	OldStatus   *string    `json:"old_status,omitempty"`
+	NewStatus   *string    `json:"new_status,omitempty"`
+	// StopsBefore and StopsAfter are the stop order before and after the
+	// transition, when it touched the stops.
+	StopsBefore []StopSnapshot `json:"stops_before,omitempty"`
+	StopsAfter  []StopSnapshot `json:"stops_after,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
 }
 
 // ApplicationRepository is the persistence contract for applications.
 type ApplicationRepository interface {
 	// Create persists a new application with its stops (no business-rule checks).
-	Create(ctx context.Context, userID, routeID uuid.UUID, in ApplyInput) (uuid.UUID, error)
+	// status is "pending", or "waitlisted" when the route is full.
+	Create(ctx context.Context, userID, routeID uuid.UUID, status string, in ApplyInput) (uuid.UUID, error)
+	// CreateApproved persists a new application and approves it like ReviewUpdate
+	// in the same transaction, for routes that auto-approve. It returns
+	// errs.ErrRouteFull, persisting nothing, when the booking no longer fits.
+	CreateApproved(ctx context.Context, userID, routeID uuid.UUID, in ApplyInput, detourKm float64) (uuid.UUID, error)
+	// CountCompletedRides counts the departed, uncancelled routes a user rode on
+	// as an approved passenger.
+	CountCompletedRides(ctx context.Context, userID uuid.UUID) (int, error)
 	// GetByID loads a single application with its stops.
 	GetByID(ctx context.Context, id uuid.UUID) (*Application, error)
 	// GetByUserAndRoute returns the active application a user has for a route, or nil.
@@ -65,8 +179,14 @@ type ApplicationRepository interface {
 	// ListByUser returns all active applications submitted by a user.
 	ListByUser(ctx context.Context, userID uuid.UUID) ([]Application, error)
 	// ReviewUpdate changes status to approved/rejected and handles the downstream DB
-	// work (stops update, participant insertion) inside a transaction.
-	ReviewUpdate(ctx context.Context, id uuid.UUID, status string, appUserID, routeID uuid.UUID) error
+	// work (stops update, participant insertion) inside a transaction. On approve,
+	// stops is the full stop order to put on the route, replacing the request's,
+	// and detourKm is the detour it adds, counted against the route's budget.
+	// It locks the route and rechecks the application inside the transaction:
+	// errs.ErrConflict when it is no longer pending or stops leave out a stop
+	// now on the route, errs.ErrRouteFull when an approval no longer fits and
+	// errs.ErrDetourBudget when its detour no longer does.
+	ReviewUpdate(ctx context.Context, id uuid.UUID, status string, appUserID, routeID uuid.UUID, stops []ApplicationStopInput, detourKm float64) error
 	// UpdateStops replaces the request_stops and optionally updates the comment for a pending application.
 	UpdateStops(ctx context.Context, id uuid.UUID, stops []ApplicationStopInput, comment *string) error
 	// RequestStopChange stores new proposed stops (and optional comment) and flags pending_stop_change on an approved application.
@@ -74,9 +194,30 @@ type ApplicationRepository interface {
 	// ReviewStopChange approves or rejects a pending stop-change request.
 	// On approve: copies new request_stops into route_stops and clears the flag.
 	// On reject: discards the proposed request_stops and clears the flag.
-	ReviewStopChange(ctx context.Context, id uuid.UUID, routeID uuid.UUID, approve bool) error
+	// On approve, stops is the full stop order replacing the proposed one and
+	// detourKm the change in the participant's detour; like ReviewUpdate it
+	// returns errs.ErrConflict or errs.ErrDetourBudget when the route changed.
+	ReviewStopChange(ctx context.Context, id uuid.UUID, routeID uuid.UUID, approve bool, stops []ApplicationStopInput, detourKm float64) error
 	// CancelStopChange lets the applicant withdraw a pending stop-change request.
 	CancelStopChange(ctx context.Context, id uuid.UUID) error
 	// SoftDelete marks an application deleted and optionally removes the participant record.
+	// Like a rejection in ReviewUpdate, it hands the freed place to the oldest
+	// waitlisted application, which becomes pending.
 	SoftDelete(ctx context.Context, id uuid.UUID, wasApproved bool) error
+	// Leave takes an approved passenger off the route with status "left" or
+	// "removed" and reason: it removes their route stops, closes their private
+	// chat, frees the seat for the waitlist and notifies the other side.
+	// It returns errs.ErrConflict when the application is no longer approved.
+	Leave(ctx context.Context, id uuid.UUID, status, reason string) error
+	// ListStale returns pending and waitlisted applications and pending stop
+	// changes on routes leaving before leavingBefore and, when awaitingBefore
+	// is set, pending ones waiting for the driver since before it.
+	ListStale(ctx context.Context, leavingBefore time.Time, awaitingBefore *time.Time) ([]StaleApplication, error)
+	// Expire rejects a stale application (or discards its stop change) with
+	// reason and queues an email to the applicant. It is a no-op when the
+	// driver decided in the meantime.
+	Expire(ctx context.Context, app StaleApplication, reason string) error
+	// ListEvents returns the history of an application, oldest first. It is
+	// empty for an unknown application.
+	ListEvents(ctx context.Context, id uuid.UUID) ([]ApplicationEvent, error)
 }
@@ -15,6 +15,9 @@ type PrivateChat struct {
 	RouteID     uuid.UUID
 	LastMessage string
 	CreatedAt   time.Time
+	// ClosedAt is set once either side left the route; the history stays
+	// readable but no new messages are accepted.
+	ClosedAt *time.Time
 }
 
 // GroupChat represents a route's group chat channel.
@@ -43,7 +46,8 @@ type ChatRepository interface {
 	GetPrivateMessages(ctx context.Context, chatID uuid.UUID) ([]ChatMessage, error)
 	// GetGroupMessages returns messages for a route's group chat, newest last.
 	GetGroupMessages(ctx context.Context, routeID uuid.UUID) ([]ChatMessage, error)
-	// SendPrivateMessage inserts a message into a private chat.
+	// SendPrivateMessage inserts a message into a private chat. It returns
+	// errs.ErrChatClosed when the chat has been closed.
 	SendPrivateMessage(ctx context.Context, chatID, senderUserID uuid.UUID, message string) (uuid.UUID, error)
 	// SendGroupMessage inserts a message into a route's group chat.
 	SendGroupMessage(ctx context.Context, routeID, senderUserID uuid.UUID, message string) (uuid.UUID, error)
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// JourneySearchInput is the body for POST /journeys/search.
+type JourneySearchInput struct {
+	StartLat float64 `json:"start_lat"`
+	StartLng float64 `json:"start_lng"`
+	EndLat   float64 `json:"end_lat"`
+	EndLng   float64 `json:"end_lng"`
+
+	// Optional filters, as in SearchRouteInput. The departure window applies
+	// to the first leg; MaxPrice caps the total over all legs.
+	LeavingAfter  *time.Time `json:"leaving_after"`
+	LeavingBefore *time.Time `json:"leaving_before"`
+	MaxPrice      *float64   `json:"max_price"`
+	MinSeats      *uint      `json:"min_seats"`
+
+	// MaxTransferKm bounds the straight-line distance between where the
+	// passenger leaves the first route (one of its stops or its end) and
+	// boards the second (its start or one of its stops); MaxWaitMin bounds the
+	// wait there. Both fall back to server defaults when nil.
+	MaxTransferKm *float64 `json:"max_transfer_km"`
+	MaxWaitMin    *int     `json:"max_wait_min"`
+
+	Limit int `json:"limit"`
+}
+
+// Journey is one itinerary of a journey search: a single route, or two routes
+// with a transfer between a stop or the end of the first and the start or a
+// stop of the second.
+type Journey struct {
+	Legs []Route `json:"legs"`
+	// Deviation is the detour (km) summed over the legs.
+	Deviation float64 `json:"deviation"`
+	// Price is the summed price of the legs, with per-km legs at their fare
+	// for the part of the journey they carry; routes without a price are free.
+	Price float64 `json:"price"`
+	// TransferFrom, TransferTo, TransferKm and TransferWaitMin are set on
+	// two-leg journeys only: where the passenger leaves the first leg and
+	// boards the second, the distance between and the wait there.
+	TransferFrom    *LatLng  `json:"transfer_from,omitempty"`
+	TransferTo      *LatLng  `json:"transfer_to,omitempty"`
+	TransferKm      *float64 `json:"transfer_km,omitempty"`
+	TransferWaitMin *int     `json:"transfer_wait_min,omitempty"`
+}
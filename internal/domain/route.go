@@ -12,6 +12,17 @@ type RouteFilter string
 const (
 	RouteFilterActive RouteFilter = "active"
 	RouteFilterPast   RouteFilter = "past"
+	// RouteFilterWithCancelled lists every route, cancelled ones included.
+	// It backs the calendar feed and is not accepted as a query filter.
+	RouteFilterWithCancelled RouteFilter = "with_cancelled"
+)
+
+// Pricing modes. A flat route charges each passenger its price; a per-km
+// route charges each approved passenger price_per_km for the distance
+// between their pickup and dropoff.
+const (
+	PricingFlat  = "flat"
+	PricingPerKm = "per_km"
 )
 
 // Stop is an intermediate waypoint on a confirmed route.
@@ -24,13 +35,23 @@ type Stop struct {
 	PlaceID          *string   `json:"place_id"`
 	FormattedAddress *string   `json:"formatted_address"`
 	ParticipantID    *string   `json:"participant_id,omitempty"`
+	// ETA is the estimated arrival at the stop; nil when the route has no leaving_at.
+	ETA *time.Time `json:"eta,omitempty"`
 }
 
 // Participant is a confirmed passenger or driver on a route.
 type Participant struct {
+	// ID is the participant record, referenced by Stop.ParticipantID. It is
+	// not exposed to clients.
+	ID     uuid.UUID `json:"-"`
 	UserID uuid.UUID `json:"user_id"`
 	Name   string    `json:"name"`
 	Status string    `json:"status"`
+	// Seats and Luggage are what the passenger booked; the driver books none.
+	Seats   uint `json:"seats"`
+	Luggage uint `json:"luggage"`
+	// PriceShare is an approved passenger's fare on a per-km route.
+	PriceShare *float64 `json:"price_share,omitempty"`
 }
 
 // Route is the full route entity returned to clients.
@@ -50,14 +71,72 @@ type Route struct {
 	EndFormattedAddress   *string       `json:"end_formatted_address"`
 	MaxPassengers         uint          `json:"max_passengers"`
 	MaxDeviation          float64       `json:"max_deviation"`
+	// RemainingDeviation is what is left of MaxDeviation after the detours
+	// approved passengers' stops add.
+	RemainingDeviation    float64       `json:"remaining_deviation"`
 	AvailablePassengers   uint          `json:"available_passengers"`
+	// Waitlisted counts applications queued for a seat on a full route.
+	Waitlisted            uint          `json:"waitlisted"`
+	// LuggageCapacity is how many pieces of luggage the car takes, nil when
+	// the driver did not declare it; AvailableLuggage is what approved
+	// bookings leave of it.
+	LuggageCapacity       *uint         `json:"luggage_capacity,omitempty"`
+	AvailableLuggage      *uint         `json:"available_luggage,omitempty"`
 	Price                 *float64      `json:"price,omitempty"`
+	PricingMode           string        `json:"pricing_mode"`
+	PricePerKm            *float64      `json:"price_per_km,omitempty"`
+	// AutoApprove approves applications as they are submitted, optionally
+	// only from passengers with a minimum rating or number of completed rides.
+	AutoApprove           bool          `json:"auto_approve"`
+	AutoApproveMinRating  *float64      `json:"auto_approve_min_rating,omitempty"`
+	AutoApproveMinRides   *uint         `json:"auto_approve_min_rides,omitempty"`
 	LeavingAt             *time.Time    `json:"leaving_at"`
+	// ArrivalETA is the estimated arrival at the end point.
+	ArrivalETA            *time.Time    `json:"arrival_eta,omitempty"`
+	// Polyline is the driving path as a Google encoded polyline (precision 5).
+	Polyline              *string       `json:"polyline,omitempty"`
+	// ScheduleID links an instance of a recurring route to its schedule.
+	ScheduleID            *uuid.UUID    `json:"schedule_id,omitempty"`
+	// ReturnOfRouteID is set on a return leg and points to its outbound route;
+	// ReturnRouteID is set on the outbound route and points to its return leg.
+	ReturnOfRouteID       *uuid.UUID    `json:"return_of_route_id,omitempty"`
+	ReturnRouteID         *uuid.UUID    `json:"return_route_id,omitempty"`
+	// CancelledAt is set on cancelled routes, which only RouteFilterWithCancelled lists.
+	CancelledAt           *time.Time    `json:"cancelled_at,omitempty"`
+	// CreatedAt and UpdatedAt are when the route was created and last changed.
+	CreatedAt             time.Time     `json:"-"`
+	UpdatedAt             time.Time     `json:"-"`
 	Stops                 []Stop        `json:"stops"`
 	Participants          []Participant `json:"participants"`
 	// CreatorRating is the driver's average rating (nil when review count < 5).
 	CreatorRating       *float64 `json:"creator_rating,omitempty"`
 	CreatorReviewCount  int      `json:"creator_review_count"`
+	// MatchedSegments is populated only in search results: for each search
+	// stop, the index of the route segment it lies on (0 = start → first stop).
+	// A stop on segment i belongs at stop position i when applying.
+	MatchedSegments []int `json:"matched_segments,omitempty"`
+	// WaitlistOnly is populated only in search results: the route is full, or
+	// has a waitlist, so applications join the waitlist.
+	WaitlistOnly bool `json:"waitlist_only,omitempty"`
+	// EstimatedPrice is populated only in search results on per-km routes:
+	// the fare per seat for the searched trip.
+	EstimatedPrice *float64 `json:"estimated_price,omitempty"`
+	// MatchedReturnRouteID is populated only in round-trip search results: the
+	// linked leg that also matches the way back.
+	MatchedReturnRouteID *uuid.UUID `json:"matched_return_route_id,omitempty"`
+	// Score is populated only in search results and explains their order.
+	Score *RouteScore `json:"score,omitempty"`
+}
+
+// RouteScore is a search result's rank score: the weighted contribution of
+// each factor, and their sum.
+type RouteScore struct {
+	Total     float64 `json:"total"`
+	Deviation float64 `json:"deviation"`
+	Departure float64 `json:"departure"`
+	Price     float64 `json:"price"`
+	Rating    float64 `json:"rating"`
+	Seats     float64 `json:"seats"`
 }
 
 // StopInput is a waypoint provided when creating a route.
@@ -81,19 +160,54 @@ type CreateRouteInput struct {
 	EndPlaceID            *string     `json:"end_place_id"`
 	EndFormattedAddress   *string     `json:"end_formatted_address"`
 	MaxPassengers         uint        `json:"max_passengers"`
+	// LuggageCapacity optionally limits the luggage passengers may book.
+	LuggageCapacity       *uint       `json:"luggage_capacity"`
 	MaxDeviation          float64     `json:"max_deviation"`
 	Price                 *float64    `json:"price"`
+	// PricingMode is PricingFlat (the default) or PricingPerKm, which
+	// requires PricePerKm.
+	PricingMode           string      `json:"pricing_mode"`
+	PricePerKm            *float64    `json:"price_per_km"`
+	AutoApprove           bool        `json:"auto_approve"`
+	AutoApproveMinRating  *float64    `json:"auto_approve_min_rating"`
+	AutoApproveMinRides   *uint       `json:"auto_approve_min_rides"`
 	LeavingAt             *time.Time  `json:"leaving_at"`
+	// Polyline is the optional driving path from start to end through the stops,
+	// as a Google encoded polyline (precision 5).
+	Polyline              *string     `json:"polyline"`
 	Stops                 []StopInput `json:"stops"`
+
+	// Return optionally creates a second route from end to start with the
+	// same vehicle and terms, linked to this one via return_of_route_id.
+	Return *ReturnLegInput `json:"return"`
+
+	// ScheduleID is set by the schedule job when it materialises an instance.
+	ScheduleID *uuid.UUID `json:"-"`
+}
+
+// ReturnLegInput describes the way back of a round trip.
+type ReturnLegInput struct {
+	LeavingAt *time.Time  `json:"leaving_at"`
+	Stops     []StopInput `json:"stops"` // in return driving order
+	Polyline  *string     `json:"polyline"`
 }
 
 // UpdateRouteInput carries the fields a creator may change (all optional).
 type UpdateRouteInput struct {
-	Description   *string    `json:"description"`
-	MaxPassengers *uint      `json:"max_passengers"`
-	MaxDeviation  *float64   `json:"max_deviation"`
-	Price         *float64   `json:"price"`
-	LeavingAt     *time.Time `json:"leaving_at"`
+	Description     *string    `json:"description"`
+	MaxPassengers   *uint      `json:"max_passengers"`
+	LuggageCapacity *uint      `json:"luggage_capacity"`
+	MaxDeviation    *float64   `json:"max_deviation"`
+	Price           *float64   `json:"price"`
+	PricingMode     *string    `json:"pricing_mode"`
+	PricePerKm      *float64   `json:"price_per_km"`
+	LeavingAt       *time.Time `json:"leaving_at"`
+
+	// Instant booking. A zero minimum rating or ride count removes that
+	// restriction.
+	AutoApprove          *bool    `json:"auto_approve"`
+	AutoApproveMinRating *float64 `json:"auto_approve_min_rating"`
+	AutoApproveMinRides  *uint    `json:"auto_approve_min_rides"`
 
 	// Route geometry — all four start/end fields should be provided together.
 	StartLat              *float64 `json:"start_lat"`
@@ -107,6 +221,10 @@ type UpdateRouteInput struct {
 
 	// Stops replaces all intermediate waypoints when non-nil.
 	Stops *[]StopInput `json:"stops"`
+
+	// Polyline replaces the driving path when non-nil; an empty string clears it.
+	// A geometry change without a new polyline also clears the stored one.
+	Polyline *string `json:"polyline"`
 }
 
 // SearchStop is a passenger pickup/dropoff point in a search query.
@@ -122,6 +240,40 @@ type SearchRouteInput struct {
 	EndLat   float64      `json:"end_lat"`
 	EndLng   float64      `json:"end_lng"`
 	Stops    []SearchStop `json:"stops"`
+
+	// Optional filters. A departure window excludes routes without leaving_at;
+	// per-km routes cost their fare for the searched trip and routes without
+	// a price count as free.
+	LeavingAfter  *time.Time `json:"leaving_after"`
+	LeavingBefore *time.Time `json:"leaving_before"`
+	MaxPrice      *float64   `json:"max_price"`
+	MinSeats      *uint      `json:"min_seats"`
+
+	// An optional return window turns the search into a round trip: routes
+	// whose linked leg also serves the way back (end → start, stops reversed)
+	// within the window are ranked first.
+	ReturnLeavingAfter  *time.Time `json:"return_leaving_after"`
+	ReturnLeavingBefore *time.Time `json:"return_leaving_before"`
+
+	// Limit caps the page size; Cursor is the next_cursor of the previous page.
+	Limit  int    `json:"limit"`
+	Cursor string `json:"cursor"`
+}
+
+// SearchRoutePage is one page of POST /routes/search results.
+// NextCursor is nil on the last page.
+type SearchRoutePage struct {
+	Items      []Route `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// RouteETAs are the estimated arrival times along a route: at each stop, by
+// stop ID, and at the end point. They are empty for routes without leaving_at.
+type RouteETAs struct {
+	Stops   map[uuid.UUID]time.Time
+	Arrival *time.Time
+	// Basis is the route the estimates were made for, when known.
+	Basis *Route
 }
 
 // RouteRepository is the persistence contract for routes.
@@ -132,6 +284,15 @@ type RouteRepository interface {
 	Delete(ctx context.Context, id, creatorID uuid.UUID) error
 	ListByCreator(ctx context.Context, creatorID uuid.UUID, filter RouteFilter) ([]Route, error)
 	ListByParticipant(ctx context.Context, userID uuid.UUID, filter RouteFilter) ([]Route, error)
-	// ListSearchable returns all routes that still have available seats.
-	ListSearchable(ctx context.Context) ([]Route, error)
+	// ListSearchable returns routes that still have available seats and whose
+	// corridor, widened by max_deviation, covers every point of the query.
+	ListSearchable(ctx context.Context, in SearchRouteInput) ([]Route, error)
+	// SetETAs stores a route's estimated arrival times, clearing those of
+	// stops missing from etas. It returns errs.ErrConflict, storing nothing,
+	// when the departure, end points or stops of the route differ from
+	// etas.Basis, so an older estimate never replaces a newer one.
+	SetETAs(ctx context.Context, routeID uuid.UUID, etas RouteETAs) error
+	// SetPriceShares stores the fares of a route's passengers, keyed by
+	// participant ID, clearing those of passengers missing from shares.
+	SetPriceShares(ctx context.Context, routeID uuid.UUID, shares map[uuid.UUID]float64) error
 }
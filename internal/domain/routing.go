@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LatLng is a geographic point in decimal degrees.
+type LatLng struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// RoutePath is a driving path through a sequence of waypoints.
+type RoutePath struct {
+	DistanceKm float64
+	Duration   time.Duration
+	// Legs holds the driving time between each pair of consecutive waypoints.
+	Legs []time.Duration
+	// Geometry is the path itself, starting at the first waypoint and ending at the last.
+	Geometry []LatLng
+	// Approximate is set when the path runs in straight lines between the
+	// waypoints rather than on the road network.
+	Approximate bool
+}
+
+// Router is the contract for a routing engine.
+type Router interface {
+	// Route returns the path that visits the given points (at least two) in order.
+	Route(ctx context.Context, points []LatLng) (*RoutePath, error)
+}
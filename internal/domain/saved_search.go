@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedSearch is a route search a passenger asked to be alerted about.
+// Query.LeavingAfter/LeavingBefore are its time window; the search stops
+// matching once LeavingBefore has passed.
+type SavedSearch struct {
+	ID        uuid.UUID        `json:"id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Query     SearchRouteInput `json:"query"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// RouteEvent is published on the "route" NATS subject after a route is
+// created or updated.
+type RouteEvent struct {
+	RouteID uuid.UUID `json:"id"`
+	Event   string    `json:"event"` // "created" | "updated"
+}
+
+// SavedSearchRepository is the persistence contract for saved searches.
+type SavedSearchRepository interface {
+	Create(ctx context.Context, userID uuid.UUID, query SearchRouteInput) (uuid.UUID, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]SavedSearch, error)
+	Delete(ctx context.Context, id, userID uuid.UUID) error
+	// ListMatchCandidates returns the live saved searches of other users whose
+	// time window contains the route's departure, whose points all lie in the
+	// route's search area, and that have not been matched to it yet.
+	ListMatchCandidates(ctx context.Context, route *Route) ([]SavedSearch, error)
+	// RecordMatch stores the match and queues a saved_search_match email for
+	// the search's owner. Matches already recorded are ignored.
+	RecordMatch(ctx context.Context, savedSearchID, routeID uuid.UUID) error
+}
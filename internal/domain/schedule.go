@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RouteSchedule is a recurring route, such as a weekday commute. The server
+// materialises it into concrete routes a fixed number of days ahead.
+type RouteSchedule struct {
+	ID        uuid.UUID `json:"id"`
+	CreatorID uuid.UUID `json:"creator_id"`
+	// Recurrence is an RRULE subset, e.g. "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR".
+	Recurrence string `json:"recurrence"`
+	// Timezone is the IANA zone whose wall-clock departure time is kept.
+	Timezone string `json:"timezone"`
+	// Template describes every instance; Template.LeavingAt is the first departure.
+	Template CreateRouteInput `json:"template"`
+	// EndsAt is the last moment an instance may depart.
+	EndsAt            time.Time  `json:"ends_at"`
+	MaterializedUntil *time.Time `json:"materialized_until"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// CreateScheduleInput is the body for POST /schedules.
+type CreateScheduleInput struct {
+	Recurrence string           `json:"recurrence"`
+	Timezone   string           `json:"timezone"`
+	Template   CreateRouteInput `json:"template"`
+	EndsAt     time.Time        `json:"ends_at"`
+}
+
+// UpdateScheduleInput carries the schedule fields a creator may change (all
+// optional). A new template without leaving_at keeps the current first departure.
+type UpdateScheduleInput struct {
+	Recurrence *string           `json:"recurrence"`
+	Timezone   *string           `json:"timezone"`
+	Template   *CreateRouteInput `json:"template"`
+	EndsAt     *time.Time        `json:"ends_at"`
+}
+
+// ScheduleRepository is the persistence contract for route schedules.
+//
+// An instance is "unbooked" while nobody but its driver has applied to it.
+// Update and Delete cancel the schedule's future unbooked instances so they
+// can be regenerated (or not) from the new definition; booked ones are kept.
+type ScheduleRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*RouteSchedule, error)
+	Create(ctx context.Context, creatorID uuid.UUID, in CreateScheduleInput) (uuid.UUID, error)
+	// Update stores the complete new definition and restarts materialisation.
+	Update(ctx context.Context, id, creatorID uuid.UUID, in CreateScheduleInput) error
+	Delete(ctx context.Context, id, creatorID uuid.UUID) error
+	ListByCreator(ctx context.Context, creatorID uuid.UUID) ([]RouteSchedule, error)
+	// ListDue returns live schedules not yet materialised up to horizon.
+	ListDue(ctx context.Context, horizon time.Time) ([]RouteSchedule, error)
+	SetMaterializedUntil(ctx context.Context, id uuid.UUID, until time.Time) error
+}
@@ -15,8 +15,11 @@ type User struct {
 	Provider    string
 	ProviderSub string
 	Status      string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// CalendarToken is the secret in the user's ICS feed URL; nil until
+	// first generated.
+	CalendarToken *string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 // UserRepository is the persistence contract for users.
@@ -25,4 +28,8 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*User, error)
 	UpdateName(ctx context.Context, id uuid.UUID, name *string) error
 	Disable(ctx context.Context, id uuid.UUID) error
+	// GetByCalendarToken returns the active user whose ICS feed token is token.
+	GetByCalendarToken(ctx context.Context, token string) (*User, error)
+	// SetCalendarToken replaces the user's ICS feed token.
+	SetCalendarToken(ctx context.Context, id uuid.UUID, token string) error
 }
@@ -36,6 +36,7 @@ type UpdateVehicleInput struct {
 
 // VehicleRepository is the persistence contract for vehicles.
 type VehicleRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*Vehicle, error)
 	ListByUser(ctx context.Context, userID uuid.UUID) ([]Vehicle, error)
 	Create(ctx context.Context, userID uuid.UUID, in CreateVehicleInput) (uuid.UUID, error)
 	Update(ctx context.Context, id, userID uuid.UUID, in UpdateVehicleInput) error
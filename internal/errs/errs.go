@@ -15,6 +15,20 @@ var (
 	ErrRouteNotFinished = errors.New("route has not started yet")
 	ErrAlreadyReviewed  = errors.New("you have already reviewed this user for this route")
 	ErrNotParticipant   = errors.New("user is not a participant of this route")
+	ErrInvalidSearch    = errors.New("invalid search")
+	ErrInvalidPolyline  = errors.New("invalid polyline")
+	ErrInvalidSchedule  = errors.New("invalid schedule")
+	ErrInvalidReturnLeg = errors.New("invalid return leg")
+	ErrDetourBudget     = errors.New("detour exceeds the route's remaining deviation")
+	ErrInvalidStops     = errors.New("invalid stops")
+	ErrInvalidPricing   = errors.New("invalid pricing")
+	ErrVehicleNotOwned  = errors.New("vehicle is not registered to the driver")
+	ErrExceedsVehicle   = errors.New("max_passengers exceeds the vehicle's passenger seats")
+	ErrBelowBooked      = errors.New("capacity is below what approved passengers booked")
+	ErrExceedsRoute     = errors.New("booking exceeds the route's seats or luggage capacity")
+	ErrAutoApproveRule  = errors.New("invalid auto-approve rule")
+	ErrInvalidReason    = errors.New("a reason of up to 500 characters is required")
+	ErrChatClosed       = errors.New("chat is closed")
 
 	ErrJWTSecretRequired = errors.New("auth: JWT secret is required")
 	ErrDSNNotConfigured  = errors.New("mysql: DSN not configured (set MYSQL_DSN or MYSQL_HOST)")
@@ -0,0 +1,101 @@
+// Package export renders routes in formats that other apps open: GeoJSON and
+// GPX for navigation apps and GIS tools, iCalendar for calendars.
+package export
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/geo"
+)
+
+// Supported formats.
+const (
+	FormatGeoJSON = "geojson"
+	FormatGPX     = "gpx"
+)
+
+// ContentType returns the media type of format, or "" when it is unsupported.
+func ContentType(format string) string {
+	switch format {
+	case FormatGeoJSON:
+		return "application/geo+json"
+	case FormatGPX:
+		return "application/gpx+xml"
+	}
+	return ""
+}
+
+// Route renders r in format. passengers maps a stop ID to the name of the
+// passenger it belongs to; stops missing from it are left unlabelled.
+func Route(r *domain.Route, passengers map[uuid.UUID]string, format string) ([]byte, error) {
+	points := waypoints(r, passengers)
+	switch format {
+	case FormatGeoJSON:
+		return geoJSON(points, path(r, points))
+	case FormatGPX:
+		return gpx(r, points, path(r, points))
+	}
+	return nil, fmt.Errorf("export: unsupported format %q", format)
+}
+
+// waypoint is the start, a stop or the end of a route.
+type waypoint struct {
+	kind      string // "start", "stop" or "end"
+	position  *uint  // stops only
+	lat, lng  float64
+	address   string
+	passenger string
+}
+
+// name labels the waypoint with its passenger, then its address.
+func (w waypoint) name() string {
+	switch {
+	case w.passenger != "":
+		return w.passenger
+	case w.address != "":
+		return w.address
+	case w.position != nil:
+		return fmt.Sprintf("Stop %d", *w.position+1)
+	case w.kind == "start":
+		return "Start"
+	default:
+		return "End"
+	}
+}
+
+func waypoints(r *domain.Route, passengers map[uuid.UUID]string) []waypoint {
+	points := make([]waypoint, 0, len(r.Stops)+2)
+	points = append(points, waypoint{kind: "start", lat: r.StartLat, lng: r.StartLng, address: deref(r.StartFormattedAddress)})
+	for _, st := range r.Stops {
+		pos := st.Position
+		points = append(points, waypoint{
+			kind: "stop", position: &pos, lat: st.Lat, lng: st.Lng,
+			address: deref(st.FormattedAddress), passenger: passengers[st.ID],
+		})
+	}
+	return append(points, waypoint{kind: "end", lat: r.EndLat, lng: r.EndLng, address: deref(r.EndFormattedAddress)})
+}
+
+// path is the route's stored driving path, or the straight lines through its
+// waypoints when it has none.
+func path(r *domain.Route, points []waypoint) []domain.LatLng {
+	if r.Polyline != nil && *r.Polyline != "" {
+		if p, err := geo.DecodePolyline(*r.Polyline); err == nil && len(p) >= 2 {
+			return p
+		}
+	}
+	out := make([]domain.LatLng, len(points))
+	for i, w := range points {
+		out[i] = domain.LatLng{Lat: w.lat, Lng: w.lng}
+	}
+	return out
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,161 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+func testRoute() (*domain.Route, map[uuid.UUID]string) {
+	start, end, addr := "Vilnius", "Kaunas", "Elektrėnai"
+	r := &domain.Route{
+		ID:       uuid.New(),
+		StartLat: 54.69, StartLng: 25.28, StartFormattedAddress: &start,
+		EndLat: 54.90, EndLng: 23.90, EndFormattedAddress: &end,
+		Stops: []domain.Stop{
+			{ID: uuid.New(), Position: 0, Lat: 54.78, Lng: 24.66, FormattedAddress: &addr},
+			{ID: uuid.New(), Position: 1, Lat: 54.85, Lng: 24.20},
+		},
+	}
+	return r, map[uuid.UUID]string{r.Stops[1].ID: "Ona"}
+}
+
+func TestRoute_GeoJSON(t *testing.T) {
+	r, passengers := testRoute()
+	out, err := Route(r, passengers, FormatGeoJSON)
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(out, &fc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 5 {
+		t.Fatalf("got %s with %d features, want FeatureCollection with 5", fc.Type, len(fc.Features))
+	}
+	if fc.Features[0].Geometry.Type != "LineString" {
+		t.Errorf("feature 0 = %s, want the LineString path", fc.Features[0].Geometry.Type)
+	}
+	wantNames := []string{"Vilnius", "Elektrėnai", "Ona", "Kaunas"}
+	for i, want := range wantNames {
+		f := fc.Features[i+1]
+		if f.Geometry.Type != "Point" || f.Properties["name"] != want {
+			t.Errorf("feature %d = %s %v, want Point named %q", i+1, f.Geometry.Type, f.Properties["name"], want)
+		}
+	}
+	if got := string(fc.Features[1].Geometry.Coordinates); got != "[25.28,54.69]" {
+		t.Errorf("start coordinates = %s, want [lng,lat]", got)
+	}
+	if _, ok := fc.Features[2].Properties["passenger"]; ok {
+		t.Error("unowned stop has a passenger label")
+	}
+}
+
+func TestRoute_GPX(t *testing.T) {
+	r, passengers := testRoute()
+	out, err := Route(r, passengers, FormatGPX)
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	var doc gpxDoc
+	if err := xml.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(doc.Route.Points) != 4 {
+		t.Fatalf("got %d route points, want 4", len(doc.Route.Points))
+	}
+	if p := doc.Route.Points[2]; p.Name != "Ona" || p.Type != "stop" || p.Lat != 54.85 {
+		t.Errorf("route point 2 = %+v, want the stop labelled Ona", p)
+	}
+	if doc.Track != nil {
+		t.Error("route without a polyline has a track")
+	}
+}
+
+func TestRoute_UnsupportedFormat(t *testing.T) {
+	r, _ := testRoute()
+	if _, err := Route(r, nil, "kml"); err == nil {
+		t.Error("Route(kml) error = nil, want an error")
+	}
+	if ContentType("kml") != "" {
+		t.Error(`ContentType("kml") != ""`)
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	r, _ := testRoute()
+	leaving := time.Date(2026, 5, 4, 7, 30, 0, 0, time.UTC)
+	arrival := leaving.Add(75 * time.Minute)
+	r.LeavingAt, r.ArrivalETA, r.CreatorName = &leaving, &arrival, "Jonas"
+	r.CreatedAt, r.UpdatedAt = leaving.Add(-48*time.Hour), leaving.Add(-46*time.Hour)
+	cancelled := *r
+	cancelled.ID = uuid.New()
+	cancelled.CancelledAt = &leaving
+	undated := *r
+	undated.ID = uuid.New()
+	undated.LeavingAt = nil
+
+	out := string(Calendar("Rides", []domain.Route{*r, cancelled, undated}, leaving))
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:route-" + r.ID.String() + "@pss-backend\r\n",
+		"DTSTART:20260504T073000Z\r\n",
+		"DTEND:20260504T084500Z\r\n",
+		"LAST-MODIFIED:20260502T093000Z\r\n",
+		"SEQUENCE:7200\r\n",
+		"STATUS:CONFIRMED\r\n",
+		"STATUS:CANCELLED\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Calendar() is missing %q", want)
+		}
+	}
+	if n := strings.Count(out, "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("Calendar() has %d events, want 2 (routes without leaving_at are skipped)", n)
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+}
+
+func TestICSEscape(t *testing.T) {
+	if got, want := icsEscape("a,b;c\\d\ne"), `a\,b\;c\\d\ne`; got != want {
+		t.Errorf("icsEscape() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteICSLine_Folds(t *testing.T) {
+	var b strings.Builder
+	line := "SUMMARY:" + strings.Repeat("ė", 60)
+	writeICSLine(&b, line)
+	parts := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n ")
+	if len(parts) < 2 {
+		t.Fatalf("writeICSLine() did not fold a %d-octet line", len(line))
+	}
+	for _, p := range parts {
+		if len(p)+1 > 75 || !utf8.ValidString(p) {
+			t.Errorf("folded part %q is too long or splits a character", p)
+		}
+	}
+	if strings.Join(parts, "") != line {
+		t.Error("unfolding does not restore the line")
+	}
+}
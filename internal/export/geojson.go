@@ -0,0 +1,56 @@
+package export
+
+import (
+	"encoding/json"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string         `json:"type"`
+	Geometry   geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// geoJSON renders a FeatureCollection with the driving path as a LineString
+// followed by the start, stops and end as Points. Coordinates are [lng, lat].
+func geoJSON(points []waypoint, line []domain.LatLng) ([]byte, error) {
+	coords := make([][2]float64, len(line))
+	for i, p := range line {
+		coords[i] = [2]float64{p.Lng, p.Lat}
+	}
+	fc := featureCollection{Type: "FeatureCollection", Features: make([]feature, 0, len(points)+1)}
+	fc.Features = append(fc.Features, feature{
+		Type:       "Feature",
+		Geometry:   geometry{Type: "LineString", Coordinates: coords},
+		Properties: map[string]any{"kind": "path"},
+	})
+	for _, w := range points {
+		props := map[string]any{"kind": w.kind, "name": w.name()}
+		if w.position != nil {
+			props["position"] = *w.position
+		}
+		if w.address != "" {
+			props["address"] = w.address
+		}
+		if w.passenger != "" {
+			props["passenger"] = w.passenger
+		}
+		fc.Features = append(fc.Features, feature{
+			Type:       "Feature",
+			Geometry:   geometry{Type: "Point", Coordinates: [2]float64{w.lng, w.lat}},
+			Properties: props,
+		})
+	}
+	return json.Marshal(fc)
+}
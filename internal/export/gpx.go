@@ -0,0 +1,61 @@
+package export
+
+import (
+	"encoding/xml"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+type gpxDoc struct {
+	XMLName xml.Name  `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+	Version string    `xml:"version,attr"`
+	Creator string    `xml:"creator,attr"`
+	Route   gpxRoute  `xml:"rte"`
+	Track   *gpxTrack `xml:"trk,omitempty"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name    string     `xml:"name"`
+	Segment []gpxPoint `xml:"trkseg>trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Name string  `xml:"name,omitempty"`
+	Desc string  `xml:"desc,omitempty"`
+	Type string  `xml:"type,omitempty"`
+}
+
+// gpx renders a GPX 1.1 document with the start, stops and end as a route
+// and, when the route has a stored driving path, that path as a track.
+func gpx(r *domain.Route, points []waypoint, line []domain.LatLng) ([]byte, error) {
+	name := r.ID.String()
+	if r.StartFormattedAddress != nil && r.EndFormattedAddress != nil {
+		name = *r.StartFormattedAddress + " – " + *r.EndFormattedAddress
+	}
+	doc := gpxDoc{Version: "1.1", Creator: "pss-backend", Route: gpxRoute{Name: name}}
+	for _, w := range points {
+		p := gpxPoint{Lat: w.lat, Lon: w.lng, Name: w.name(), Type: w.kind}
+		if w.passenger != "" {
+			p.Desc = w.address
+		}
+		doc.Route.Points = append(doc.Route.Points, p)
+	}
+	if r.Polyline != nil && *r.Polyline != "" {
+		doc.Track = &gpxTrack{Name: name}
+		for _, p := range line {
+			doc.Track.Segment = append(doc.Track.Segment, gpxPoint{Lat: p.Lat, Lon: p.Lng})
+		}
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,95 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+// ICSContentType is the media type of Calendar's output.
+const ICSContentType = "text/calendar; charset=utf-8"
+
+const icsTimeFormat = "20060102T150405Z"
+
+// Calendar renders routes as an iCalendar (RFC 5545) feed with one event per
+// dated route. Event UIDs derive from route IDs, so calendar apps update an
+// event in place when its route changes; LAST-MODIFIED and SEQUENCE grow with
+// every change, so they know which version is newer. Cancelled routes keep
+// their event with STATUS:CANCELLED.
+func Calendar(name string, routes []domain.Route, now time.Time) []byte {
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//pss-backend//rides//EN")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	writeICSLine(&b, "X-WR-CALNAME:"+icsEscape(name))
+	for i := range routes {
+		r := &routes[i]
+		if r.LeavingAt == nil {
+			continue
+		}
+		from, to := placeName(r.StartFormattedAddress, r.StartLat, r.StartLng), placeName(r.EndFormattedAddress, r.EndLat, r.EndLng)
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:route-"+r.ID.String()+"@pss-backend")
+		writeICSLine(&b, "DTSTAMP:"+now.UTC().Format(icsTimeFormat))
+		if !r.UpdatedAt.IsZero() {
+			writeICSLine(&b, "LAST-MODIFIED:"+r.UpdatedAt.UTC().Format(icsTimeFormat))
+			writeICSLine(&b, fmt.Sprintf("SEQUENCE:%d", icsSequence(r)))
+		}
+		writeICSLine(&b, "DTSTART:"+r.LeavingAt.UTC().Format(icsTimeFormat))
+		if r.ArrivalETA != nil && r.ArrivalETA.After(*r.LeavingAt) {
+			writeICSLine(&b, "DTEND:"+r.ArrivalETA.UTC().Format(icsTimeFormat))
+		}
+		writeICSLine(&b, "SUMMARY:"+icsEscape("Ride: "+from+" → "+to))
+		writeICSLine(&b, "LOCATION:"+icsEscape(from))
+		writeICSLine(&b, "DESCRIPTION:"+icsEscape(fmt.Sprintf("From %s to %s.\nDriver: %s", from, to, r.CreatorName)))
+		if r.CancelledAt != nil {
+			writeICSLine(&b, "STATUS:CANCELLED")
+		} else {
+			writeICSLine(&b, "STATUS:CONFIRMED")
+		}
+		writeICSLine(&b, "END:VEVENT")
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// icsSequence numbers the versions of a route's event by the seconds between
+// its creation and its last change.
+func icsSequence(r *domain.Route) int64 {
+	return max(0, int64(r.UpdatedAt.Sub(r.CreatedAt)/time.Second))
+}
+
+func placeName(address *string, lat, lng float64) string {
+	if address != nil && *address != "" {
+		return *address
+	}
+	return fmt.Sprintf("%.5f, %.5f", lat, lng)
+}
+
+// icsEscape escapes a TEXT value.
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine writes a content line, folded at 75 octets without splitting
+// UTF-8 sequences, and terminated with CRLF.
+func writeICSLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts toward the limit.
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,14 @@
+package geo
+
+import "math"
+
+// Haversine returns the great-circle distance in kilometres between two points.
+func Haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*
+			math.Sin(dLng/2)*math.Sin(dLng/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,75 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name      string
+		lat1      float64
+		lng1      float64
+		lat2      float64
+		lng2      float64
+		want      float64
+		tolerance float64
+	}{
+		{"same point", 0.0, 0.0, 0.0, 0.0, 0.0, 0.001},
+		{"New York to Los Angeles", 40.7128, -74.0060, 34.0522, -118.2437, 3944.0, 50.0},
+		{"London to Paris", 51.5074, -0.1278, 48.8566, 2.3522, 344.0, 10.0},
+		{"short distance", 0.0, 0.0, 0.1, 0.1, 15.7, 1.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Haversine(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			if math.Abs(got-tt.want) > tt.tolerance {
+				t.Errorf("Haversine() = %v, want %v (tolerance: %v)", got, tt.want, tt.tolerance)
+			}
+		})
+	}
+}
+
+func TestPolyline(t *testing.T) {
+	// Example from Google's polyline algorithm documentation.
+	const encoded = "_p~iF~ps|U_ulLnnqC_mqNvxq`@"
+	want := []domain.LatLng{{Lat: 38.5, Lng: -120.2}, {Lat: 40.7, Lng: -120.95}, {Lat: 43.252, Lng: -126.453}}
+
+	got, err := DecodePolyline(encoded)
+	if err != nil {
+		t.Fatalf("DecodePolyline() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("DecodePolyline() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if math.Abs(got[i].Lat-want[i].Lat) > 1e-9 || math.Abs(got[i].Lng-want[i].Lng) > 1e-9 {
+			t.Errorf("DecodePolyline()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if enc := EncodePolyline(want); enc != encoded {
+		t.Errorf("EncodePolyline() = %q, want %q", enc, encoded)
+	}
+}
+
+func TestDecodePolyline_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr error
+	}{
+		{"truncated", "_p~iF~ps|", ErrPolylineMalformed},
+		{"invalid character", "_p~iF ~ps|U", ErrPolylineMalformed},
+		{"latitude out of range", EncodePolyline([]domain.LatLng{{Lat: 91, Lng: 0}}), ErrPolylineOutOfBounds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodePolyline(tt.in); !errors.Is(err, tt.wantErr) {
+				t.Errorf("DecodePolyline(%q) = %v, want %v", tt.in, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+// Polyline errors returned by DecodePolyline.
+var (
+	ErrPolylineMalformed   = errors.New("polyline: malformed encoding")
+	ErrPolylineOutOfBounds = errors.New("polyline: coordinate out of range")
+)
+
+const polylinePrecision = 1e5
+
+// DecodePolyline decodes a path in Google's encoded polyline format
+// (precision 5), as produced by the Maps JavaScript API and OSRM.
+func DecodePolyline(s string) ([]domain.LatLng, error) {
+	var points []domain.LatLng
+	var lat, lng int64
+	for i := 0; i < len(s); {
+		var deltas [2]int64
+		for k := range deltas {
+			var result int64
+			var shift uint
+			for {
+				if i >= len(s) || shift > 60 {
+					return nil, ErrPolylineMalformed
+				}
+				b := int64(s[i]) - 63
+				i++
+				if b < 0 || b > 63 {
+					return nil, ErrPolylineMalformed
+				}
+				result |= (b & 0x1f) << shift
+				shift += 5
+				if b < 0x20 {
+					break
+				}
+			}
+			if result&1 != 0 {
+				deltas[k] = ^(result >> 1)
+			} else {
+				deltas[k] = result >> 1
+			}
+		}
+		lat += deltas[0]
+		lng += deltas[1]
+		p := domain.LatLng{Lat: float64(lat) / polylinePrecision, Lng: float64(lng) / polylinePrecision}
+		if math.Abs(p.Lat) > 90 || math.Abs(p.Lng) > 180 {
+			return nil, ErrPolylineOutOfBounds
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// EncodePolyline encodes a path in Google's encoded polyline format (precision 5).
+func EncodePolyline(points []domain.LatLng) string {
+	var b strings.Builder
+	var prevLat, prevLng int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Lat * polylinePrecision))
+		lng := int64(math.Round(p.Lng * polylinePrecision))
+		encodePolylineValue(&b, lat-prevLat)
+		encodePolylineValue(&b, lng-prevLng)
+		prevLat, prevLng = lat, lng
+	}
+	return b.String()
+}
+
+func encodePolylineValue(b *strings.Builder, v int64) {
+	u := v << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		b.WriteByte(byte((0x20 | (u & 0x1f)) + 63))
+		u >>= 5
+	}
+	b.WriteByte(byte(u + 63))
+}
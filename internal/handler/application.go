@@ -39,7 +39,7 @@ func (h *ApplicationHandler) Apply(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
-	appID, err := h.svc.Apply(r.Context(), u.ID, routeID, in)
+	app, err := h.svc.Apply(r.Context(), u.ID, routeID, in)
 	if err != nil {
 		switch {
 		case errors.Is(err, errs.ErrNotFound):
@@ -52,13 +52,47 @@ func (h *ApplicationHandler) Apply(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "route is full", http.StatusConflict)
 		case errors.Is(err, errs.ErrAlreadyApplied):
 			http.Error(w, "already applied to this route", http.StatusConflict)
+		case errors.Is(err, errs.ErrInvalidStops), errors.Is(err, errs.ErrExceedsRoute):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		default:
 			h.log.Error("apply to route", slog.Any("error", err))
 			http.Error(w, "failed to apply", http.StatusInternalServerError)
 		}
 		return
 	}
-	writeJSON(w, http.StatusCreated, map[string]string{"id": appID.String()})
+	writeJSON(w, http.StatusCreated, app)
+}
+
+// Preview handles POST /routes/{id}/applications/preview
+func (h *ApplicationHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	routeID, ok := parseUUIDPath(w, r, "id")
+	if !ok {
+		return
+	}
+	var in domain.ApplicationPreviewInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	preview, err := h.svc.Preview(r.Context(), routeID, in)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			http.Error(w, "route not found", http.StatusNotFound)
+		case errors.Is(err, errs.ErrInvalidStops):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		default:
+			h.log.Error("preview application", slog.Any("error", err))
+			http.Error(w, "failed to preview application", http.StatusInternalServerError)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, preview)
 }
 
 // ListByRoute handles GET /routes/{id}/applications
@@ -121,6 +155,8 @@ func (h *ApplicationHandler) ReviewApplication(w http.ResponseWriter, r *http.Re
 			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
 		case errors.Is(err, errs.ErrConflict):
 			http.Error(w, "application is not in pending state", http.StatusConflict)
+		case errors.Is(err, errs.ErrDetourBudget), errors.Is(err, errs.ErrRouteFull):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
 		default:
 			h.log.Error("review application", slog.Any("error", err))
 			http.Error(w, "failed to review application", http.StatusInternalServerError)
@@ -160,6 +196,90 @@ func (h *ApplicationHandler) Cancel(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Leave handles POST /routes/{id}/applications/{appId}/leave
+func (h *ApplicationHandler) Leave(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	appID, ok := parseUUIDPath(w, r, "appId")
+	if !ok {
+		return
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.svc.Leave(r.Context(), appID, u.ID, body.Reason); err != nil {
+		switch {
+		case errors.Is(err, errs.ErrInvalidReason):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case errors.Is(err, errs.ErrNotFound):
+			http.Error(w, "application not found", http.StatusNotFound)
+		case errors.Is(err, errs.ErrRouteStarted):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "route has already started"})
+		case errors.Is(err, errs.ErrForbidden):
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		case errors.Is(err, errs.ErrConflict):
+			http.Error(w, "only approved passengers can leave a route", http.StatusConflict)
+		default:
+			h.log.Error("leave route", slog.Any("error", err))
+			http.Error(w, "failed to leave route", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveParticipant handles POST /routes/{id}/participants/{userId}/remove
+func (h *ApplicationHandler) RemoveParticipant(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	routeID, ok := parseUUIDPath(w, r, "id")
+	if !ok {
+		return
+	}
+	userID, ok := parseUUIDPath(w, r, "userId")
+	if !ok {
+		return
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.svc.Remove(r.Context(), routeID, userID, u.ID, body.Reason); err != nil {
+		switch {
+		case errors.Is(err, errs.ErrInvalidReason):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case errors.Is(err, errs.ErrNotFound):
+			http.Error(w, "route not found", http.StatusNotFound)
+		case errors.Is(err, errs.ErrNotParticipant):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not a passenger of this route"})
+		case errors.Is(err, errs.ErrRouteStarted):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "route has already started"})
+		case errors.Is(err, errs.ErrForbidden):
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		case errors.Is(err, errs.ErrConflict):
+			http.Error(w, "only approved passengers can be removed", http.StatusConflict)
+		default:
+			h.log.Error("remove participant", slog.Any("error", err))
+			http.Error(w, "failed to remove participant", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // CancelStopChange handles DELETE /routes/{id}/applications/{appId}/stop-change
 func (h *ApplicationHandler) CancelStopChange(w http.ResponseWriter, r *http.Request) {
 	u := middleware.GetUser(r.Context())
@@ -214,6 +334,37 @@ func (h *ApplicationHandler) GetMyForRoute(w http.ResponseWriter, r *http.Reques
 	writeJSON(w, http.StatusOK, app)
 }
 
+// History handles GET /routes/{id}/applications/{appId}/history
+func (h *ApplicationHandler) History(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	routeID, ok := parseUUIDPath(w, r, "id")
+	if !ok {
+		return
+	}
+	appID, ok := parseUUIDPath(w, r, "appId")
+	if !ok {
+		return
+	}
+	events, err := h.svc.History(r.Context(), routeID, appID, u.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			http.Error(w, "application not found", http.StatusNotFound)
+		case errors.Is(err, errs.ErrForbidden):
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		default:
+			h.log.Error("application history", slog.Any("error", err))
+			http.Error(w, "failed to get application history", http.StatusInternalServerError)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
 // UpdateMyStops handles PATCH /routes/{id}/applications/{appId}/stops
 func (h *ApplicationHandler) UpdateMyStops(w http.ResponseWriter, r *http.Request) {
 	u := middleware.GetUser(r.Context())
@@ -243,6 +394,8 @@ func (h *ApplicationHandler) UpdateMyStops(w http.ResponseWriter, r *http.Reques
 			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
 		case errors.Is(err, errs.ErrConflict):
 			http.Error(w, "application cannot be edited once accepted or rejected", http.StatusConflict)
+		case errors.Is(err, errs.ErrInvalidStops):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		default:
 			h.log.Error("update application stops", slog.Any("error", err))
 			http.Error(w, "failed to update stops", http.StatusInternalServerError)
@@ -281,6 +434,8 @@ func (h *ApplicationHandler) RequestStopChange(w http.ResponseWriter, r *http.Re
 			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
 		case errors.Is(err, errs.ErrConflict):
 			http.Error(w, "application is not approved", http.StatusConflict)
+		case errors.Is(err, errs.ErrInvalidStops):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		default:
 			h.log.Error("request stop change", slog.Any("error", err))
 			http.Error(w, "failed to submit stop change request", http.StatusInternalServerError)
@@ -318,6 +473,8 @@ func (h *ApplicationHandler) ReviewStopChange(w http.ResponseWriter, r *http.Req
 			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
 		case errors.Is(err, errs.ErrConflict):
 			http.Error(w, "no pending stop change request", http.StatusConflict)
+		case errors.Is(err, errs.ErrDetourBudget):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
 		default:
 			h.log.Error("review stop change", slog.Any("error", err))
 			http.Error(w, "failed to review stop change", http.StatusInternalServerError)
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/export"
+	"github.com/jmartynas/pss-backend/internal/middleware"
+	"github.com/jmartynas/pss-backend/internal/service"
+)
+
+// CalendarHandler handles the ICS feed endpoints.
+type CalendarHandler struct {
+	svc *service.CalendarService
+	log *slog.Logger
+}
+
+// NewCalendarHandler creates a CalendarHandler backed by the given service.
+func NewCalendarHandler(svc *service.CalendarService, log *slog.Logger) *CalendarHandler {
+	return &CalendarHandler{svc: svc, log: log}
+}
+
+// Feed handles GET /calendar/{token}.ics. The token is the only credential,
+// so calendar apps can subscribe without a session.
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutSuffix(r.PathValue("token"), ".ics")
+	if !ok || token == "" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	body, err := h.svc.Feed(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error("calendar feed", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", export.ICSContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(body) //nolint:errcheck
+}
+
+// RotateToken handles POST /users/me/calendar-token. It returns the new feed
+// path; the previous one stops working.
+func (h *CalendarHandler) RotateToken(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	token, err := h.svc.RotateToken(r.Context(), u.ID)
+	if err != nil {
+		h.log.Error("rotate calendar token", slog.Any("error", err))
+		http.Error(w, "failed to rotate calendar token", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token, "path": "/calendar/" + token + ".ics"})
+}
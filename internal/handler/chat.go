@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
 	"github.com/jmartynas/pss-backend/internal/hub"
 	"github.com/jmartynas/pss-backend/internal/middleware"
 )
@@ -155,6 +157,10 @@ func (h *ChatHandler) SendPrivateMessage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	id, err := h.repo.SendPrivateMessage(r.Context(), chatID, u.ID, strings.TrimSpace(in.Message))
+	if errors.Is(err, errs.ErrChatClosed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		h.log.Error("send private message", slog.Any("error", err))
 		http.Error(w, "internal error", http.StatusInternalServerError)
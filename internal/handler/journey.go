@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/service"
+)
+
+// JourneyHandler handles journey planning endpoints.
+type JourneyHandler struct {
+	svc *service.JourneyService
+	log *slog.Logger
+}
+
+// NewJourneyHandler creates a JourneyHandler backed by the given service.
+func NewJourneyHandler(svc *service.JourneyService, log *slog.Logger) *JourneyHandler {
+	return &JourneyHandler{svc: svc, log: log}
+}
+
+// Search handles POST /journeys/search
+func (h *JourneyHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var in domain.JourneySearchInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	journeys, err := h.svc.Search(r.Context(), in)
+	if errors.Is(err, errs.ErrInvalidSearch) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log.Error("search journeys", slog.Any("error", err))
+		http.Error(w, "failed to search journeys", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, journeys)
+}
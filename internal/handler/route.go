@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmartynas/pss-backend/internal/domain"
 	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/export"
 	"github.com/jmartynas/pss-backend/internal/middleware"
 	"github.com/jmartynas/pss-backend/internal/service"
 )
@@ -60,6 +61,15 @@ func (h *RouteHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id, err := h.svc.Create(r.Context(), u.ID, in)
+	if errors.Is(err, errs.ErrInvalidPolyline) || errors.Is(err, errs.ErrInvalidReturnLeg) || errors.Is(err, errs.ErrInvalidPricing) ||
+		errors.Is(err, errs.ErrExceedsVehicle) || errors.Is(err, errs.ErrAutoApproveRule) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errs.ErrVehicleNotOwned) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		h.log.Error("create route", slog.Any("error", err))
 		http.Error(w, "failed to create route", http.StatusInternalServerError)
@@ -91,6 +101,11 @@ func (h *RouteHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusConflict, map[string]string{"error": "route has already started and cannot be modified"})
 		case errors.Is(err, errs.ErrForbidden):
 			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		case errors.Is(err, errs.ErrInvalidPolyline), errors.Is(err, errs.ErrInvalidPricing), errors.Is(err, errs.ErrExceedsVehicle),
+			errors.Is(err, errs.ErrAutoApproveRule), errors.Is(err, errs.ErrInvalidReturnLeg):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case errors.Is(err, errs.ErrBelowBooked):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
 		default:
 			h.log.Error("update route", slog.String("id", id.String()), slog.Any("error", err))
 			http.Error(w, "internal error", http.StatusInternalServerError)
@@ -139,16 +154,20 @@ func (h *RouteHandler) SearchRoutes(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
-	routes, err := h.svc.Search(r.Context(), in)
+	page, err := h.svc.Search(r.Context(), in)
+	if errors.Is(err, errs.ErrInvalidSearch) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		h.log.Error("search routes", slog.Any("error", err))
 		http.Error(w, "failed to search routes", http.StatusInternalServerError)
 		return
 	}
-	if routes == nil {
-		routes = []domain.Route{}
+	if page.Items == nil {
+		page.Items = []domain.Route{}
 	}
-	writeJSON(w, http.StatusOK, routes)
+	writeJSON(w, http.StatusOK, page)
 }
 
 func (h *RouteHandler) GetMyRoutes(w http.ResponseWriter, r *http.Request) {
@@ -189,6 +208,44 @@ func (h *RouteHandler) GetMyParticipatedRoutes(w http.ResponseWriter, r *http.Re
 	writeJSON(w, http.StatusOK, routes)
 }
 
+// ExportRoute handles GET /routes/{id}/export?format=geojson|gpx
+func (h *RouteHandler) ExportRoute(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	id, ok := parseUUIDPath(w, r, "id")
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatGeoJSON
+	}
+	contentType := export.ContentType(format)
+	if contentType == "" {
+		http.Error(w, "format must be geojson or gpx", http.StatusBadRequest)
+		return
+	}
+	body, err := h.svc.Export(r.Context(), id, u.ID, format)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			http.Error(w, "not found", http.StatusNotFound)
+		case errors.Is(err, errs.ErrForbidden):
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		default:
+			h.log.Error("export route", slog.String("id", id.String()), slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="route-`+id.String()+"."+format+`"`)
+	w.Write(body) //nolint:errcheck
+}
+
 func (h *RouteHandler) GetMyReviews(w http.ResponseWriter, r *http.Request) {
 	u := middleware.GetUser(r.Context())
 	if u == nil {
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/middleware"
+	"github.com/jmartynas/pss-backend/internal/service"
+)
+
+// SavedSearchHandler handles saved search endpoints.
+type SavedSearchHandler struct {
+	svc *service.SavedSearchService
+	log *slog.Logger
+}
+
+// NewSavedSearchHandler creates a SavedSearchHandler backed by the given service.
+func NewSavedSearchHandler(svc *service.SavedSearchService, log *slog.Logger) *SavedSearchHandler {
+	return &SavedSearchHandler{svc: svc, log: log}
+}
+
+// ListMy handles GET /saved-searches/my
+func (h *SavedSearchHandler) ListMy(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	searches, err := h.svc.ListByUser(r.Context(), u.ID)
+	if err != nil {
+		h.log.Error("list saved searches", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, searches)
+}
+
+// Create handles POST /saved-searches. The body is a search query as for
+// POST /routes/search; leaving_before is required.
+func (h *SavedSearchHandler) Create(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	var in domain.SearchRouteInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	id, err := h.svc.Create(r.Context(), u.ID, in)
+	if errors.Is(err, errs.ErrInvalidSearch) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log.Error("create saved search", slog.Any("error", err))
+		http.Error(w, "failed to save search", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"id": id.String()})
+}
+
+// Delete handles DELETE /saved-searches/{id}
+func (h *SavedSearchHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	id, ok := parseUUIDPath(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.svc.Delete(r.Context(), id, u.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errs.ErrForbidden) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		h.log.Error("delete saved search", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/middleware"
+	"github.com/jmartynas/pss-backend/internal/service"
+)
+
+// ScheduleHandler handles recurring route endpoints.
+type ScheduleHandler struct {
+	svc *service.ScheduleService
+	log *slog.Logger
+}
+
+// NewScheduleHandler creates a ScheduleHandler backed by the given service.
+func NewScheduleHandler(svc *service.ScheduleService, log *slog.Logger) *ScheduleHandler {
+	return &ScheduleHandler{svc: svc, log: log}
+}
+
+// ListMy handles GET /schedules/my
+func (h *ScheduleHandler) ListMy(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	schedules, err := h.svc.ListByCreator(r.Context(), u.ID)
+	if err != nil {
+		h.log.Error("list schedules", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+// Create handles POST /schedules
+func (h *ScheduleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	var in domain.CreateScheduleInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	id, err := h.svc.Create(r.Context(), u.ID, in)
+	if errors.Is(err, errs.ErrInvalidSchedule) || errors.Is(err, errs.ErrInvalidPolyline) || errors.Is(err, errs.ErrInvalidReturnLeg) || errors.Is(err, errs.ErrInvalidPricing) ||
+		errors.Is(err, errs.ErrExceedsVehicle) || errors.Is(err, errs.ErrAutoApproveRule) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errs.ErrVehicleNotOwned) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log.Error("create schedule", slog.Any("error", err))
+		http.Error(w, "failed to create schedule", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"id": id.String()})
+}
+
+// Update handles PATCH /schedules/{id}
+func (h *ScheduleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	id, ok := parseUUIDPath(w, r, "id")
+	if !ok {
+		return
+	}
+	var in domain.UpdateScheduleInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.svc.Update(r.Context(), id, u.ID, in); err != nil {
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			http.Error(w, "not found", http.StatusNotFound)
+		case errors.Is(err, errs.ErrForbidden):
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		case errors.Is(err, errs.ErrVehicleNotOwned):
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		case errors.Is(err, errs.ErrInvalidSchedule), errors.Is(err, errs.ErrInvalidPolyline), errors.Is(err, errs.ErrInvalidReturnLeg), errors.Is(err, errs.ErrInvalidPricing),
+			errors.Is(err, errs.ErrExceedsVehicle), errors.Is(err, errs.ErrAutoApproveRule):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		default:
+			h.log.Error("update schedule", slog.String("id", id.String()), slog.Any("error", err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	schedule, err := h.svc.GetByID(r.Context(), id)
+	if err != nil {
+		h.log.Error("get schedule after update", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+// Delete handles DELETE /schedules/{id}
+func (h *ScheduleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	u := middleware.GetUser(r.Context())
+	if u == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	id, ok := parseUUIDPath(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.svc.Delete(r.Context(), id, u.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errs.ErrForbidden) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		h.log.Error("delete schedule", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
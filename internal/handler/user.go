@@ -36,6 +36,8 @@ type meResponse struct {
 	Status    string    `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// CalendarToken is the secret of the user's ICS feed, if generated.
+	CalendarToken *string `json:"calendar_token,omitempty"`
 }
 
 type reviewResponse struct {
@@ -70,13 +72,14 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	writeJSON(w, http.StatusOK, meResponse{
-		ID:        fresh.ID.String(),
-		Email:     fresh.Email,
-		Name:      fresh.Name,
-		Provider:  fresh.Provider,
-		Status:    fresh.Status,
-		CreatedAt: fresh.CreatedAt,
-		UpdatedAt: fresh.UpdatedAt,
+		ID:            fresh.ID.String(),
+		Email:         fresh.Email,
+		Name:          fresh.Name,
+		Provider:      fresh.Provider,
+		Status:        fresh.Status,
+		CreatedAt:     fresh.CreatedAt,
+		UpdatedAt:     fresh.UpdatedAt,
+		CalendarToken: fresh.CalendarToken,
 	})
 }
 
@@ -106,13 +109,14 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	writeJSON(w, http.StatusOK, meResponse{
-		ID:        fresh.ID.String(),
-		Email:     fresh.Email,
-		Name:      fresh.Name,
-		Provider:  fresh.Provider,
-		Status:    fresh.Status,
-		CreatedAt: fresh.CreatedAt,
-		UpdatedAt: fresh.UpdatedAt,
+		ID:            fresh.ID.String(),
+		Email:         fresh.Email,
+		Name:          fresh.Name,
+		Provider:      fresh.Provider,
+		Status:        fresh.Status,
+		CreatedAt:     fresh.CreatedAt,
+		UpdatedAt:     fresh.UpdatedAt,
+		CalendarToken: fresh.CalendarToken,
 	})
 }
 
@@ -0,0 +1,135 @@
+// Package recurrence implements the subset of iCalendar RRULEs (RFC 5545)
+// used by route schedules: FREQ=DAILY or FREQ=WEEKLY with optional INTERVAL
+// and BYDAY. The end of a series is stored separately, so UNTIL and COUNT
+// are not accepted.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRule is returned by Parse for rules outside the supported subset.
+var ErrInvalidRule = errors.New("recurrence: invalid rule")
+
+// Frequency is the base unit a rule repeats in.
+type Frequency string
+
+const (
+	Daily  Frequency = "DAILY"
+	Weekly Frequency = "WEEKLY"
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// ByDay limits a weekly rule to these weekdays. Empty means the weekday
+	// of the first occurrence.
+	ByDay []time.Weekday
+}
+
+// Parse parses a rule such as "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR". An optional
+// "RRULE:" prefix is ignored.
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, fmt.Errorf("%w: empty", ErrInvalidRule)
+	}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, fmt.Errorf("%w: %q is not KEY=VALUE", ErrInvalidRule, part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch f := Frequency(strings.ToUpper(value)); f {
+			case Daily, Weekly:
+				r.Freq = f
+			default:
+				return r, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRule, value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("%w: INTERVAL must be a positive integer", ErrInvalidRule)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[strings.ToUpper(d)]
+				if !ok {
+					return r, fmt.Errorf("%w: unknown BYDAY %q", ErrInvalidRule, d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		default:
+			return r, fmt.Errorf("%w: unsupported part %q", ErrInvalidRule, key)
+		}
+	}
+	if r.Freq == "" {
+		return r, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if r.Freq == Daily && len(r.ByDay) > 0 {
+		return r, fmt.Errorf("%w: BYDAY requires FREQ=WEEKLY", ErrInvalidRule)
+	}
+	return r, nil
+}
+
+// Between returns the occurrences of the series that starts at first and
+// fall in (after, until]. Occurrences keep first's wall-clock time in first's
+// location, so a 07:30 commute stays at 07:30 across DST changes.
+func (r Rule) Between(first, after, until time.Time) []time.Time {
+	var out []time.Time
+	loc := first.Location()
+	y, m, d := first.Date()
+	// Start a day before after to cover offset differences between zones.
+	skip := 0
+	if days := int(after.Sub(first).Hours() / 24); days > 1 {
+		skip = days - 1
+	}
+	for i := skip; ; i++ {
+		at := time.Date(y, m, d+i, first.Hour(), first.Minute(), first.Second(), 0, loc)
+		if at.After(until) {
+			return out
+		}
+		if at.After(after) && !at.Before(first) && r.matches(i, at.Weekday(), first.Weekday()) {
+			out = append(out, at)
+		}
+	}
+}
+
+// matches reports whether the day offset days after the first occurrence,
+// falling on weekday wd, is part of the series.
+func (r Rule) matches(offset int, wd, firstWd time.Weekday) bool {
+	if r.Freq == Daily {
+		return offset%r.Interval == 0
+	}
+	// Weeks start on Monday (RFC 5545 WKST default).
+	week := (offset + mondayIndex(firstWd)) / 7
+	if week%r.Interval != 0 {
+		return false
+	}
+	if len(r.ByDay) == 0 {
+		return wd == firstWd
+	}
+	for _, d := range r.ByDay {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func mondayIndex(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
@@ -0,0 +1,86 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rule
+		wantErr bool
+	}{
+		{in: "FREQ=DAILY", want: Rule{Freq: Daily, Interval: 1}},
+		{in: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", want: Rule{Freq: Weekly, Interval: 2, ByDay: []time.Weekday{time.Monday, time.Friday}}},
+		{in: "", wantErr: true},
+		{in: "INTERVAL=2", wantErr: true},
+		{in: "FREQ=MONTHLY", wantErr: true},
+		{in: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{in: "FREQ=WEEKLY;COUNT=5", wantErr: true},
+		{in: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{in: "FREQ=DAILY;BYDAY=MO", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRule) {
+					t.Errorf("Parse(%q) error = %v, want ErrInvalidRule", tt.in, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.in, err)
+			}
+			if got.Freq != tt.want.Freq || got.Interval != tt.want.Interval || len(got.ByDay) != len(tt.want.ByDay) {
+				t.Fatalf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			for i := range got.ByDay {
+				if got.ByDay[i] != tt.want.ByDay[i] {
+					t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRule_Between(t *testing.T) {
+	vilnius, err := time.LoadLocation("Europe/Vilnius")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	// Thursday 2026-03-26 07:30 local; DST starts on Sunday 2026-03-29.
+	first := time.Date(2026, 3, 26, 7, 30, 0, 0, vilnius)
+
+	weekdays, _ := Parse("FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR")
+	got := weekdays.Between(first, first.Add(-time.Minute), first.AddDate(0, 0, 7))
+	want := []string{"2026-03-26", "2026-03-27", "2026-03-30", "2026-03-31", "2026-04-01", "2026-04-02"}
+	if len(got) != len(want) {
+		t.Fatalf("Between() = %v, want dates %v", got, want)
+	}
+	for i, at := range got {
+		if at.Format("2006-01-02") != want[i] || at.Hour() != 7 || at.Minute() != 30 {
+			t.Errorf("Between()[%d] = %v, want %s 07:30", i, at, want[i])
+		}
+	}
+
+	// after is exclusive, until inclusive.
+	got = weekdays.Between(first, first, first.AddDate(0, 0, 1))
+	if len(got) != 1 || !got[0].Equal(first.AddDate(0, 0, 1)) {
+		t.Errorf("Between(exclusive after) = %v, want only %v", got, first.AddDate(0, 0, 1))
+	}
+
+	everyOtherWeek, _ := Parse("FREQ=WEEKLY;INTERVAL=2")
+	got = everyOtherWeek.Between(first, first.Add(-time.Minute), first.AddDate(0, 0, 35))
+	if len(got) != 3 || !got[1].Equal(first.AddDate(0, 0, 14)) {
+		t.Errorf("Between(INTERVAL=2) = %v, want 3 occurrences two weeks apart", got)
+	}
+
+	everyThirdDay, _ := Parse("FREQ=DAILY;INTERVAL=3")
+	got = everyThirdDay.Between(first, first.AddDate(0, 0, 4), first.AddDate(0, 0, 10))
+	if len(got) != 2 || !got[0].Equal(first.AddDate(0, 0, 6)) {
+		t.Errorf("Between(DAILY;INTERVAL=3) = %v, want days 6 and 9", got)
+	}
+}
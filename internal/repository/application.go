@@ -24,19 +24,61 @@ func NewApplicationRepository(db *sql.DB, nc *nats.Conn) domain.ApplicationRepos
 	return &applicationRepository{db: db, nc: nc}
 }
 
-// Create inserts a participant row (status='pending'), a request row, and its request_stops,
-// all inside a single transaction so partial writes are never committed.
-func (r *applicationRepository) Create(ctx context.Context, userID, routeID uuid.UUID, in domain.ApplyInput) (uuid.UUID, error) {
+// Create inserts a participant row (status='pending' or 'waitlisted'), a request row, and its
+// request_stops, all inside a single transaction so partial writes are never committed.
+func (r *applicationRepository) Create(ctx context.Context, userID, routeID uuid.UUID, status string, in domain.ApplyInput) (uuid.UUID, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("application create: begin tx: %w", err)
 	}
 	defer tx.Rollback() //nolint:errcheck
 
+	participantID, err := insertApplication(ctx, tx, userID, routeID, status, in)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("application create: commit: %w", err)
+	}
+	return participantID, nil
+}
+
+// CreateApproved inserts an application like Create and approves it like
+// ReviewUpdate, in one transaction.
+func (r *applicationRepository) CreateApproved(ctx context.Context, userID, routeID uuid.UUID, in domain.ApplyInput, detourKm float64) (uuid.UUID, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("application create: begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	participantID, err := insertApplication(ctx, tx, userID, routeID, "pending", in)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	emailLogID, err := approveApplication(ctx, tx, participantID, routeID, nil, detourKm, actorSystem)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("application create: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("application create: commit: %w", err)
+	}
+	publishEmailLog(r.nc, emailLogID, "application_approved")
+	return participantID, nil
+}
+
+// insertApplication writes the participant, request, request_stops and
+// private chat rows of a new application.
+func insertApplication(ctx context.Context, tx *sql.Tx, userID, routeID uuid.UUID, status string, in domain.ApplyInput) (uuid.UUID, error) {
 	participantID := uuid.New()
-	_, err = sq.Insert("participants").
-		Columns("id", "user_id", "route_id", "status").
-		Values(participantID.String(), userID.String(), routeID.String(), "pending").
+	// Waitlisted applications start waiting for the driver once promoted.
+	var awaitingSince any
+	if status == "pending" {
+		awaitingSince = sq.Expr("NOW()")
+	}
+	_, err := sq.Insert("participants").
+		Columns("id", "user_id", "route_id", "status", "seats", "luggage", "awaiting_since").
+		Values(participantID.String(), userID.String(), routeID.String(), status, in.Seats, in.Luggage, awaitingSince).
 		RunWith(tx).ExecContext(ctx)
 	if err != nil {
 		var mysqlErr *mysql.MySQLError
@@ -64,6 +106,15 @@ func (r *applicationRepository) Create(ctx context.Context, userID, routeID uuid
 			return uuid.Nil, fmt.Errorf("application create stop %d: %w", i, err)
 		}
 	}
+	err = recordApplicationEvent(ctx, tx, participantID.String(), applicationEvent{
+		eventType:  domain.EventSubmitted,
+		actor:      actorApplicant,
+		newStatus:  status,
+		stopsAfter: inputStopsSnapshot(in.Stops),
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("application create: %w", err)
+	}
 
 	// Create the private chat between the driver and this applicant on application submit.
 	var driverParticipantID string
@@ -80,24 +131,20 @@ func (r *applicationRepository) Create(ctx context.Context, userID, routeID uuid
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("application create: create private chat: %w", err)
 	}
-
-	if err = tx.Commit(); err != nil {
-		return uuid.Nil, fmt.Errorf("application create: commit: %w", err)
-	}
 	return participantID, nil
 }
 
 func (r *applicationRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Application, error) {
 	var a domain.Application
 	var idStr, userIDStr, routeIDStr string
-	err := sq.Select("p.id", "p.user_id", "COALESCE(u.name, u.email, '')", "p.route_id", "p.status", "req.comment", "p.created_at", "p.pending_stop_change").
+	err := sq.Select("p.id", "p.user_id", "COALESCE(u.name, u.email, '')", "p.route_id", "p.status", "req.comment", "p.created_at", "p.pending_stop_change", "p.rejection_reason", "p.leave_reason", "p.seats", "p.luggage").
 		From("participants p").
 		Join("users u ON u.id = p.user_id").
 		LeftJoin("requests req ON req.participant_id = p.id").
 		Where(sq.Eq{"p.id": id.String(), "p.deleted_at": nil}).
 		Where("p.status != 'driver'").
 		RunWith(r.db).QueryRowContext(ctx).
-		Scan(&idStr, &userIDStr, &a.UserName, &routeIDStr, &a.Status, &a.Comment, &a.CreatedAt, &a.PendingStopChange)
+		Scan(&idStr, &userIDStr, &a.UserName, &routeIDStr, &a.Status, &a.Comment, &a.CreatedAt, &a.PendingStopChange, &a.RejectionReason, &a.LeaveReason, &a.Seats, &a.Luggage)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, errs.ErrNotFound
 	}
@@ -137,7 +184,7 @@ func (r *applicationRepository) GetByUserAndRoute(ctx context.Context, userID, r
 }
 
 func (r *applicationRepository) ListByRoute(ctx context.Context, routeID uuid.UUID) ([]domain.Application, error) {
-	rows, err := sq.Select("p.id", "p.user_id", "COALESCE(u.name, u.email, '')", "p.route_id", "p.status", "req.comment", "p.created_at", "p.pending_stop_change").
+	rows, err := sq.Select("p.id", "p.user_id", "COALESCE(u.name, u.email, '')", "p.route_id", "p.status", "req.comment", "p.created_at", "p.pending_stop_change", "p.rejection_reason", "p.leave_reason", "p.seats", "p.luggage").
 		From("participants p").
 		Join("users u ON u.id = p.user_id").
 		LeftJoin("requests req ON req.participant_id = p.id").
@@ -154,8 +201,8 @@ func (r *applicationRepository) ListByRoute(ctx context.Context, routeID uuid.UU
 
 func (r *applicationRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Application, error) {
 	rows, err := sq.Select(
-		"p.id", "p.user_id", "COALESCE(u.name, u.email, '')", "p.route_id", "p.status", "req.comment", "p.created_at", "p.pending_stop_change",
-		"ro.leaving_at", "ro.start_formatted_address", "ro.end_formatted_address",
+		"p.id", "p.user_id", "COALESCE(u.name, u.email, '')", "p.route_id", "p.status", "req.comment", "p.created_at", "p.pending_stop_change", "p.rejection_reason", "p.leave_reason", "p.seats", "p.luggage",
+		"ro.leaving_at", "ro.start_formatted_address", "ro.end_formatted_address", "p.price_share",
 	).
 		From("participants p").
 		Join("users u ON u.id = p.user_id").
@@ -164,7 +211,7 @@ func (r *applicationRepository) ListByUser(ctx context.Context, userID uuid.UUID
 		Where(sq.Eq{"p.user_id": userID.String()}).
 		Where(sq.Or{
 			sq.Eq{"p.deleted_at": nil},
-			sq.Eq{"p.status": "left"},
+			sq.Eq{"p.status": []string{"left", "removed"}},
 		}).
 		Where("p.status != 'driver'").
 		OrderBy("p.created_at DESC").
@@ -176,130 +223,362 @@ func (r *applicationRepository) ListByUser(ctx context.Context, userID uuid.UUID
 	return scanUserApplicationsWithStops(ctx, r.db, rows)
 }
 
+func (r *applicationRepository) CountCompletedRides(ctx context.Context, userID uuid.UUID) (int, error) {
+	var n int
+	err := sq.Select("COUNT(*)").
+		From("participants p").
+		Join("routes r ON r.id = p.route_id").
+		Where(sq.Eq{"p.user_id": userID.String(), "p.status": "approved", "p.deleted_at": nil, "r.deleted_at": nil}).
+		Where("r.leaving_at < NOW()").
+		RunWith(r.db).QueryRowContext(ctx).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("application count completed rides: %w", err)
+	}
+	return n, nil
+}
+
 // ReviewUpdate updates participant status. When approved, replaces the route's stops
-// with the full ordered stop list from the request.
-func (r *applicationRepository) ReviewUpdate(ctx context.Context, id uuid.UUID, status string, appUserID, routeID uuid.UUID) error {
+// with the full ordered stop list from the request. The status is only changed
+// while the application is still pending, checked inside the transaction.
+func (r *applicationRepository) ReviewUpdate(ctx context.Context, id uuid.UUID, status string, appUserID, routeID uuid.UUID, stops []domain.ApplicationStopInput, detourKm float64) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("application review: begin tx: %w", err)
 	}
 	defer tx.Rollback() //nolint:errcheck
 
-	_, err = sq.Update("participants").
+	if status == "approved" {
+		emailLogID, err := approveApplication(ctx, tx, id, routeID, stops, detourKm, actorDriver)
+		if err != nil {
+			return fmt.Errorf("application review: %w", err)
+		}
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("application review: commit: %w", err)
+		}
+		publishEmailLog(r.nc, emailLogID, "application_approved")
+		return nil
+	}
+
+	res, err := sq.Update("participants").
 		Set("status", status).
-		Where(sq.Eq{"id": id.String()}).
+		Set("detour_km", detourKm).
+		Set("awaiting_since", nil).
+		Where(sq.Eq{"id": id.String(), "status": "pending", "deleted_at": nil}).
 		RunWith(tx).ExecContext(ctx)
 	if err != nil {
 		return fmt.Errorf("application review: update status: %w", err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errs.ErrConflict
+	}
+	err = recordApplicationEvent(ctx, tx, id.String(), applicationEvent{
+		eventType: domain.EventRejected,
+		actor:     actorDriver,
+		oldStatus: "pending",
+		newStatus: status,
+	})
+	if err != nil {
+		return fmt.Errorf("application review: %w", err)
+	}
+	// A rejected applicant no longer holds a place for the waitlist.
+	promoted, err := promoteWaitlisted(ctx, tx, routeID.String())
+	if err != nil {
+		return fmt.Errorf("application review: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("application review: commit: %w", err)
+	}
+	publishPromotions(r.nc, promoted)
+	return nil
+}
 
-	if status == "approved" {
-		// Snapshot participant_id for every existing route stop before we replace them.
-		pRows, err := sq.Select("id", "participant_id").
-			From("route_stops").
-			Where(sq.Eq{"route_id": routeID.String()}).
-			RunWith(tx).QueryContext(ctx)
-		if err != nil {
-			return fmt.Errorf("application review: snapshot route stops: %w", err)
-		}
-		participantByStopID := make(map[string]*string)
-		for pRows.Next() {
-			var stopID string
-			var pID *string
-			if err := pRows.Scan(&stopID, &pID); err != nil {
-				pRows.Close()
-				return fmt.Errorf("application review: scan route stop: %w", err)
-			}
-			participantByStopID[stopID] = pID
-		}
-		pRows.Close()
-		if err := pRows.Err(); err != nil {
-			return fmt.Errorf("application review: read route stops: %w", err)
+// approveApplication marks application id approved with its detour, replaces
+// the route's stops with the full ordered stop list from its request and
+// queues the approval email. stops, when not nil, first replaces the
+// request's stop list. actor is who approved it, for the history. It returns
+// the email_log ID to publish after commit.
+func approveApplication(ctx context.Context, tx *sql.Tx, id, routeID uuid.UUID, stops []domain.ApplicationStopInput, detourKm float64, actor string) (string, error) {
+	if err := reserveSeats(ctx, tx, id, routeID, detourKm); err != nil {
+		return "", err
+	}
+	var requestID string
+	err := sq.Select("id").From("requests").
+		Where(sq.Eq{"participant_id": id.String()}).
+		RunWith(tx).QueryRowContext(ctx).Scan(&requestID)
+	if err != nil {
+		return "", fmt.Errorf("find request: %w", err)
+	}
+	if stops != nil {
+		if err = replaceRequestStops(ctx, tx, requestID, stops); err != nil {
+			return "", err
 		}
+	}
+	if err = requireCurrentStops(ctx, tx, id.String(), routeID.String()); err != nil {
+		return "", err
+	}
+	stopsBefore, err := routeStopsSnapshot(ctx, tx, routeID.String())
+	if err != nil {
+		return "", err
+	}
+	_, err = sq.Update("participants").
+		Set("status", "approved").
+		Set("detour_km", detourKm).
+		Set("awaiting_since", nil).
+		Where(sq.Eq{"id": id.String()}).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("update status: %w", err)
+	}
 
-		// Fetch the full proposed order from request_stops (includes context stops via route_stop_id).
-		rows, err := sq.Select("rs.position", "rs.lat", "rs.lng", "rs.place_id", "rs.formatted_address", "rs.route_stop_id").
-			From("request_stops rs").
-			Join("requests req ON req.id = rs.request_id").
-			Where(sq.Eq{"req.participant_id": id.String()}).
-			OrderBy("rs.position ASC").
-			RunWith(tx).QueryContext(ctx)
-		if err != nil {
-			return fmt.Errorf("application review: fetch request stops: %w", err)
+	// Snapshot participant_id for every existing route stop before we replace them.
+	pRows, err := sq.Select("id", "participant_id").
+		From("route_stops").
+		Where(sq.Eq{"route_id": routeID.String()}).
+		RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("snapshot route stops: %w", err)
+	}
+	participantByStopID := make(map[string]*string)
+	for pRows.Next() {
+		var stopID string
+		var pID *string
+		if err := pRows.Scan(&stopID, &pID); err != nil {
+			pRows.Close()
+			return "", fmt.Errorf("scan route stop: %w", err)
 		}
-		type stopRow struct {
-			position         uint
-			lat, lng         float64
-			placeID          *string
-			formattedAddress *string
-			routeStopID      *string
+		participantByStopID[stopID] = pID
+	}
+	pRows.Close()
+	if err := pRows.Err(); err != nil {
+		return "", fmt.Errorf("read route stops: %w", err)
+	}
+
+	// Fetch the full proposed order from request_stops (includes context stops via route_stop_id).
+	rows, err := sq.Select("rs.position", "rs.lat", "rs.lng", "rs.place_id", "rs.formatted_address", "rs.route_stop_id").
+		From("request_stops rs").
+		Join("requests req ON req.id = rs.request_id").
+		Where(sq.Eq{"req.participant_id": id.String()}).
+		OrderBy("rs.position ASC").
+		RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fetch request stops: %w", err)
+	}
+	type stopRow struct {
+		position         uint
+		lat, lng         float64
+		placeID          *string
+		formattedAddress *string
+		routeStopID      *string
+	}
+	var newStops []stopRow
+	for rows.Next() {
+		var s stopRow
+		if err := rows.Scan(&s.position, &s.lat, &s.lng, &s.placeID, &s.formattedAddress, &s.routeStopID); err != nil {
+			rows.Close()
+			return "", fmt.Errorf("scan request stop: %w", err)
 		}
-		var newStops []stopRow
-		for rows.Next() {
-			var s stopRow
-			if err := rows.Scan(&s.position, &s.lat, &s.lng, &s.placeID, &s.formattedAddress, &s.routeStopID); err != nil {
-				rows.Close()
-				return fmt.Errorf("application review: scan request stop: %w", err)
+		newStops = append(newStops, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("read request stops: %w", err)
+	}
+
+	// Replace ALL route stops with the full proposed order.
+	_, err = sq.Delete("route_stops").
+		Where(sq.Eq{"route_id": routeID.String()}).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("clear route stops: %w", err)
+	}
+	appIDStr := id.String()
+	for i, s := range newStops {
+		var participantID *string
+		if s.routeStopID != nil {
+			// Context stop: restore original ownership from snapshot, and skip
+			// stops that came off the route since the order was proposed.
+			pID, ok := participantByStopID[*s.routeStopID]
+			if !ok {
+				continue
 			}
-			newStops = append(newStops, s)
+			participantID = pID
+		} else {
+			// Own new stop: belongs to this applicant.
+			participantID = &appIDStr
 		}
-		rows.Close()
-		if err := rows.Err(); err != nil {
-			return fmt.Errorf("application review: read request stops: %w", err)
-		}
-
-		// Replace ALL route stops with the full proposed order.
-		_, err = sq.Delete("route_stops").
-			Where(sq.Eq{"route_id": routeID.String()}).
+		_, err = sq.Insert("route_stops").
+			Columns("id", "route_id", "position", "lat", "lng", "place_id", "formatted_address", "participant_id").
+			Values(uuid.New().String(), routeID.String(), s.position, s.lat, s.lng, s.placeID, s.formattedAddress, participantID).
 			RunWith(tx).ExecContext(ctx)
 		if err != nil {
-			return fmt.Errorf("application review: clear route stops: %w", err)
-		}
-		appIDStr := id.String()
-		for i, s := range newStops {
-			var participantID *string
-			if s.routeStopID != nil {
-				// Context stop: restore original ownership from snapshot.
-				participantID = participantByStopID[*s.routeStopID]
-			} else {
-				// Own new stop: belongs to this applicant.
-				participantID = &appIDStr
-			}
-			_, err = sq.Insert("route_stops").
-				Columns("id", "route_id", "position", "lat", "lng", "place_id", "formatted_address", "participant_id").
-				Values(uuid.New().String(), routeID.String(), s.position, s.lat, s.lng, s.placeID, s.formattedAddress, participantID).
-				RunWith(tx).ExecContext(ctx)
-			if err != nil {
-				return fmt.Errorf("application review: insert route stop %d: %w", i, err)
-			}
+			return "", fmt.Errorf("insert route stop %d: %w", i, err)
 		}
 	}
+	if err = refreshSearchArea(ctx, tx, routeID.String()); err != nil {
+		return "", err
+	}
+	stopsAfter, err := routeStopsSnapshot(ctx, tx, routeID.String())
+	if err != nil {
+		return "", err
+	}
+	err = recordApplicationEvent(ctx, tx, id.String(), applicationEvent{
+		eventType:   domain.EventApproved,
+		actor:       actor,
+		oldStatus:   "pending",
+		newStatus:   "approved",
+		stopsBefore: stopsBefore,
+		stopsAfter:  stopsAfter,
+	})
+	if err != nil {
+		return "", err
+	}
 
-	// Email log for approved application.
-	if status == "approved" {
-		var requestID string
-		err := sq.Select("id").From("requests").
-			Where(sq.Eq{"participant_id": id.String()}).
-			RunWith(tx).QueryRowContext(ctx).Scan(&requestID)
-		if err != nil {
-			return fmt.Errorf("application review: find request for email log: %w", err)
-		}
-		emailLogID := uuid.New().String()
-		_, err = sq.Insert("email_logs").
-			Columns("id", "request_id", "type", "status").
-			Values(emailLogID, requestID, "application_approved", "created").
+	emailLogID := uuid.New().String()
+	_, err = sq.Insert("email_logs").
+		Columns("id", "request_id", "type", "status").
+		Values(emailLogID, requestID, "application_approved", "created").
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("insert email_log: %w", err)
+	}
+	return emailLogID, nil
+}
+
+// lockedRoute is the capacity of a route locked by lockRoute.
+type lockedRoute struct {
+	maxPassengers   int
+	luggageCapacity *int
+	maxDeviation    float64
+}
+
+// lockRoute locks the route row for the rest of tx, so approvals and other
+// changes to the route's passengers and stops take turns. It returns
+// errs.ErrNotFound or errs.ErrRouteStarted when the route can no longer change.
+func lockRoute(ctx context.Context, tx *sql.Tx, routeID string) (lockedRoute, error) {
+	var lr lockedRoute
+	var started bool
+	err := sq.Select("max_passengers", "luggage_capacity", "max_deviation", "leaving_at IS NOT NULL AND leaving_at <= NOW()").
+		From("routes").
+		Where(sq.Eq{"id": routeID, "deleted_at": nil}).
+		Suffix("FOR UPDATE").
+		RunWith(tx).QueryRowContext(ctx).Scan(&lr.maxPassengers, &lr.luggageCapacity, &lr.maxDeviation, &started)
+	if errors.Is(err, sql.ErrNoRows) {
+		return lr, errs.ErrNotFound
+	}
+	if err != nil {
+		return lr, fmt.Errorf("lock route: %w", err)
+	}
+	if started {
+		return lr, errs.ErrRouteStarted
+	}
+	return lr, nil
+}
+
+// reserveSeats locks the route with lockRoute, then checks that application
+// id is still pending and that its booking and detourKm fit in what approved
+// passengers leave free. It returns errs.ErrConflict, errs.ErrRouteFull or
+// errs.ErrDetourBudget otherwise.
+func reserveSeats(ctx context.Context, tx *sql.Tx, id, routeID uuid.UUID, detourKm float64) error {
+	route, err := lockRoute(ctx, tx, routeID.String())
+	if err != nil {
+		return err
+	}
+
+	var status string
+	var seats, luggage int
+	err = sq.Select("status", "seats", "luggage").
+		From("participants").
+		Where(sq.Eq{"id": id.String(), "deleted_at": nil}).
+		Suffix("FOR UPDATE").
+		RunWith(tx).QueryRowContext(ctx).Scan(&status, &seats, &luggage)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("lock application: %w", err)
+	}
+	if status != "pending" {
+		return errs.ErrConflict
+	}
+
+	// A locking read sees approvals committed after tx's snapshot was taken.
+	var seatsTaken, luggageTaken int
+	err = sq.Select("COALESCE(SUM(seats), 0)", "COALESCE(SUM(luggage), 0)").
+		From("participants").
+		Where(sq.Eq{"route_id": routeID.String(), "status": "approved", "deleted_at": nil}).
+		Suffix("LOCK IN SHARE MODE").
+		RunWith(tx).QueryRowContext(ctx).Scan(&seatsTaken, &luggageTaken)
+	if err != nil {
+		return fmt.Errorf("count booked seats: %w", err)
+	}
+	if seatsTaken+seats > route.maxPassengers || (route.luggageCapacity != nil && luggageTaken+luggage > *route.luggageCapacity) {
+		return errs.ErrRouteFull
+	}
+	return reserveDetour(ctx, tx, routeID.String(), route, detourKm)
+}
+
+// reserveDetour checks, on a route locked by lockRoute, that detourKm more
+// fits in the deviation budget approved passengers' detours leave, and
+// returns errs.ErrDetourBudget otherwise.
+func reserveDetour(ctx context.Context, tx *sql.Tx, routeID string, route lockedRoute, detourKm float64) error {
+	var used float64
+	err := sq.Select("COALESCE(SUM(detour_km), 0)").
+		From("participants").
+		Where(sq.Eq{"route_id": routeID, "status": "approved", "deleted_at": nil}).
+		Suffix("LOCK IN SHARE MODE").
+		RunWith(tx).QueryRowContext(ctx).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("sum detours: %w", err)
+	}
+	if detourKm > route.maxDeviation-used {
+		return errs.ErrDetourBudget
+	}
+	return nil
+}
+
+// requireCurrentStops checks, on a route locked by lockRoute, that the
+// request of application id still lists every route stop other than its own.
+// Approving it replaces the route's stops with that list, so it returns
+// errs.ErrConflict when the route's stops changed since it was ordered.
+func requireCurrentStops(ctx context.Context, tx *sql.Tx, id, routeID string) error {
+	var missing int
+	err := sq.Select("COUNT(*)").
+		From("route_stops rts").
+		Where(sq.Eq{"rts.route_id": routeID}).
+		Where(sq.Or{sq.Eq{"rts.participant_id": nil}, sq.NotEq{"rts.participant_id": id}}).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM request_stops rs
+			JOIN requests req ON req.id = rs.request_id
+			WHERE req.participant_id = ? AND rs.route_stop_id = rts.id
+		)`, id).
+		RunWith(tx).QueryRowContext(ctx).Scan(&missing)
+	if err != nil {
+		return fmt.Errorf("check route stops: %w", err)
+	}
+	if missing > 0 {
+		return errs.ErrConflict
+	}
+	return nil
+}
+
+// replaceRequestStops replaces the stops of request requestID with stops.
+func replaceRequestStops(ctx context.Context, tx *sql.Tx, requestID string, stops []domain.ApplicationStopInput) error {
+	_, err := sq.Delete("request_stops").
+		Where(sq.Eq{"request_id": requestID}).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("clear stops: %w", err)
+	}
+	for i, s := range stops {
+		_, err = sq.Insert("request_stops").
+			Columns("id", "request_id", "position", "lat", "lng", "place_id", "formatted_address", "route_stop_id").
+			Values(uuid.New().String(), requestID, s.Position, s.Lat, s.Lng, nullablePtr(s.PlaceID), nullablePtr(s.FormattedAddress), nullablePtr(s.RouteStopID)).
 			RunWith(tx).ExecContext(ctx)
 		if err != nil {
-			return fmt.Errorf("application review: insert email_log: %w", err)
+			return fmt.Errorf("insert stop %d: %w", i, err)
 		}
-		if err = tx.Commit(); err != nil {
-			return fmt.Errorf("application review: commit: %w", err)
-		}
-		publishEmailLog(r.nc, emailLogID, "application_approved")
-		return nil
 	}
-
-	return tx.Commit()
+	return nil
 }
 
 // UpdateStops replaces the request_stops and optionally updates the comment for a pending application inside a transaction.
@@ -318,24 +597,24 @@ func (r *applicationRepository) UpdateStops(ctx context.Context, id uuid.UUID, s
 	if err != nil {
 		return fmt.Errorf("application update stops: find request: %w", err)
 	}
-
-	// Delete existing stops.
-	_, err = sq.Delete("request_stops").
-		Where(sq.Eq{"request_id": requestIDStr}).
-		RunWith(tx).ExecContext(ctx)
+	stopsBefore, err := requestStopsSnapshot(ctx, tx, id.String())
+	if err != nil {
+		return fmt.Errorf("application update stops: %w", err)
+	}
+	err = recordApplicationEvent(ctx, tx, id.String(), applicationEvent{
+		eventType:   domain.EventStopsUpdated,
+		actor:       actorApplicant,
+		oldStatus:   "pending",
+		newStatus:   "pending",
+		stopsBefore: stopsBefore,
+		stopsAfter:  inputStopsSnapshot(stops),
+	})
 	if err != nil {
-		return fmt.Errorf("application update stops: clear stops: %w", err)
+		return fmt.Errorf("application update stops: %w", err)
 	}
 
-	// Insert new stops.
-	for i, s := range stops {
-		_, err = sq.Insert("request_stops").
-			Columns("id", "request_id", "position", "lat", "lng", "place_id", "formatted_address", "route_stop_id").
-			Values(uuid.New().String(), requestIDStr, s.Position, s.Lat, s.Lng, nullablePtr(s.PlaceID), nullablePtr(s.FormattedAddress), nullablePtr(s.RouteStopID)).
-			RunWith(tx).ExecContext(ctx)
-		if err != nil {
-			return fmt.Errorf("application update stops: insert stop %d: %w", i, err)
-		}
+	if err = replaceRequestStops(ctx, tx, requestIDStr, stops); err != nil {
+		return fmt.Errorf("application update stops: %w", err)
 	}
 
 	// Update comment if provided.
@@ -356,6 +635,7 @@ func (r *applicationRepository) UpdateStops(ctx context.Context, id uuid.UUID, s
 // If the participant was approved (i.e. already on the ride), the row is kept with
 // status='left' and deleted_at set so it appears in the user's history.
 // Otherwise the row is hard-deleted so the unique (route_id, user_id) slot is freed.
+// Either way the oldest waitlisted application may take the freed seat.
 func (r *applicationRepository) SoftDelete(ctx context.Context, id uuid.UUID, wasApproved bool) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -363,6 +643,25 @@ func (r *applicationRepository) SoftDelete(ctx context.Context, id uuid.UUID, wa
 	}
 	defer tx.Rollback() //nolint:errcheck
 
+	var routeID, status string
+	err = sq.Select("route_id", "status").From("participants").
+		Where(sq.Eq{"id": id.String()}).
+		RunWith(tx).QueryRowContext(ctx).Scan(&routeID, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("application delete: find route: %w", err)
+	}
+
+	ev := applicationEvent{eventType: domain.EventCancelled, actor: actorApplicant, oldStatus: status}
+	if wasApproved {
+		ev.eventType, ev.newStatus = domain.EventLeft, "left"
+	}
+	if err = recordApplicationEvent(ctx, tx, id.String(), ev); err != nil {
+		return fmt.Errorf("application delete: %w", err)
+	}
+
 	// Always remove request/stops — they're no longer needed.
 	_, err = sq.Delete("requests").
 		Where(sq.Eq{"participant_id": id.String()}).
@@ -391,7 +690,15 @@ func (r *applicationRepository) SoftDelete(ctx context.Context, id uuid.UUID, wa
 		}
 	}
 
-	return tx.Commit()
+	promoted, err := promoteWaitlisted(ctx, tx, routeID)
+	if err != nil {
+		return fmt.Errorf("application delete: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("application delete: commit: %w", err)
+	}
+	publishPromotions(r.nc, promoted)
+	return nil
 }
 
 // RequestStopChange stores new proposed stops (and optional comment) and sets pending_stop_change=1 on an approved application.
@@ -409,22 +716,24 @@ func (r *applicationRepository) RequestStopChange(ctx context.Context, id uuid.U
 	if err != nil {
 		return fmt.Errorf("request stop change: find request: %w", err)
 	}
-
-	_, err = sq.Delete("request_stops").
-		Where(sq.Eq{"request_id": requestIDStr}).
-		RunWith(tx).ExecContext(ctx)
+	stopsBefore, err := requestStopsSnapshot(ctx, tx, id.String())
+	if err != nil {
+		return fmt.Errorf("request stop change: %w", err)
+	}
+	err = recordApplicationEvent(ctx, tx, id.String(), applicationEvent{
+		eventType:   domain.EventStopChangeRequested,
+		actor:       actorApplicant,
+		oldStatus:   "approved",
+		newStatus:   "approved",
+		stopsBefore: stopsBefore,
+		stopsAfter:  inputStopsSnapshot(stops),
+	})
 	if err != nil {
-		return fmt.Errorf("request stop change: clear stops: %w", err)
+		return fmt.Errorf("request stop change: %w", err)
 	}
 
-	for i, s := range stops {
-		_, err = sq.Insert("request_stops").
-			Columns("id", "request_id", "position", "lat", "lng", "place_id", "formatted_address", "route_stop_id").
-			Values(uuid.New().String(), requestIDStr, s.Position, s.Lat, s.Lng, nullablePtr(s.PlaceID), nullablePtr(s.FormattedAddress), s.RouteStopID).
-			RunWith(tx).ExecContext(ctx)
-		if err != nil {
-			return fmt.Errorf("request stop change: insert stop %d: %w", i, err)
-		}
+	if err = replaceRequestStops(ctx, tx, requestIDStr, stops); err != nil {
+		return fmt.Errorf("request stop change: %w", err)
 	}
 
 	_, err = sq.Update("requests").
@@ -437,6 +746,8 @@ func (r *applicationRepository) RequestStopChange(ctx context.Context, id uuid.U
 
 	_, err = sq.Update("participants").
 		Set("pending_stop_change", 1).
+		Set("awaiting_since", sq.Expr("NOW()")).
+		Set("rejection_reason", nil).
 		Where(sq.Eq{"id": id.String()}).
 		RunWith(tx).ExecContext(ctx)
 	if err != nil {
@@ -447,9 +758,10 @@ func (r *applicationRepository) RequestStopChange(ctx context.Context, id uuid.U
 }
 
 // ReviewStopChange approves or rejects a pending stop-change.
-// On approve: replaces route_stops with the new request_stops and clears the flag.
+// On approve: replaces route_stops with stops and clears the flag, after
+// rechecking them and the detour against the locked route.
 // On reject: deletes the proposed request_stops and clears the flag.
-func (r *applicationRepository) ReviewStopChange(ctx context.Context, id uuid.UUID, routeID uuid.UUID, approve bool) error {
+func (r *applicationRepository) ReviewStopChange(ctx context.Context, id uuid.UUID, routeID uuid.UUID, approve bool, stops []domain.ApplicationStopInput, detourKm float64) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("review stop change: begin tx: %w", err)
@@ -457,6 +769,30 @@ func (r *applicationRepository) ReviewStopChange(ctx context.Context, id uuid.UU
 	defer tx.Rollback() //nolint:errcheck
 
 	if approve {
+		route, err := lockRoute(ctx, tx, routeID.String())
+		if err != nil {
+			return fmt.Errorf("review stop change: %w", err)
+		}
+		if err = reserveDetour(ctx, tx, routeID.String(), route, detourKm); err != nil {
+			return fmt.Errorf("review stop change: %w", err)
+		}
+		var requestID string
+		err = sq.Select("id").From("requests").
+			Where(sq.Eq{"participant_id": id.String()}).
+			RunWith(tx).QueryRowContext(ctx).Scan(&requestID)
+		if err != nil {
+			return fmt.Errorf("review stop change: find request: %w", err)
+		}
+		if err = replaceRequestStops(ctx, tx, requestID, stops); err != nil {
+			return fmt.Errorf("review stop change: %w", err)
+		}
+		if err = requireCurrentStops(ctx, tx, id.String(), routeID.String()); err != nil {
+			return fmt.Errorf("review stop change: %w", err)
+		}
+		stopsBefore, err := routeStopsSnapshot(ctx, tx, routeID.String())
+		if err != nil {
+			return fmt.Errorf("review stop change: %w", err)
+		}
 		// Snapshot participant_id for every existing route stop before we delete them.
 		participantByStopID := make(map[string]*string)
 		snapRows, err := sq.Select("id", "participant_id").
@@ -521,8 +857,13 @@ func (r *applicationRepository) ReviewStopChange(ctx context.Context, id uuid.UU
 		for i, s := range newStops {
 			var participantID *string
 			if s.routeStopID != nil {
-				// Existing stop: restore its original participant_id.
-				participantID = participantByStopID[*s.routeStopID]
+				// Existing stop: restore its original participant_id, unless it
+				// has since come off the route.
+				pID, ok := participantByStopID[*s.routeStopID]
+				if !ok {
+					continue
+				}
+				participantID = pID
 			} else {
 				// New stop created by the editor: assign to this participant.
 				participantID = &appIDStr
@@ -535,6 +876,24 @@ func (r *applicationRepository) ReviewStopChange(ctx context.Context, id uuid.UU
 				return fmt.Errorf("review stop change: insert route stop %d: %w", i, err)
 			}
 		}
+		if err = refreshSearchArea(ctx, tx, routeID.String()); err != nil {
+			return fmt.Errorf("review stop change: %w", err)
+		}
+		stopsAfter, err := routeStopsSnapshot(ctx, tx, routeID.String())
+		if err != nil {
+			return fmt.Errorf("review stop change: %w", err)
+		}
+		err = recordApplicationEvent(ctx, tx, id.String(), applicationEvent{
+			eventType:   domain.EventStopChangeApproved,
+			actor:       actorDriver,
+			oldStatus:   "approved",
+			newStatus:   "approved",
+			stopsBefore: stopsBefore,
+			stopsAfter:  stopsAfter,
+		})
+		if err != nil {
+			return fmt.Errorf("review stop change: %w", err)
+		}
 	} else {
 		// Rejected: discard the proposed stops.
 		var requestIDStr string
@@ -544,6 +903,20 @@ func (r *applicationRepository) ReviewStopChange(ctx context.Context, id uuid.UU
 		if err != nil {
 			return fmt.Errorf("review stop change: find request: %w", err)
 		}
+		proposed, err := requestStopsSnapshot(ctx, tx, id.String())
+		if err != nil {
+			return fmt.Errorf("review stop change: %w", err)
+		}
+		err = recordApplicationEvent(ctx, tx, id.String(), applicationEvent{
+			eventType:   domain.EventStopChangeRejected,
+			actor:       actorDriver,
+			oldStatus:   "approved",
+			newStatus:   "approved",
+			stopsBefore: proposed,
+		})
+		if err != nil {
+			return fmt.Errorf("review stop change: %w", err)
+		}
 		_, err = sq.Delete("request_stops").
 			Where(sq.Eq{"request_id": requestIDStr}).
 			RunWith(tx).ExecContext(ctx)
@@ -552,11 +925,14 @@ func (r *applicationRepository) ReviewStopChange(ctx context.Context, id uuid.UU
 		}
 	}
 
-	_, err = sq.Update("participants").
+	qb := sq.Update("participants").
 		Set("pending_stop_change", 0).
-		Where(sq.Eq{"id": id.String()}).
-		RunWith(tx).ExecContext(ctx)
-	if err != nil {
+		Set("awaiting_since", nil).
+		Where(sq.Eq{"id": id.String()})
+	if approve {
+		qb = qb.Set("detour_km", sq.Expr("GREATEST(0, detour_km + ?)", detourKm))
+	}
+	if _, err = qb.RunWith(tx).ExecContext(ctx); err != nil {
 		return fmt.Errorf("review stop change: clear flag: %w", err)
 	}
 
@@ -602,6 +978,20 @@ func (r *applicationRepository) CancelStopChange(ctx context.Context, id uuid.UU
 	if err != nil {
 		return fmt.Errorf("cancel stop change: find request: %w", err)
 	}
+	proposed, err := requestStopsSnapshot(ctx, tx, id.String())
+	if err != nil {
+		return fmt.Errorf("cancel stop change: %w", err)
+	}
+	err = recordApplicationEvent(ctx, tx, id.String(), applicationEvent{
+		eventType:   domain.EventStopChangeCancelled,
+		actor:       actorApplicant,
+		oldStatus:   "approved",
+		newStatus:   "approved",
+		stopsBefore: proposed,
+	})
+	if err != nil {
+		return fmt.Errorf("cancel stop change: %w", err)
+	}
 
 	_, err = sq.Delete("request_stops").
 		Where(sq.Eq{"request_id": requestIDStr}).
@@ -612,6 +1002,7 @@ func (r *applicationRepository) CancelStopChange(ctx context.Context, id uuid.UU
 
 	_, err = sq.Update("participants").
 		Set("pending_stop_change", 0).
+		Set("awaiting_since", nil).
 		Where(sq.Eq{"id": id.String()}).
 		RunWith(tx).ExecContext(ctx)
 	if err != nil {
@@ -629,7 +1020,7 @@ func scanApplicationsWithStops(ctx context.Context, db *sql.DB, rows *sql.Rows)
 	for rows.Next() {
 		var a domain.Application
 		var idStr, userIDStr, routeIDStr string
-		if err := rows.Scan(&idStr, &userIDStr, &a.UserName, &routeIDStr, &a.Status, &a.Comment, &a.CreatedAt, &a.PendingStopChange); err != nil {
+		if err := rows.Scan(&idStr, &userIDStr, &a.UserName, &routeIDStr, &a.Status, &a.Comment, &a.CreatedAt, &a.PendingStopChange, &a.RejectionReason, &a.LeaveReason, &a.Seats, &a.Luggage); err != nil {
 			return nil, fmt.Errorf("scan application: %w", err)
 		}
 		a.ID, _ = uuid.Parse(idStr)
@@ -675,8 +1066,8 @@ func scanUserApplicationsWithStops(ctx context.Context, db *sql.DB, rows *sql.Ro
 		var a domain.Application
 		var idStr, userIDStr, routeIDStr string
 		if err := rows.Scan(
-			&idStr, &userIDStr, &a.UserName, &routeIDStr, &a.Status, &a.Comment, &a.CreatedAt, &a.PendingStopChange,
-			&a.RouteLeavingAt, &a.RouteStartAddress, &a.RouteEndAddress,
+			&idStr, &userIDStr, &a.UserName, &routeIDStr, &a.Status, &a.Comment, &a.CreatedAt, &a.PendingStopChange, &a.RejectionReason, &a.LeaveReason, &a.Seats, &a.Luggage,
+			&a.RouteLeavingAt, &a.RouteStartAddress, &a.RouteEndAddress, &a.PriceShare,
 		); err != nil {
 			return nil, fmt.Errorf("scan user application: %w", err)
 		}
@@ -722,9 +1113,19 @@ func fetchApplicationStops(ctx context.Context, db *sql.DB, participantIDs []str
 	if len(participantIDs) == 0 {
 		return nil, nil
 	}
-	rows, err := sq.Select("rs.id", "req.participant_id", "rs.position", "rs.lat", "rs.lng", "rs.place_id", "rs.formatted_address", "rs.route_stop_id").
+	// A stop's ETA is that of its route stop: the one it references for a
+	// context stop, or the applicant's own stop at the same point. Stops of
+	// applications that are not approved are not on the route yet.
+	rows, err := sq.Select("rs.id", "req.participant_id", "rs.position", "rs.lat", "rs.lng", "rs.place_id", "rs.formatted_address", "rs.route_stop_id",
+		`CASE WHEN p.status = 'approved' THEN (
+			SELECT rts.eta FROM route_stops rts
+			WHERE rts.route_id = p.route_id AND (rts.id = rs.route_stop_id
+				OR (rs.route_stop_id IS NULL AND rts.participant_id = p.id AND rts.lat = rs.lat AND rts.lng = rs.lng))
+			ORDER BY rts.position LIMIT 1
+		) END`).
 		From("request_stops rs").
 		Join("requests req ON req.id = rs.request_id").
+		Join("participants p ON p.id = req.participant_id").
 		Where(sq.Eq{"req.participant_id": participantIDs}).
 		OrderBy("req.participant_id", "rs.position").
 		RunWith(db).QueryContext(ctx)
@@ -737,7 +1138,7 @@ func fetchApplicationStops(ctx context.Context, db *sql.DB, participantIDs []str
 	for rows.Next() {
 		var s appStopWithID
 		var idStr, participantIDStr string
-		if err := rows.Scan(&idStr, &participantIDStr, &s.Position, &s.Lat, &s.Lng, &s.PlaceID, &s.FormattedAddress, &s.RouteStopID); err != nil {
+		if err := rows.Scan(&idStr, &participantIDStr, &s.Position, &s.Lat, &s.Lng, &s.PlaceID, &s.FormattedAddress, &s.RouteStopID, &s.ETA); err != nil {
 			return nil, fmt.Errorf("scan application stop: %w", err)
 		}
 		s.ID, _ = uuid.Parse(idStr)
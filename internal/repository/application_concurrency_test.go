@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/migrations"
+)
+
+// openTestDB connects to the MySQL database in PSS_TEST_MYSQL_DSN (with
+// parseTime=true&multiStatements=true) and migrates it. Tests using it are
+// skipped when the variable is unset.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("PSS_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("PSS_TEST_MYSQL_DSN is not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migrations.Run(db, os.DirFS("../../cmd/server/migrations"), ".", nil); err != nil {
+		t.Fatalf("migrate test db: %v", err)
+	}
+	return db
+}
+
+func createTestUser(t *testing.T, db *sql.DB) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	_, err := db.Exec("INSERT INTO users (id, email, provider, provider_sub) VALUES (?, ?, 'test', ?)",
+		id.String(), id.String()+"@example.com", id.String())
+	if err != nil {
+		t.Fatalf("create test user: %v", err)
+	}
+	return id
+}
+
+// createTestRoute creates a route tomorrow with seats free seats and the
+// given number of pending applications for one seat each.
+func createTestRoute(t *testing.T, db *sql.DB, seats uint, pending int) (uuid.UUID, []uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	leaving := time.Now().Add(24 * time.Hour)
+	routeID, err := NewRouteRepository(db, nil).Create(ctx, createTestUser(t, db), domain.CreateRouteInput{
+		StartLat: 54.6872, StartLng: 25.2797,
+		EndLat: 54.8985, EndLng: 23.9036,
+		MaxPassengers: seats,
+		MaxDeviation:  10,
+		LeavingAt:     &leaving,
+	})
+	if err != nil {
+		t.Fatalf("create test route: %v", err)
+	}
+	apps := NewApplicationRepository(db, nil)
+	ids := make([]uuid.UUID, pending)
+	for i := range ids {
+		if ids[i], err = apps.Create(ctx, createTestUser(t, db), routeID, "pending", domain.ApplyInput{Seats: 1}); err != nil {
+			t.Fatalf("create test application: %v", err)
+		}
+	}
+	return routeID, ids
+}
+
+// runConcurrently calls fn for 0..n-1 at once and returns the errors.
+func runConcurrently(n int, fn func(i int) error) []error {
+	out := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			out[i] = fn(i)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return out
+}
+
+func TestApplicationRepository_ReviewUpdate_ConcurrentApprovals(t *testing.T) {
+	db := openTestDB(t)
+	routeID, ids := createTestRoute(t, db, 2, 6)
+	apps := NewApplicationRepository(db, nil)
+
+	results := runConcurrently(len(ids), func(i int) error {
+		return apps.ReviewUpdate(context.Background(), ids[i], "approved", uuid.Nil, routeID, nil, 0)
+	})
+	approved := 0
+	for i, err := range results {
+		switch {
+		case err == nil:
+			approved++
+		case !errors.Is(err, errs.ErrRouteFull):
+			t.Errorf("ReviewUpdate(%d) = %v, want nil or ErrRouteFull", i, err)
+		}
+	}
+	if approved != 2 {
+		t.Errorf("%d approvals succeeded, want 2", approved)
+	}
+
+	var seats int
+	err := db.QueryRow("SELECT COALESCE(SUM(seats), 0) FROM participants WHERE route_id = ? AND status = 'approved' AND deleted_at IS NULL",
+		routeID.String()).Scan(&seats)
+	if err != nil {
+		t.Fatalf("count approved seats: %v", err)
+	}
+	if seats != 2 {
+		t.Errorf("approved seats = %d, want 2", seats)
+	}
+}
+
+func TestApplicationRepository_ReviewUpdate_ConcurrentDecisions(t *testing.T) {
+	db := openTestDB(t)
+	routeID, ids := createTestRoute(t, db, 2, 1)
+	apps := NewApplicationRepository(db, nil)
+
+	statuses := []string{"approved", "rejected", "approved", "rejected"}
+	results := runConcurrently(len(statuses), func(i int) error {
+		return apps.ReviewUpdate(context.Background(), ids[0], statuses[i], uuid.Nil, routeID, nil, 0)
+	})
+	decided := 0
+	for i, err := range results {
+		switch {
+		case err == nil:
+			decided++
+		case !errors.Is(err, errs.ErrConflict):
+			t.Errorf("ReviewUpdate(%s) = %v, want nil or ErrConflict", statuses[i], err)
+		}
+	}
+	if decided != 1 {
+		t.Errorf("%d decisions succeeded, want 1", decided)
+	}
+}
+
+func TestApplicationRepository_ReviewUpdate_ConcurrentDetours(t *testing.T) {
+	db := openTestDB(t)
+	routeID, ids := createTestRoute(t, db, 4, 3)
+	apps := NewApplicationRepository(db, nil)
+
+	// Each detour fits the 10 km budget alone, but no two fit together.
+	results := runConcurrently(len(ids), func(i int) error {
+		return apps.ReviewUpdate(context.Background(), ids[i], "approved", uuid.Nil, routeID, nil, 6)
+	})
+	approved := 0
+	for i, err := range results {
+		switch {
+		case err == nil:
+			approved++
+		case !errors.Is(err, errs.ErrDetourBudget):
+			t.Errorf("ReviewUpdate(%d) = %v, want nil or ErrDetourBudget", i, err)
+		}
+	}
+	if approved != 1 {
+		t.Errorf("%d approvals succeeded, want 1", approved)
+	}
+}
+
+func TestRouteRepository_Update_BelowBooked(t *testing.T) {
+	db := openTestDB(t)
+	routeID, ids := createTestRoute(t, db, 2, 1)
+	if err := NewApplicationRepository(db, nil).ReviewUpdate(context.Background(), ids[0], "approved", uuid.Nil, routeID, nil, 6); err != nil {
+		t.Fatalf("approve test application: %v", err)
+	}
+	var creator string
+	if err := db.QueryRow("SELECT creator_user_id FROM routes WHERE id = ?", routeID.String()).Scan(&creator); err != nil {
+		t.Fatalf("fetch route creator: %v", err)
+	}
+	routes := NewRouteRepository(db, nil)
+
+	noSeats, oneSeat := uint(0), uint(1)
+	below, booked := 5.0, 6.0
+	tests := []struct {
+		name    string
+		in      domain.UpdateRouteInput
+		wantErr error
+	}{
+		{"seats below booked", domain.UpdateRouteInput{MaxPassengers: &noSeats}, errs.ErrBelowBooked},
+		{"seats booked", domain.UpdateRouteInput{MaxPassengers: &oneSeat}, nil},
+		{"deviation below booked", domain.UpdateRouteInput{MaxDeviation: &below}, errs.ErrBelowBooked},
+		{"deviation booked", domain.UpdateRouteInput{MaxDeviation: &booked}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := routes.Update(context.Background(), routeID, uuid.MustParse(creator), tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Update() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+// Who caused an application event; recordApplicationEvent resolves it to a
+// user. Automatic transitions (waitlist promotion, expiry) have no actor.
+const (
+	actorApplicant = "applicant"
+	actorDriver    = "driver"
+	actorSystem    = ""
+)
+
+// applicationEvent is a row to append to application_events. An empty status
+// means the application did not exist before, or no longer exists after, the
+// transition; nil stops mean it did not touch them.
+type applicationEvent struct {
+	eventType   string
+	actor       string
+	oldStatus   string
+	newStatus   string
+	stopsBefore []domain.StopSnapshot
+	stopsAfter  []domain.StopSnapshot
+}
+
+// recordApplicationEvent appends ev to the history of participant
+// participantID inside tx. The participant row must still exist.
+func recordApplicationEvent(ctx context.Context, tx *sql.Tx, participantID string, ev applicationEvent) error {
+	before, err := marshalStops(ev.stopsBefore)
+	if err != nil {
+		return err
+	}
+	after, err := marshalStops(ev.stopsAfter)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO application_events
+			(id, participant_id, route_id, user_id, actor_user_id, type, old_status, new_status, stops_before, stops_after)
+		SELECT ?, p.id, p.route_id, p.user_id,
+			CASE ? WHEN 'applicant' THEN p.user_id WHEN 'driver' THEN r.creator_user_id END,
+			?, ?, ?, ?, ?
+		FROM participants p
+		JOIN routes r ON r.id = p.route_id
+		WHERE p.id = ?
+	`, uuid.New().String(), ev.actor, ev.eventType, nullableStr(ev.oldStatus), nullableStr(ev.newStatus), before, after, participantID)
+	if err != nil {
+		return fmt.Errorf("record %s event: %w", ev.eventType, err)
+	}
+	return nil
+}
+
+func marshalStops(stops []domain.StopSnapshot) (interface{}, error) {
+	if stops == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(stops)
+	if err != nil {
+		return nil, fmt.Errorf("marshal stop snapshot: %w", err)
+	}
+	return string(b), nil
+}
+
+// requestStopsSnapshot returns the stops an application currently proposes.
+func requestStopsSnapshot(ctx context.Context, tx *sql.Tx, participantID string) ([]domain.StopSnapshot, error) {
+	return scanStopSnapshot(sq.Select("rs.position", "rs.lat", "rs.lng", "rs.formatted_address").
+		From("request_stops rs").
+		Join("requests req ON req.id = rs.request_id").
+		Where(sq.Eq{"req.participant_id": participantID}).
+		OrderBy("rs.position ASC").
+		RunWith(tx).QueryContext(ctx))
+}
+
+// routeStopsSnapshot returns a route's current stop order.
+func routeStopsSnapshot(ctx context.Context, tx *sql.Tx, routeID string) ([]domain.StopSnapshot, error) {
+	return scanStopSnapshot(sq.Select("position", "lat", "lng", "formatted_address").
+		From("route_stops").
+		Where(sq.Eq{"route_id": routeID}).
+		OrderBy("position ASC").
+		RunWith(tx).QueryContext(ctx))
+}
+
+func scanStopSnapshot(rows *sql.Rows, err error) ([]domain.StopSnapshot, error) {
+	if err != nil {
+		return nil, fmt.Errorf("snapshot stops: %w", err)
+	}
+	defer rows.Close()
+	out := []domain.StopSnapshot{}
+	for rows.Next() {
+		var s domain.StopSnapshot
+		if err := rows.Scan(&s.Position, &s.Lat, &s.Lng, &s.FormattedAddress); err != nil {
+			return nil, fmt.Errorf("snapshot stops: scan: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("snapshot stops: %w", err)
+	}
+	return out, nil
+}
+
+// inputStopsSnapshot returns submitted stops as they will be stored.
+func inputStopsSnapshot(stops []domain.ApplicationStopInput) []domain.StopSnapshot {
+	out := make([]domain.StopSnapshot, 0, len(stops))
+	for i, s := range stops {
+		pos := uint(i)
+		if s.Position != nil {
+			pos = *s.Position
+		}
+		out = append(out, domain.StopSnapshot{Position: pos, Lat: s.Lat, Lng: s.Lng, FormattedAddress: s.FormattedAddress})
+	}
+	return out
+}
+
+// ListEvents returns the history of application id, oldest first.
+func (r *applicationRepository) ListEvents(ctx context.Context, id uuid.UUID) ([]domain.ApplicationEvent, error) {
+	rows, err := sq.Select("e.id", "e.participant_id", "e.route_id", "e.user_id", "e.type",
+		"e.actor_user_id", "COALESCE(u.name, u.email)", "e.old_status", "e.new_status",
+		"e.stops_before", "e.stops_after", "e.created_at").
+		From("application_events e").
+		LeftJoin("users u ON u.id = e.actor_user_id").
+		Where(sq.Eq{"e.participant_id": id.String()}).
+		OrderBy("e.created_at ASC").
+		RunWith(r.db).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("application list events: %w", err)
+	}
+	defer rows.Close()
+
+	var out []domain.ApplicationEvent
+	for rows.Next() {
+		var e domain.ApplicationEvent
+		var idStr, appIDStr, routeIDStr, userIDStr string
+		var actorIDStr *string
+		var before, after []byte
+		if err := rows.Scan(&idStr, &appIDStr, &routeIDStr, &userIDStr, &e.Type,
+			&actorIDStr, &e.ActorName, &e.OldStatus, &e.NewStatus,
+			&before, &after, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("application list events: scan: %w", err)
+		}
+		e.ID, _ = uuid.Parse(idStr)
+		e.ApplicationID, _ = uuid.Parse(appIDStr)
+		e.RouteID, _ = uuid.Parse(routeIDStr)
+		e.UserID, _ = uuid.Parse(userIDStr)
+		if actorIDStr != nil {
+			actorID, _ := uuid.Parse(*actorIDStr)
+			e.ActorUserID = &actorID
+		}
+		if before != nil {
+			if err := json.Unmarshal(before, &e.StopsBefore); err != nil {
+				return nil, fmt.Errorf("application list events: stops before: %w", err)
+			}
+		}
+		if after != nil {
+			if err := json.Unmarshal(after, &e.StopsAfter); err != nil {
+				return nil, fmt.Errorf("application list events: stops after: %w", err)
+			}
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("application list events: %w", err)
+	}
+	return out, nil
+}
@@ -8,6 +8,7 @@ import (
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
 	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
 )
 
 type chatRepository struct{ db *sql.DB }
@@ -22,6 +23,7 @@ func (r *chatRepository) ListPrivateChats(ctx context.Context, userID uuid.UUID)
 		SELECT
 			pc.id,
 			pc.created_at,
+			pc.closed_at,
 			p_other.user_id,
 			COALESCE(u_other.name, u_other.email, ''),
 			p_self.route_id
@@ -41,7 +43,7 @@ func (r *chatRepository) ListPrivateChats(ctx context.Context, userID uuid.UUID)
 	for rows.Next() {
 		var c domain.PrivateChat
 		var idStr, otherUserIDStr, routeIDStr string
-		if err := rows.Scan(&idStr, &c.CreatedAt, &otherUserIDStr, &c.OtherName, &routeIDStr); err != nil {
+		if err := rows.Scan(&idStr, &c.CreatedAt, &c.ClosedAt, &otherUserIDStr, &c.OtherName, &routeIDStr); err != nil {
 			return nil, fmt.Errorf("list private chats scan: %w", err)
 		}
 		c.ID, _ = uuid.Parse(idStr)
@@ -115,13 +117,18 @@ func (r *chatRepository) GetGroupMessages(ctx context.Context, routeID uuid.UUID
 
 func (r *chatRepository) SendPrivateMessage(ctx context.Context, chatID, senderUserID uuid.UUID, message string) (uuid.UUID, error) {
 	id := uuid.New()
-	_, err := sq.Insert("private_messages").
-		Columns("id", "chat_id", "sender_user_id", "message").
-		Values(id.String(), chatID.String(), senderUserID.String(), message).
-		RunWith(r.db).ExecContext(ctx)
+	// Insert only while the chat is open, so a close racing the send wins.
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO private_messages (id, chat_id, sender_user_id, message)
+		SELECT ?, pc.id, ?, ? FROM private_chats pc
+		WHERE pc.id = ? AND pc.closed_at IS NULL
+	`, id.String(), senderUserID.String(), message, chatID.String())
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("send private message: %w", err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return uuid.Nil, errs.ErrChatClosed
+	}
 	return id, nil
 }
 
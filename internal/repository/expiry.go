@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+// ListStale returns the applications and stop changes past their deadline,
+// oldest first. Waitlisted applications only expire with their route: they
+// wait for a seat, not for the driver.
+func (r *applicationRepository) ListStale(ctx context.Context, leavingBefore time.Time, awaitingBefore *time.Time) ([]domain.StaleApplication, error) {
+	deadline := sq.Or{sq.Lt{"r.leaving_at": leavingBefore}}
+	if awaitingBefore != nil {
+		deadline = append(deadline, sq.And{
+			sq.NotEq{"p.status": "waitlisted"},
+			sq.Lt{"p.awaiting_since": *awaitingBefore},
+		})
+	}
+	rows, err := sq.Select("p.id", "p.route_id", "p.status", "p.pending_stop_change", "p.awaiting_since", "r.leaving_at").
+		From("participants p").
+		Join("routes r ON r.id = p.route_id").
+		Where(sq.Eq{"p.deleted_at": nil, "r.deleted_at": nil}).
+		Where(sq.Or{
+			sq.Eq{"p.status": []string{"pending", "waitlisted"}},
+			sq.Eq{"p.status": "approved", "p.pending_stop_change": 1},
+		}).
+		Where(deadline).
+		OrderBy("p.awaiting_since ASC", "p.created_at ASC").
+		RunWith(r.db).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("application list stale: %w", err)
+	}
+	defer rows.Close()
+
+	var out []domain.StaleApplication
+	for rows.Next() {
+		var a domain.StaleApplication
+		var idStr, routeIDStr string
+		if err := rows.Scan(&idStr, &routeIDStr, &a.Status, &a.StopChange, &a.AwaitingSince, &a.RouteLeavingAt); err != nil {
+			return nil, fmt.Errorf("application list stale: scan: %w", err)
+		}
+		a.ID, _ = uuid.Parse(idStr)
+		a.RouteID, _ = uuid.Parse(routeIDStr)
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("application list stale: %w", err)
+	}
+	return out, nil
+}
+
+// Expire rejects a stale application, or discards the proposed stops of a
+// stale stop change, records reason and queues an email to the applicant.
+// The update is conditioned on the state ListStale saw, so a decision the
+// driver made in the meantime wins.
+func (r *applicationRepository) Expire(ctx context.Context, app domain.StaleApplication, reason string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("application expire: begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var requestID string
+	err = sq.Select("id").From("requests").
+		Where(sq.Eq{"participant_id": app.ID.String()}).
+		RunWith(tx).QueryRowContext(ctx).Scan(&requestID)
+	if err != nil {
+		return fmt.Errorf("application expire: find request: %w", err)
+	}
+
+	ub := sq.Update("participants").
+		Set("awaiting_since", nil).
+		Set("rejection_reason", reason).
+		Where(sq.Eq{"id": app.ID.String(), "deleted_at": nil})
+	emailType := "application_expired"
+	if app.StopChange {
+		ub = ub.Set("pending_stop_change", 0).
+			Where(sq.Eq{"status": "approved", "pending_stop_change": 1})
+		emailType = "stop_change_expired"
+	} else {
+		ub = ub.Set("status", "rejected").
+			Where(sq.Eq{"status": app.Status})
+	}
+	res, err := ub.RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("application expire: update: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+
+	ev := applicationEvent{
+		eventType: domain.EventExpired,
+		actor:     actorSystem,
+		oldStatus: app.Status,
+		newStatus: "rejected",
+	}
+	if app.StopChange {
+		ev.eventType, ev.newStatus = domain.EventStopChangeExpired, app.Status
+		if ev.stopsBefore, err = requestStopsSnapshot(ctx, tx, app.ID.String()); err != nil {
+			return fmt.Errorf("application expire: %w", err)
+		}
+	}
+	if err = recordApplicationEvent(ctx, tx, app.ID.String(), ev); err != nil {
+		return fmt.Errorf("application expire: %w", err)
+	}
+
+	if app.StopChange {
+		_, err = sq.Delete("request_stops").
+			Where(sq.Eq{"request_id": requestID}).
+			RunWith(tx).ExecContext(ctx)
+		if err != nil {
+			return fmt.Errorf("application expire: clear proposed stops: %w", err)
+		}
+	}
+
+	emailLogID := uuid.New().String()
+	_, err = sq.Insert("email_logs").
+		Columns("id", "request_id", "type", "status").
+		Values(emailLogID, requestID, emailType, "created").
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("application expire: insert email_log: %w", err)
+	}
+
+	// An expired pending application frees its place for the waitlist, unless
+	// the route is about to leave and the promoted ones would expire too.
+	var promoted []string
+	if app.Status == "pending" && !app.StopChange && reason != domain.ExpiryReasonDeparture {
+		if promoted, err = promoteWaitlisted(ctx, tx, app.RouteID.String()); err != nil {
+			return fmt.Errorf("application expire: %w", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("application expire: commit: %w", err)
+	}
+	publishEmailLog(r.nc, emailLogID, emailType)
+	publishPromotions(r.nc, promoted)
+	return nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/geo"
+)
+
+// Conservative kilometre-per-degree factors: the shortest degree of latitude
+// (at the equator) and a degree of longitude on the equator, scaled by
+// cos(lat) at the point furthest from it.
+const (
+	kmPerDegreeLat      = 110.574
+	kmPerDegreeLngEquat = 111.320
+)
+
+// searchArea is a lat/lng bounding box stored in routes.search_area.
+type searchArea struct {
+	minLat, minLng, maxLat, maxLng float64
+}
+
+// newSearchArea returns the bounding box of points widened by padKm on every
+// side. Any point within padKm of a segment between two of the points lies
+// inside it.
+func newSearchArea(padKm float64, points ...[2]float64) searchArea {
+	a := searchArea{
+		minLat: math.Inf(1), minLng: math.Inf(1),
+		maxLat: math.Inf(-1), maxLng: math.Inf(-1),
+	}
+	for _, p := range points {
+		a.minLat = math.Min(a.minLat, p[0])
+		a.maxLat = math.Max(a.maxLat, p[0])
+		a.minLng = math.Min(a.minLng, p[1])
+		a.maxLng = math.Max(a.maxLng, p[1])
+	}
+
+	padLat := padKm / kmPerDegreeLat
+	furthestLat := math.Min(math.Max(math.Abs(a.minLat), math.Abs(a.maxLat))+padLat, 89)
+	padLng := padKm / (kmPerDegreeLngEquat * math.Max(math.Cos(furthestLat*math.Pi/180), 0.01))
+
+	a.minLat -= padLat
+	a.maxLat += padLat
+	a.minLng -= padLng
+	a.maxLng += padLng
+	return a
+}
+
+// wkt returns the area as a POLYGON in X=lng, Y=lat order (SRID 0).
+func (a searchArea) wkt() string {
+	return "POLYGON((" +
+		wktPoint(a.minLat, a.minLng) + "," +
+		wktPoint(a.minLat, a.maxLng) + "," +
+		wktPoint(a.maxLat, a.maxLng) + "," +
+		wktPoint(a.maxLat, a.minLng) + "," +
+		wktPoint(a.minLat, a.minLng) + "))"
+}
+
+// searchPointsWKT returns every point of a search query as a MULTIPOINT.
+func searchPointsWKT(in domain.SearchRouteInput) string {
+	points := make([]string, 0, len(in.Stops)+2)
+	points = append(points, wktPoint(in.StartLat, in.StartLng), wktPoint(in.EndLat, in.EndLng))
+	for _, s := range in.Stops {
+		points = append(points, wktPoint(s.Lat, s.Lng))
+	}
+	return "MULTIPOINT(" + strings.Join(points, ",") + ")"
+}
+
+// polylinePoints returns the vertices of an encoded route polyline. The
+// service validates polylines before they are stored, so undecodable input
+// yields no points.
+func polylinePoints(polyline *string) [][2]float64 {
+	if polyline == nil {
+		return nil
+	}
+	path, err := geo.DecodePolyline(*polyline)
+	if err != nil {
+		return nil
+	}
+	points := make([][2]float64, len(path))
+	for i, p := range path {
+		points[i] = [2]float64{p.Lat, p.Lng}
+	}
+	return points
+}
+
+func wktPoint(lat, lng float64) string {
+	return strconv.FormatFloat(lng, 'f', -1, 64) + " " + strconv.FormatFloat(lat, 'f', -1, 64)
+}
+
+// refreshSearchArea recomputes routes.search_area from the route's current
+// start, end, stops, polyline and max_deviation. Call it inside every
+// transaction that changes any of those.
+func refreshSearchArea(ctx context.Context, tx *sql.Tx, routeID string) error {
+	var startLat, startLng, endLat, endLng, maxDeviation float64
+	var polyline *string
+	err := sq.Select("start_lat", "start_lng", "end_lat", "end_lng", "max_deviation", "polyline").
+		From("routes").
+		Where(sq.Eq{"id": routeID}).
+		RunWith(tx).QueryRowContext(ctx).
+		Scan(&startLat, &startLng, &endLat, &endLng, &maxDeviation, &polyline)
+	if err != nil {
+		return fmt.Errorf("refresh search area: load route: %w", err)
+	}
+
+	points := append([][2]float64{{startLat, startLng}, {endLat, endLng}}, polylinePoints(polyline)...)
+	rows, err := sq.Select("lat", "lng").
+		From("route_stops").
+		Where(sq.Eq{"route_id": routeID}).
+		RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return fmt.Errorf("refresh search area: load stops: %w", err)
+	}
+	for rows.Next() {
+		var p [2]float64
+		if err := rows.Scan(&p[0], &p[1]); err != nil {
+			rows.Close()
+			return fmt.Errorf("refresh search area: scan stop: %w", err)
+		}
+		points = append(points, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("refresh search area: read stops: %w", err)
+	}
+
+	area := newSearchArea(maxDeviation, points...)
+	if _, err = sq.Update("routes").
+		Set("search_area", sq.Expr("ST_GeomFromText(?, 0)", area.wkt())).
+		Where(sq.Eq{"id": routeID}).
+		RunWith(tx).ExecContext(ctx); err != nil {
+		return fmt.Errorf("refresh search area: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+func TestNewSearchArea(t *testing.T) {
+	// Vilnius → Kaunas with a 10 km allowance.
+	area := newSearchArea(10, [2]float64{54.6872, 25.2797}, [2]float64{54.8985, 23.9036})
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		inside   bool
+	}{
+		{"route start", 54.6872, 25.2797, true},
+		{"9 km north of start", 54.6872 + 9/111.0, 25.2797, true},
+		{"9 km east of start", 54.6872, 25.2797 + 9/(111.32*0.577), true},
+		{"midpoint", 54.79, 24.59, true},
+		{"20 km south of start", 54.6872 - 20/111.0, 25.2797, false},
+		{"Riga", 56.9496, 24.1052, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.lat >= area.minLat && tt.lat <= area.maxLat && tt.lng >= area.minLng && tt.lng <= area.maxLng
+			if got != tt.inside {
+				t.Errorf("area %+v contains (%v, %v) = %v, want %v", area, tt.lat, tt.lng, got, tt.inside)
+			}
+		})
+	}
+}
+
+func TestSearchAreaWKT(t *testing.T) {
+	area := searchArea{minLat: 1, minLng: 2, maxLat: 3, maxLng: 4}
+	want := "POLYGON((2 1,4 1,4 3,2 3,2 1))"
+	if got := area.wkt(); got != want {
+		t.Errorf("wkt() = %q, want %q", got, want)
+	}
+}
+
+func TestSearchPointsWKT(t *testing.T) {
+	in := domain.SearchRouteInput{
+		StartLat: 54.5, StartLng: 25.25,
+		EndLat: 54.75, EndLng: 23.5,
+		Stops: []domain.SearchStop{{Lat: 54.6, Lng: 24}},
+	}
+	want := "MULTIPOINT(25.25 54.5,23.5 54.75,24 54.6)"
+	if got := searchPointsWKT(in); got != want {
+		t.Errorf("searchPointsWKT() = %q, want %q", got, want)
+	}
+}
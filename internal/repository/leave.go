@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+)
+
+// Leave takes an approved passenger off their route. The participant row is
+// kept for history with status "left" or "removed" and reason, their stops
+// come off the route (and out of other applications' proposed orders), their
+// private chat is closed and the freed seat goes to the waitlist. The other side is notified: the driver when the passenger
+// left, the passenger when the driver removed them.
+func (r *applicationRepository) Leave(ctx context.Context, id uuid.UUID, status, reason string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("application leave: begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var routeID, requestID string
+	err = sq.Select("p.route_id", "req.id").
+		From("participants p").
+		Join("requests req ON req.participant_id = p.id").
+		Where(sq.Eq{"p.id": id.String(), "p.status": "approved", "p.deleted_at": nil}).
+		RunWith(tx).QueryRowContext(ctx).Scan(&routeID, &requestID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Left, removed or expired in the meantime.
+		return errs.ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("application leave: find request: %w", err)
+	}
+
+	if _, err = lockRoute(ctx, tx, routeID); err != nil {
+		return fmt.Errorf("application leave: %w", err)
+	}
+	stopsBefore, err := routeStopsSnapshot(ctx, tx, routeID)
+	if err != nil {
+		return fmt.Errorf("application leave: %w", err)
+	}
+
+	_, err = sq.Update("participants").
+		Set("status", status).
+		Set("leave_reason", reason).
+		Set("pending_stop_change", 0).
+		Set("awaiting_since", nil).
+		Set("price_share", nil).
+		Set("deleted_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id.String()}).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("application leave: mark %s: %w", status, err)
+	}
+
+	// Other applications may have placed their stops around the leaver's;
+	// drop those references so their proposals only keep stops that remain.
+	_, err = sq.Delete("request_stops").
+		Where("route_stop_id IN (SELECT id FROM route_stops WHERE route_id = ? AND participant_id = ?)", routeID, id.String()).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("application leave: drop references to removed stops: %w", err)
+	}
+	_, err = sq.Delete("route_stops").
+		Where(sq.Eq{"route_id": routeID, "participant_id": id.String()}).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("application leave: remove route stops: %w", err)
+	}
+	if err = refreshSearchArea(ctx, tx, routeID); err != nil {
+		return fmt.Errorf("application leave: %w", err)
+	}
+
+	ev := applicationEvent{
+		eventType:   domain.EventLeft,
+		actor:       actorApplicant,
+		oldStatus:   "approved",
+		newStatus:   status,
+		stopsBefore: stopsBefore,
+	}
+	if status == "removed" {
+		ev.eventType, ev.actor = domain.EventRemoved, actorDriver
+	}
+	if ev.stopsAfter, err = routeStopsSnapshot(ctx, tx, routeID); err != nil {
+		return fmt.Errorf("application leave: %w", err)
+	}
+	if err = recordApplicationEvent(ctx, tx, id.String(), ev); err != nil {
+		return fmt.Errorf("application leave: %w", err)
+	}
+
+	_, err = sq.Update("private_chats").
+		Set("closed_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"closed_at": nil}).
+		Where(sq.Or{sq.Eq{"user1_id": id.String()}, sq.Eq{"user2_id": id.String()}}).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("application leave: close private chat: %w", err)
+	}
+
+	emailType := "passenger_left"
+	if status == "removed" {
+		emailType = "passenger_removed"
+	}
+	emailLogID := uuid.New().String()
+	_, err = sq.Insert("email_logs").
+		Columns("id", "request_id", "type", "status").
+		Values(emailLogID, requestID, emailType, "created").
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("application leave: insert email_log: %w", err)
+	}
+
+	promoted, err := promoteWaitlisted(ctx, tx, routeID)
+	if err != nil {
+		return fmt.Errorf("application leave: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("application leave: commit: %w", err)
+	}
+	publishEmailLog(r.nc, emailLogID, emailType)
+	publishPromotions(r.nc, promoted)
+	return nil
+}
@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 	"github.com/jmartynas/pss-backend/internal/domain"
 	"github.com/jmartynas/pss-backend/internal/errs"
@@ -24,6 +26,15 @@ func NewRouteRepository(db *sql.DB, nc *nats.Conn) domain.RouteRepository {
 	return &routeRepository{db: db, nc: nc}
 }
 
+// approvedSeatsSQL counts the seats taken by approved passengers of route r.
+const approvedSeatsSQL = "(SELECT COALESCE(SUM(p.seats), 0) FROM participants p WHERE p.route_id = r.id AND p.status = 'approved' AND p.deleted_at IS NULL)"
+
+// approvedLuggageSQL counts the luggage approved passengers of route r bring.
+const approvedLuggageSQL = "(SELECT COALESCE(SUM(p.luggage), 0) FROM participants p WHERE p.route_id = r.id AND p.status = 'approved' AND p.deleted_at IS NULL)"
+
+// usedDeviationSQL is the detour (km) approved passengers' stops add to route r.
+const usedDeviationSQL = "(SELECT COALESCE(SUM(p.detour_km), 0) FROM participants p WHERE p.route_id = r.id AND p.status = 'approved' AND p.deleted_at IS NULL)"
+
 // routeColumns are the SELECT columns used by all route queries.
 var routeColumns = []string{
 	"r.id",
@@ -37,9 +48,26 @@ var routeColumns = []string{
 	"r.end_place_id", "r.end_formatted_address",
 	"r.max_passengers",
 	"r.max_deviation",
-	"GREATEST(0, r.max_passengers - (SELECT COUNT(*) FROM participants p WHERE p.route_id = r.id AND p.status = 'approved' AND p.deleted_at IS NULL)) AS available_passengers",
+	"GREATEST(0, r.max_deviation - " + usedDeviationSQL + ") AS remaining_deviation",
+	"GREATEST(0, r.max_passengers - " + approvedSeatsSQL + ") AS available_passengers",
+	"(SELECT COUNT(*) FROM participants p WHERE p.route_id = r.id AND p.status = 'waitlisted' AND p.deleted_at IS NULL) AS waitlisted",
+	"r.luggage_capacity",
+	"GREATEST(0, CAST(r.luggage_capacity AS SIGNED) - " + approvedLuggageSQL + ") AS available_luggage",
 	"r.price",
+	"r.pricing_mode",
+	"r.price_per_km",
+	"r.auto_approve",
+	"r.auto_approve_min_rating",
+	"r.auto_approve_min_rides",
 	"r.leaving_at",
+	"r.arrival_eta",
+	"r.polyline",
+	"r.schedule_id",
+	"r.return_of_route_id",
+	"(SELECT rr.id FROM routes rr WHERE rr.return_of_route_id = r.id AND rr.deleted_at IS NULL LIMIT 1) AS return_route_id",
+	"r.deleted_at",
+	"r.created_at",
+	"r.updated_at",
 }
 
 func routeBaseSelect() sq.SelectBuilder {
@@ -51,18 +79,32 @@ func routeBaseSelect() sq.SelectBuilder {
 func scanRouteRow(row sq.RowScanner) (domain.Route, string, string, error) {
 	var d domain.Route
 	var idStr, creatorIDStr string
-	var vehicleIDStr *string
+	var vehicleIDStr, scheduleIDStr, returnOfStr, returnStr *string
 	err := row.Scan(
 		&idStr, &creatorIDStr, &d.CreatorName, &vehicleIDStr, &d.Description,
 		&d.StartLat, &d.StartLng, &d.StartPlaceID, &d.StartFormattedAddress,
 		&d.EndLat, &d.EndLng, &d.EndPlaceID, &d.EndFormattedAddress,
-		&d.MaxPassengers, &d.MaxDeviation, &d.AvailablePassengers,
-		&d.Price, &d.LeavingAt,
+		&d.MaxPassengers, &d.MaxDeviation, &d.RemainingDeviation, &d.AvailablePassengers,
+		&d.Waitlisted, &d.LuggageCapacity, &d.AvailableLuggage, &d.Price, &d.PricingMode, &d.PricePerKm,
+		&d.AutoApprove, &d.AutoApproveMinRating, &d.AutoApproveMinRides, &d.LeavingAt, &d.ArrivalETA, &d.Polyline, &scheduleIDStr,
+		&returnOfStr, &returnStr, &d.CancelledAt, &d.CreatedAt, &d.UpdatedAt,
 	)
 	if vehicleIDStr != nil {
 		parsed, _ := uuid.Parse(*vehicleIDStr)
 		d.VehicleID = &parsed
 	}
+	if scheduleIDStr != nil {
+		parsed, _ := uuid.Parse(*scheduleIDStr)
+		d.ScheduleID = &parsed
+	}
+	if returnOfStr != nil {
+		parsed, _ := uuid.Parse(*returnOfStr)
+		d.ReturnOfRouteID = &parsed
+	}
+	if returnStr != nil {
+		parsed, _ := uuid.Parse(*returnStr)
+		d.ReturnRouteID = &parsed
+	}
 	return d, idStr, creatorIDStr, err
 }
 
@@ -109,35 +151,94 @@ func (r *routeRepository) Create(ctx context.Context, creatorID uuid.UUID, in do
 	defer tx.Rollback() //nolint:errcheck
 
 	id := uuid.New()
-	var vehicleIDVal interface{}
+	if err = insertRoute(ctx, tx, id, creatorID, in, nil); err != nil {
+		return uuid.Nil, err
+	}
+	var returnID uuid.UUID
+	if in.Return != nil {
+		returnID = uuid.New()
+		if err = insertRoute(ctx, tx, returnID, creatorID, returnLegInput(in), &id); err != nil {
+			return uuid.Nil, fmt.Errorf("route create return leg: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("route create: commit: %w", err)
+	}
+	publishRouteEvent(r.nc, id, "created")
+	if in.Return != nil {
+		publishRouteEvent(r.nc, returnID, "created")
+	}
+	return id, nil
+}
+
+// returnLegInput derives the return route from a route with a return leg:
+// the same car and terms, driven from end to start.
+func returnLegInput(in domain.CreateRouteInput) domain.CreateRouteInput {
+	ret := in
+	ret.StartLat, ret.StartLng, ret.StartPlaceID, ret.StartFormattedAddress = in.EndLat, in.EndLng, in.EndPlaceID, in.EndFormattedAddress
+	ret.EndLat, ret.EndLng, ret.EndPlaceID, ret.EndFormattedAddress = in.StartLat, in.StartLng, in.StartPlaceID, in.StartFormattedAddress
+	ret.LeavingAt = in.Return.LeavingAt
+	ret.Stops = in.Return.Stops
+	ret.Polyline = in.Return.Polyline
+	ret.Return = nil
+	// Only the outbound route is a schedule instance; the return leg follows it
+	// through return_of_route_id.
+	ret.ScheduleID = nil
+	return ret
+}
+
+// insertRoute inserts a route row with its driver participant and stops.
+func insertRoute(ctx context.Context, tx *sql.Tx, id, creatorID uuid.UUID, in domain.CreateRouteInput, returnOf *uuid.UUID) error {
+	var vehicleIDVal, scheduleIDVal, returnOfVal interface{}
 	if in.VehicleID != nil {
 		vehicleIDVal = in.VehicleID.String()
 	}
-	_, err = sq.Insert("routes").
+	if in.ScheduleID != nil {
+		scheduleIDVal = in.ScheduleID.String()
+	}
+	if returnOf != nil {
+		returnOfVal = returnOf.String()
+	}
+	areaPoints := [][2]float64{{in.StartLat, in.StartLng}, {in.EndLat, in.EndLng}}
+	for _, s := range in.Stops {
+		areaPoints = append(areaPoints, [2]float64{s.Lat, s.Lng})
+	}
+	areaPoints = append(areaPoints, polylinePoints(in.Polyline)...)
+	area := newSearchArea(in.MaxDeviation, areaPoints...)
+	_, err := sq.Insert("routes").
 		Columns(
-			"id", "creator_user_id", "vehicle_id", "description",
+			"id", "creator_user_id", "vehicle_id", "schedule_id", "return_of_route_id", "description",
 			"start_lat", "start_lng", "start_place_id", "start_formatted_address",
 			"end_lat", "end_lng", "end_place_id", "end_formatted_address",
-			"max_passengers", "max_deviation", "price", "leaving_at",
+			"max_passengers", "luggage_capacity", "max_deviation", "search_area", "price", "pricing_mode", "price_per_km",
+			"auto_approve", "auto_approve_min_rating", "auto_approve_min_rides", "leaving_at", "polyline",
 		).
 		Values(
-			id.String(), creatorID.String(), vehicleIDVal, nullablePtr(in.Description),
+			id.String(), creatorID.String(), vehicleIDVal, scheduleIDVal, returnOfVal, nullablePtr(in.Description),
 			in.StartLat, in.StartLng, nullablePtr(in.StartPlaceID), nullablePtr(in.StartFormattedAddress),
 			in.EndLat, in.EndLng, nullablePtr(in.EndPlaceID), nullablePtr(in.EndFormattedAddress),
-			in.MaxPassengers, in.MaxDeviation, nullableFloat(in.Price), nullableTime(in.LeavingAt),
+			in.MaxPassengers, in.LuggageCapacity, in.MaxDeviation, sq.Expr("ST_GeomFromText(?, 0)", area.wkt()), nullableFloat(in.Price), pricingMode(in.PricingMode), nullableFloat(in.PricePerKm),
+			in.AutoApprove, positiveFloat(in.AutoApproveMinRating), positiveUint(in.AutoApproveMinRides),
+			nullableTime(in.LeavingAt), nullablePtr(in.Polyline),
 		).
 		RunWith(tx).ExecContext(ctx)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("route create: %w", err)
+		// Only schedule instances have a unique key: (schedule_id, leaving_at).
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return errs.ErrConflict
+		}
+		return fmt.Errorf("route create: %w", err)
 	}
 
 	// Creator becomes a participant with status='driver'.
 	_, err = sq.Insert("participants").
-		Columns("id", "user_id", "route_id", "status").
-		Values(uuid.New().String(), creatorID.String(), id.String(), "driver").
+		Columns("id", "user_id", "route_id", "status", "seats").
+		Values(uuid.New().String(), creatorID.String(), id.String(), "driver", 0).
 		RunWith(tx).ExecContext(ctx)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("route create driver participant: %w", err)
+		return fmt.Errorf("route create driver participant: %w", err)
 	}
 
 	for i, s := range in.Stops {
@@ -146,14 +247,39 @@ func (r *routeRepository) Create(ctx context.Context, creatorID uuid.UUID, in do
 			Values(uuid.New().String(), id.String(), uint(i), s.Lat, s.Lng, nullablePtr(s.PlaceID), nullablePtr(s.FormattedAddress)).
 			RunWith(tx).ExecContext(ctx)
 		if err != nil {
-			return uuid.Nil, fmt.Errorf("route create stop %d: %w", i, err)
+			return fmt.Errorf("route create stop %d: %w", i, err)
 		}
 	}
+	return nil
+}
 
-	if err = tx.Commit(); err != nil {
-		return uuid.Nil, fmt.Errorf("route create: commit: %w", err)
+// checkBooked checks, on a route locked by lockRoute, that the capacities set
+// by in still hold what approved passengers booked, and returns
+// errs.ErrBelowBooked otherwise.
+func checkBooked(ctx context.Context, tx *sql.Tx, routeID string, in domain.UpdateRouteInput) error {
+	if in.MaxPassengers == nil && in.LuggageCapacity == nil && in.MaxDeviation == nil {
+		return nil
 	}
-	return id, nil
+	// A locking read sees approvals committed after tx's snapshot was taken.
+	var seats, luggage uint
+	var detours float64
+	err := sq.Select("COALESCE(SUM(seats), 0)", "COALESCE(SUM(luggage), 0)", "COALESCE(SUM(detour_km), 0)").
+		From("participants").
+		Where(sq.Eq{"route_id": routeID, "status": "approved", "deleted_at": nil}).
+		Suffix("LOCK IN SHARE MODE").
+		RunWith(tx).QueryRowContext(ctx).Scan(&seats, &luggage, &detours)
+	if err != nil {
+		return fmt.Errorf("count booked seats: %w", err)
+	}
+	switch {
+	case in.MaxPassengers != nil && *in.MaxPassengers < seats:
+		return fmt.Errorf("%w: %d seats are booked", errs.ErrBelowBooked, seats)
+	case in.LuggageCapacity != nil && *in.LuggageCapacity < luggage:
+		return fmt.Errorf("%w: %d pieces of luggage are booked", errs.ErrBelowBooked, luggage)
+	case in.MaxDeviation != nil && *in.MaxDeviation < detours:
+		return fmt.Errorf("%w: %.1f km of detours are booked", errs.ErrBelowBooked, detours)
+	}
+	return nil
 }
 
 func (r *routeRepository) Update(ctx context.Context, id, creatorID uuid.UUID, in domain.UpdateRouteInput) error {
@@ -178,6 +304,13 @@ func (r *routeRepository) Update(ctx context.Context, id, creatorID uuid.UUID, i
 	}
 	defer tx.Rollback() //nolint:errcheck
 
+	if _, err = lockRoute(ctx, tx, id.String()); err != nil {
+		return err
+	}
+	if err = checkBooked(ctx, tx, id.String(), in); err != nil {
+		return err
+	}
+
 	ub := sq.Update("routes").Where(sq.Eq{"id": id.String()})
 	if in.Description != nil {
 		ub = ub.Set("description", nullablePtr(in.Description))
@@ -185,12 +318,33 @@ func (r *routeRepository) Update(ctx context.Context, id, creatorID uuid.UUID, i
 	if in.MaxPassengers != nil {
 		ub = ub.Set("max_passengers", *in.MaxPassengers)
 	}
+	if in.LuggageCapacity != nil {
+		ub = ub.Set("luggage_capacity", *in.LuggageCapacity)
+	}
 	if in.MaxDeviation != nil {
 		ub = ub.Set("max_deviation", *in.MaxDeviation)
 	}
 	if in.Price != nil {
 		ub = ub.Set("price", *in.Price)
 	}
+	if in.PricingMode != nil {
+		ub = ub.Set("pricing_mode", pricingMode(*in.PricingMode))
+		if *in.PricingMode != domain.PricingPerKm {
+			ub = ub.Set("price_per_km", nil)
+		}
+	}
+	if in.PricePerKm != nil {
+		ub = ub.Set("price_per_km", *in.PricePerKm)
+	}
+	if in.AutoApprove != nil {
+		ub = ub.Set("auto_approve", *in.AutoApprove)
+	}
+	if in.AutoApproveMinRating != nil {
+		ub = ub.Set("auto_approve_min_rating", positiveFloat(in.AutoApproveMinRating))
+	}
+	if in.AutoApproveMinRides != nil {
+		ub = ub.Set("auto_approve_min_rides", positiveUint(in.AutoApproveMinRides))
+	}
 	if in.LeavingAt != nil {
 		ub = ub.Set("leaving_at", nullableTime(in.LeavingAt))
 	}
@@ -206,6 +360,12 @@ func (r *routeRepository) Update(ctx context.Context, id, creatorID uuid.UUID, i
 			Set("end_place_id", nullablePtr(in.EndPlaceID)).
 			Set("end_formatted_address", nullablePtr(in.EndFormattedAddress))
 	}
+	if in.Polyline != nil {
+		ub = ub.Set("polyline", nullableStr(*in.Polyline))
+	} else if in.StartLat != nil || in.EndLat != nil || in.Stops != nil {
+		// The stored path no longer matches the new geometry.
+		ub = ub.Set("polyline", nil)
+	}
 
 	sqlStr, args, sqlErr := ub.ToSql()
 	if sqlErr == nil && len(args) > 1 {
@@ -230,6 +390,17 @@ func (r *routeRepository) Update(ctx context.Context, id, creatorID uuid.UUID, i
 		}
 	}
 
+	if err = refreshSearchArea(ctx, tx, id.String()); err != nil {
+		return fmt.Errorf("route update: %w", err)
+	}
+
+	var promoted []string
+	if in.MaxPassengers != nil || in.LuggageCapacity != nil {
+		if promoted, err = promoteWaitlisted(ctx, tx, id.String()); err != nil {
+			return fmt.Errorf("route update: %w", err)
+		}
+	}
+
 	// Find driver participant and create a request + email_log for this update.
 	var driverParticipantID string
 	err = sq.Select("id").From("participants").
@@ -258,9 +429,135 @@ func (r *routeRepository) Update(ctx context.Context, id, creatorID uuid.UUID, i
 		return fmt.Errorf("route update: commit: %w", err)
 	}
 	publishEmailLog(r.nc, emailLogID, "route_updated")
+	publishPromotions(r.nc, promoted)
+	publishRouteEvent(r.nc, id, "updated")
+	return nil
+}
+
+func (r *routeRepository) SetETAs(ctx context.Context, routeID uuid.UUID, etas domain.RouteETAs) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("route set etas: begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if etas.Basis != nil {
+		if err = requireETABasis(ctx, tx, routeID.String(), etas.Basis); err != nil {
+			return err
+		}
+	}
+
+	_, err = sq.Update("routes").
+		Set("arrival_eta", etas.Arrival).
+		Where(sq.Eq{"id": routeID.String()}).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("route set etas: update route: %w", err)
+	}
+	_, err = sq.Update("route_stops").
+		Set("eta", nil).
+		Where(sq.Eq{"route_id": routeID.String()}).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("route set etas: clear stops: %w", err)
+	}
+	for stopID, eta := range etas.Stops {
+		_, err = sq.Update("route_stops").
+			Set("eta", eta).
+			Where(sq.Eq{"id": stopID.String(), "route_id": routeID.String()}).
+			RunWith(tx).ExecContext(ctx)
+		if err != nil {
+			return fmt.Errorf("route set etas: update stop: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// requireETABasis locks route routeID for the rest of tx and checks that its
+// departure, end points and stops are still those of basis. It returns
+// errs.ErrConflict otherwise.
+func requireETABasis(ctx context.Context, tx *sql.Tx, routeID string, basis *domain.Route) error {
+	var leavingAt sql.NullTime
+	var startLat, startLng, endLat, endLng float64
+	err := sq.Select("leaving_at", "start_lat", "start_lng", "end_lat", "end_lng").
+		From("routes").
+		Where(sq.Eq{"id": routeID, "deleted_at": nil}).
+		Suffix("FOR UPDATE").
+		RunWith(tx).QueryRowContext(ctx).Scan(&leavingAt, &startLat, &startLng, &endLat, &endLng)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("lock route: %w", err)
+	}
+	if leavingAt.Valid != (basis.LeavingAt != nil) || (leavingAt.Valid && !leavingAt.Time.Equal(*basis.LeavingAt)) ||
+		startLat != basis.StartLat || startLng != basis.StartLng || endLat != basis.EndLat || endLng != basis.EndLng {
+		return errs.ErrConflict
+	}
+
+	rows, err := sq.Select("id", "lat", "lng").
+		From("route_stops").
+		Where(sq.Eq{"route_id": routeID}).
+		OrderBy("position").
+		RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch route stops: %w", err)
+	}
+	defer rows.Close()
+	i := 0
+	for ; rows.Next(); i++ {
+		var id string
+		var lat, lng float64
+		if err := rows.Scan(&id, &lat, &lng); err != nil {
+			return fmt.Errorf("scan route stop: %w", err)
+		}
+		if i >= len(basis.Stops) || id != basis.Stops[i].ID.String() || lat != basis.Stops[i].Lat || lng != basis.Stops[i].Lng {
+			return errs.ErrConflict
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("fetch route stops: %w", err)
+	}
+	if i != len(basis.Stops) {
+		return errs.ErrConflict
+	}
 	return nil
 }
 
+func (r *routeRepository) SetPriceShares(ctx context.Context, routeID uuid.UUID, shares map[uuid.UUID]float64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("route set price shares: begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	_, err = sq.Update("participants").
+		Set("price_share", nil).
+		Where(sq.Eq{"route_id": routeID.String()}).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("route set price shares: clear: %w", err)
+	}
+	for participantID, share := range shares {
+		_, err = sq.Update("participants").
+			Set("price_share", share).
+			Where(sq.Eq{"id": participantID.String(), "route_id": routeID.String()}).
+			RunWith(tx).ExecContext(ctx)
+		if err != nil {
+			return fmt.Errorf("route set price shares: update participant: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// pricingMode stores an unset mode as flat.
+func pricingMode(mode string) string {
+	if mode == "" {
+		return domain.PricingFlat
+	}
+	return mode
+}
+
 func (r *routeRepository) Delete(ctx context.Context, id, creatorID uuid.UUID) error {
 	var ownerIDStr string
 	err := sq.Select("creator_user_id").
@@ -402,7 +699,10 @@ func cancelRouteCleanup(ctx context.Context, tx *sql.Tx, routeID string) (string
 
 func (r *routeRepository) ListByCreator(ctx context.Context, creatorID uuid.UUID, filter domain.RouteFilter) ([]domain.Route, error) {
 	qb := routeBaseSelect().
-		Where(sq.Eq{"r.creator_user_id": creatorID.String(), "r.deleted_at": nil})
+		Where(sq.Eq{"r.creator_user_id": creatorID.String()})
+	if filter != domain.RouteFilterWithCancelled {
+		qb = qb.Where(sq.Eq{"r.deleted_at": nil})
+	}
 	qb = applyRouteTimeFilter(qb, filter)
 	rows, err := qb.OrderBy("r.created_at DESC").RunWith(r.db).QueryContext(ctx)
 	if err != nil {
@@ -413,9 +713,17 @@ func (r *routeRepository) ListByCreator(ctx context.Context, creatorID uuid.UUID
 }
 
 func (r *routeRepository) ListByParticipant(ctx context.Context, userID uuid.UUID, filter domain.RouteFilter) ([]domain.Route, error) {
+	join := "participants p_filter ON p_filter.route_id = r.id AND p_filter.deleted_at IS NULL AND p_filter.status IN ('driver','approved')"
+	if filter == domain.RouteFilterWithCancelled {
+		// Cancelling a route soft-deletes its participants along with it.
+		join = "participants p_filter ON p_filter.route_id = r.id AND (p_filter.deleted_at IS NULL OR p_filter.deleted_at >= r.deleted_at) AND p_filter.status IN ('driver','approved')"
+	}
 	qb := routeBaseSelect().
-		Join("participants p_filter ON p_filter.route_id = r.id AND p_filter.deleted_at IS NULL AND p_filter.status IN ('driver','approved')").
-		Where(sq.Eq{"p_filter.user_id": userID.String(), "r.deleted_at": nil})
+		Join(join).
+		Where(sq.Eq{"p_filter.user_id": userID.String()})
+	if filter != domain.RouteFilterWithCancelled {
+		qb = qb.Where(sq.Eq{"r.deleted_at": nil})
+	}
 	qb = applyRouteTimeFilter(qb, filter)
 	rows, err := qb.OrderBy("r.created_at DESC").RunWith(r.db).QueryContext(ctx)
 	if err != nil {
@@ -425,17 +733,38 @@ func (r *routeRepository) ListByParticipant(ctx context.Context, userID uuid.UUI
 	return scanRoutesWithDetails(ctx, r.db, rows)
 }
 
-func (r *routeRepository) ListSearchable(ctx context.Context) ([]domain.Route, error) {
-	rows, err := routeBaseSelect().
+// ListSearchable narrows candidates with the routes_search_area spatial index:
+// a route can only be within max_deviation of the query when its search area
+// contains every query point. Full routes are included, since passengers can
+// still join their waitlist, unless the search asks for free seats.
+func (r *routeRepository) ListSearchable(ctx context.Context, in domain.SearchRouteInput) ([]domain.Route, error) {
+	qb := routeBaseSelect().
 		Where(sq.And{
 			sq.Eq{"r.deleted_at": nil},
-			sq.Expr("r.max_passengers > (SELECT COUNT(*) FROM participants p WHERE p.route_id = r.id AND p.deleted_at IS NULL)"),
+			sq.Expr("MBRContains(r.search_area, ST_GeomFromText(?, 0))", searchPointsWKT(in)),
 			sq.Or{
 				sq.Eq{"r.leaving_at": nil},
 				sq.Expr("r.leaving_at > NOW()"),
 			},
-		}).
-		RunWith(r.db).QueryContext(ctx)
+		})
+	if in.LeavingAfter != nil {
+		qb = qb.Where(sq.GtOrEq{"r.leaving_at": *in.LeavingAfter})
+	}
+	if in.LeavingBefore != nil {
+		qb = qb.Where(sq.LtOrEq{"r.leaving_at": *in.LeavingBefore})
+	}
+	if in.MaxPrice != nil {
+		// A per-km fare depends on the trip, so the service filters those.
+		qb = qb.Where(sq.Or{
+			sq.Eq{"r.pricing_mode": domain.PricingPerKm},
+			sq.Eq{"r.price": nil},
+			sq.LtOrEq{"r.price": *in.MaxPrice},
+		})
+	}
+	if in.MinSeats != nil {
+		qb = qb.Where(sq.Expr("r.max_passengers - "+approvedSeatsSQL+" >= ?", *in.MinSeats))
+	}
+	rows, err := qb.RunWith(r.db).QueryContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("route list searchable: %w", err)
 	}
@@ -508,7 +837,7 @@ func scanRoutesWithDetails(ctx context.Context, db *sql.DB, rows *sql.Rows) ([]d
 
 // fetchRouteStops batch-fetches stops for the given route IDs.
 func fetchRouteStops(ctx context.Context, db *sql.DB, routeIDs []string) (map[string][]domain.Stop, error) {
-	rows, err := sq.Select("route_id", "id", "position", "lat", "lng", "place_id", "formatted_address", "participant_id").
+	rows, err := sq.Select("route_id", "id", "position", "lat", "lng", "place_id", "formatted_address", "participant_id", "eta").
 		From("route_stops").
 		Where(sq.Eq{"route_id": routeIDs}).
 		OrderBy("route_id", "position").
@@ -522,7 +851,7 @@ func fetchRouteStops(ctx context.Context, db *sql.DB, routeIDs []string) (map[st
 	for rows.Next() {
 		var routeIDStr, stopIDStr string
 		var s domain.Stop
-		if err := rows.Scan(&routeIDStr, &stopIDStr, &s.Position, &s.Lat, &s.Lng, &s.PlaceID, &s.FormattedAddress, &s.ParticipantID); err != nil {
+		if err := rows.Scan(&routeIDStr, &stopIDStr, &s.Position, &s.Lat, &s.Lng, &s.PlaceID, &s.FormattedAddress, &s.ParticipantID, &s.ETA); err != nil {
 			return nil, fmt.Errorf("scan route stop: %w", err)
 		}
 		s.ID, _ = uuid.Parse(stopIDStr)
@@ -541,7 +870,7 @@ type participantWithRoute struct {
 
 // fetchRouteParticipants batch-fetches participants for the given route IDs.
 func fetchRouteParticipants(ctx context.Context, db *sql.DB, routeIDs []string) ([]participantWithRoute, error) {
-	rows, err := sq.Select("p.route_id", "p.user_id", "COALESCE(u.name, '')", "p.status").
+	rows, err := sq.Select("p.route_id", "p.id", "p.user_id", "COALESCE(u.name, '')", "p.status", "p.seats", "p.luggage", "p.price_share").
 		From("participants p").
 		Join("users u ON u.id = p.user_id").
 		Where(sq.Eq{"p.route_id": routeIDs, "p.deleted_at": nil}).
@@ -556,10 +885,11 @@ func fetchRouteParticipants(ctx context.Context, db *sql.DB, routeIDs []string)
 	var result []participantWithRoute
 	for rows.Next() {
 		var pr participantWithRoute
-		var userIDStr string
-		if err := rows.Scan(&pr.routeID, &userIDStr, &pr.Name, &pr.Status); err != nil {
+		var idStr, userIDStr string
+		if err := rows.Scan(&pr.routeID, &idStr, &userIDStr, &pr.Name, &pr.Status, &pr.Seats, &pr.Luggage, &pr.PriceShare); err != nil {
 			return nil, fmt.Errorf("scan participant: %w", err)
 		}
+		pr.ID, _ = uuid.Parse(idStr)
 		pr.UserID, _ = uuid.Parse(userIDStr)
 		result = append(result, pr)
 	}
@@ -579,6 +909,17 @@ func publishEmailLog(nc *nats.Conn, emailLogID, emailType string) {
 	nc.Publish("email", []byte(payload)) //nolint:errcheck
 }
 
+// publishRouteEvent publishes a "route" NATS message after a route is created
+// or updated, for consumers such as the saved search matcher. Like
+// publishEmailLog it ignores errors.
+func publishRouteEvent(nc *nats.Conn, routeID uuid.UUID, event string) {
+	if nc == nil {
+		return
+	}
+	payload, _ := json.Marshal(domain.RouteEvent{RouteID: routeID, Event: event})
+	nc.Publish("route", payload) //nolint:errcheck
+}
+
 func nullablePtr(s *string) interface{} {
 	if s == nil {
 		return nil
@@ -599,3 +940,19 @@ func nullableFloat(f *float64) interface{} {
 	}
 	return *f
 }
+
+// positiveFloat stores an unset or zero minimum as NULL (no minimum).
+func positiveFloat(f *float64) interface{} {
+	if f == nil || *f <= 0 {
+		return nil
+	}
+	return *f
+}
+
+// positiveUint stores an unset or zero minimum as NULL (no minimum).
+func positiveUint(n *uint) interface{} {
+	if n == nil || *n == 0 {
+		return nil
+	}
+	return *n
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/nats-io/nats.go"
+)
+
+type savedSearchRepository struct {
+	db *sql.DB
+	nc *nats.Conn
+}
+
+// NewSavedSearchRepository returns a domain.SavedSearchRepository backed by MySQL.
+func NewSavedSearchRepository(db *sql.DB, nc *nats.Conn) domain.SavedSearchRepository {
+	return &savedSearchRepository{db: db, nc: nc}
+}
+
+func (r *savedSearchRepository) Create(ctx context.Context, userID uuid.UUID, query domain.SearchRouteInput) (uuid.UUID, error) {
+	query.Limit, query.Cursor = 0, ""
+	b, err := json.Marshal(query)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("saved search create: encode query: %w", err)
+	}
+	id := uuid.New()
+	_, err = sq.Insert("saved_searches").
+		Columns("id", "user_id", "query", "points", "leaving_after", "leaving_before").
+		Values(
+			id.String(), userID.String(), string(b),
+			sq.Expr("ST_GeomFromText(?, 0)", searchPointsWKT(query)),
+			nullableTime(query.LeavingAfter), nullableTime(query.LeavingBefore),
+		).
+		RunWith(r.db).ExecContext(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("saved search create: %w", err)
+	}
+	return id, nil
+}
+
+func (r *savedSearchRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.SavedSearch, error) {
+	rows, err := sq.Select("s.id", "s.user_id", "s.query", "s.created_at").
+		From("saved_searches s").
+		Where(sq.Eq{"s.user_id": userID.String(), "s.deleted_at": nil}).
+		OrderBy("s.created_at DESC").
+		RunWith(r.db).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("saved search list: %w", err)
+	}
+	return scanSavedSearches(rows)
+}
+
+func (r *savedSearchRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	var ownerStr string
+	err := sq.Select("user_id").From("saved_searches").
+		Where(sq.Eq{"id": id.String(), "deleted_at": nil}).
+		RunWith(r.db).QueryRowContext(ctx).Scan(&ownerStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("saved search delete: fetch owner: %w", err)
+	}
+	if ownerStr != userID.String() {
+		return errs.ErrForbidden
+	}
+	_, err = sq.Update("saved_searches").
+		Set("deleted_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id.String()}).
+		RunWith(r.db).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("saved search delete: %w", err)
+	}
+	return nil
+}
+
+// ListMatchCandidates narrows saved searches with the saved_searches_points
+// spatial index; the service then checks deviation and the remaining filters.
+func (r *savedSearchRepository) ListMatchCandidates(ctx context.Context, route *domain.Route) ([]domain.SavedSearch, error) {
+	if route.LeavingAt == nil {
+		return []domain.SavedSearch{}, nil
+	}
+	rows, err := sq.Select("s.id", "s.user_id", "s.query", "s.created_at").
+		From("saved_searches s").
+		Join("routes r ON r.id = ?", route.ID.String()).
+		Where(sq.Eq{"s.deleted_at": nil}).
+		Where(sq.NotEq{"s.user_id": route.CreatorID.String()}).
+		Where(sq.GtOrEq{"s.leaving_before": *route.LeavingAt}).
+		Where(sq.Or{sq.Eq{"s.leaving_after": nil}, sq.LtOrEq{"s.leaving_after": *route.LeavingAt}}).
+		Where("MBRWithin(s.points, r.search_area)").
+		Where("NOT EXISTS (SELECT 1 FROM saved_search_matches m WHERE m.saved_search_id = s.id AND m.route_id = r.id)").
+		RunWith(r.db).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("saved search match candidates: %w", err)
+	}
+	return scanSavedSearches(rows)
+}
+
+func (r *savedSearchRepository) RecordMatch(ctx context.Context, savedSearchID, routeID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("saved search match: begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	emailLogID := uuid.New().String()
+	_, err = sq.Insert("email_logs").
+		Columns("id", "type", "status").
+		Values(emailLogID, "saved_search_match", "created").
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("saved search match: insert email_log: %w", err)
+	}
+	_, err = sq.Insert("saved_search_matches").
+		Columns("id", "saved_search_id", "route_id", "email_log_id").
+		Values(uuid.New().String(), savedSearchID.String(), routeID.String(), emailLogID).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil
+		}
+		return fmt.Errorf("saved search match: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("saved search match: commit: %w", err)
+	}
+	publishEmailLog(r.nc, emailLogID, "saved_search_match")
+	return nil
+}
+
+func scanSavedSearches(rows *sql.Rows) ([]domain.SavedSearch, error) {
+	defer rows.Close()
+	out := []domain.SavedSearch{}
+	for rows.Next() {
+		var s domain.SavedSearch
+		var idStr, userIDStr string
+		var query []byte
+		if err := rows.Scan(&idStr, &userIDStr, &query, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("saved search scan: %w", err)
+		}
+		if err := json.Unmarshal(query, &s.Query); err != nil {
+			return nil, fmt.Errorf("saved search scan: decode query: %w", err)
+		}
+		s.ID, _ = uuid.Parse(idStr)
+		s.UserID, _ = uuid.Parse(userIDStr)
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("saved search scan: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+)
+
+type scheduleRepository struct{ db *sql.DB }
+
+// NewScheduleRepository returns a domain.ScheduleRepository backed by MySQL.
+func NewScheduleRepository(db *sql.DB) domain.ScheduleRepository {
+	return &scheduleRepository{db: db}
+}
+
+var scheduleColumns = []string{
+	"id", "creator_user_id", "recurrence", "timezone", "template",
+	"ends_at", "materialized_until", "created_at",
+}
+
+func scanSchedule(row sq.RowScanner) (domain.RouteSchedule, error) {
+	var s domain.RouteSchedule
+	var idStr, creatorIDStr string
+	var template []byte
+	err := row.Scan(&idStr, &creatorIDStr, &s.Recurrence, &s.Timezone, &template,
+		&s.EndsAt, &s.MaterializedUntil, &s.CreatedAt)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(template, &s.Template); err != nil {
+		return s, fmt.Errorf("decode template: %w", err)
+	}
+	s.ID, _ = uuid.Parse(idStr)
+	s.CreatorID, _ = uuid.Parse(creatorIDStr)
+	return s, nil
+}
+
+func (r *scheduleRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.RouteSchedule, error) {
+	s, err := scanSchedule(sq.Select(scheduleColumns...).
+		From("route_schedules").
+		Where(sq.Eq{"id": id.String(), "deleted_at": nil}).
+		RunWith(r.db).QueryRowContext(ctx))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("schedule get by id: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *scheduleRepository) Create(ctx context.Context, creatorID uuid.UUID, in domain.CreateScheduleInput) (uuid.UUID, error) {
+	template, err := json.Marshal(in.Template)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("schedule create: encode template: %w", err)
+	}
+	id := uuid.New()
+	_, err = sq.Insert("route_schedules").
+		Columns("id", "creator_user_id", "recurrence", "timezone", "template", "ends_at").
+		Values(id.String(), creatorID.String(), in.Recurrence, in.Timezone, string(template), in.EndsAt).
+		RunWith(r.db).ExecContext(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("schedule create: %w", err)
+	}
+	return id, nil
+}
+
+func (r *scheduleRepository) Update(ctx context.Context, id, creatorID uuid.UUID, in domain.CreateScheduleInput) error {
+	if err := r.checkOwner(ctx, id, creatorID); err != nil {
+		return fmt.Errorf("schedule update: %w", err)
+	}
+	template, err := json.Marshal(in.Template)
+	if err != nil {
+		return fmt.Errorf("schedule update: encode template: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("schedule update: begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	_, err = sq.Update("route_schedules").
+		Set("recurrence", in.Recurrence).
+		Set("timezone", in.Timezone).
+		Set("template", string(template)).
+		Set("ends_at", in.EndsAt).
+		Set("materialized_until", nil).
+		Where(sq.Eq{"id": id.String()}).
+		RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("schedule update: %w", err)
+	}
+	if err = cancelUnbookedInstances(ctx, tx, id); err != nil {
+		return fmt.Errorf("schedule update: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("schedule update: commit: %w", err)
+	}
+	return nil
+}
+
+func (r *scheduleRepository) Delete(ctx context.Context, id, creatorID uuid.UUID) error {
+	if err := r.checkOwner(ctx, id, creatorID); err != nil {
+		return fmt.Errorf("schedule delete: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("schedule delete: begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err = sq.Update("route_schedules").
+		Set("deleted_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id.String()}).
+		RunWith(tx).ExecContext(ctx); err != nil {
+		return fmt.Errorf("schedule delete: %w", err)
+	}
+	if err = cancelUnbookedInstances(ctx, tx, id); err != nil {
+		return fmt.Errorf("schedule delete: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("schedule delete: commit: %w", err)
+	}
+	return nil
+}
+
+func (r *scheduleRepository) checkOwner(ctx context.Context, id, creatorID uuid.UUID) error {
+	var ownerStr string
+	err := sq.Select("creator_user_id").From("route_schedules").
+		Where(sq.Eq{"id": id.String(), "deleted_at": nil}).
+		RunWith(r.db).QueryRowContext(ctx).Scan(&ownerStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("fetch owner: %w", err)
+	}
+	if ownerStr != creatorID.String() {
+		return errs.ErrForbidden
+	}
+	return nil
+}
+
+// cancelUnbookedInstances soft-deletes the schedule's future instances that
+// nobody has applied to. They are also detached from the schedule so that
+// instances regenerated for the same departure do not hit the
+// (schedule_id, leaving_at) unique key; instances cancelled individually by
+// the driver stay attached, which stops the job from recreating them.
+func cancelUnbookedInstances(ctx context.Context, tx *sql.Tx, scheduleID uuid.UUID) error {
+	// Return legs are not attached to the schedule, so cancel them through the
+	// outbound instance before it is detached.
+	_, err := tx.ExecContext(ctx, `
+		UPDATE routes rr
+		JOIN routes r ON r.id = rr.return_of_route_id
+		SET rr.deleted_at = NOW()
+		WHERE r.schedule_id = ? AND r.deleted_at IS NULL AND r.leaving_at > NOW()
+		  AND rr.deleted_at IS NULL
+		  AND NOT EXISTS (
+		    SELECT 1 FROM participants p
+		    WHERE p.route_id IN (r.id, rr.id) AND p.status <> 'driver' AND p.deleted_at IS NULL
+		  )`, scheduleID.String())
+	if err != nil {
+		return fmt.Errorf("cancel unbooked return legs: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE routes r
+		SET r.deleted_at = NOW(), r.schedule_id = NULL
+		WHERE r.schedule_id = ? AND r.deleted_at IS NULL AND r.leaving_at > NOW()
+		  AND NOT EXISTS (
+		    SELECT 1 FROM participants p
+		    WHERE p.route_id = r.id AND p.status <> 'driver' AND p.deleted_at IS NULL
+		  )`, scheduleID.String())
+	if err != nil {
+		return fmt.Errorf("cancel unbooked instances: %w", err)
+	}
+	return nil
+}
+
+func (r *scheduleRepository) ListByCreator(ctx context.Context, creatorID uuid.UUID) ([]domain.RouteSchedule, error) {
+	return r.list(ctx, sq.Select(scheduleColumns...).
+		From("route_schedules").
+		Where(sq.Eq{"creator_user_id": creatorID.String(), "deleted_at": nil}).
+		OrderBy("created_at DESC"))
+}
+
+func (r *scheduleRepository) ListDue(ctx context.Context, horizon time.Time) ([]domain.RouteSchedule, error) {
+	return r.list(ctx, sq.Select(scheduleColumns...).
+		From("route_schedules").
+		Where(sq.Eq{"deleted_at": nil}).
+		Where(sq.Gt{"ends_at": time.Now()}).
+		Where(sq.Or{sq.Eq{"materialized_until": nil}, sq.Lt{"materialized_until": horizon}}))
+}
+
+func (r *scheduleRepository) list(ctx context.Context, qb sq.SelectBuilder) ([]domain.RouteSchedule, error) {
+	rows, err := qb.RunWith(r.db).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("schedule list: %w", err)
+	}
+	defer rows.Close()
+
+	out := []domain.RouteSchedule{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("schedule scan: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("schedule list: %w", err)
+	}
+	return out, nil
+}
+
+func (r *scheduleRepository) SetMaterializedUntil(ctx context.Context, id uuid.UUID, until time.Time) error {
+	_, err := sq.Update("route_schedules").
+		Set("materialized_until", until).
+		Where(sq.Eq{"id": id.String()}).
+		RunWith(r.db).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("schedule set materialized until: %w", err)
+	}
+	return nil
+}
@@ -46,22 +46,53 @@ func (r *userRepository) Upsert(ctx context.Context, email, name, provider, prov
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
-	var u domain.User
-	var idStr string
-	err := sq.Select(
-		"id", "email", "COALESCE(name, '')", "provider", "provider_sub",
-		"COALESCE(status, '')", "created_at", "updated_at",
-	).
-		From("users").
-		Where(sq.Eq{"id": id.String()}).
-		RunWith(r.db).QueryRowContext(ctx).
-		Scan(&idStr, &u.Email, &u.Name, &u.Provider, &u.ProviderSub, &u.Status, &u.CreatedAt, &u.UpdatedAt)
+	u, err := scanUser(userSelect().Where(sq.Eq{"id": id.String()}).RunWith(r.db).QueryRowContext(ctx))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errs.ErrNotFound
 		}
 		return nil, fmt.Errorf("user get by id: %w", err)
 	}
+	return u, nil
+}
+
+func (r *userRepository) GetByCalendarToken(ctx context.Context, token string) (*domain.User, error) {
+	u, err := scanUser(userSelect().
+		Where(sq.Eq{"calendar_token": token, "status": "active"}).
+		RunWith(r.db).QueryRowContext(ctx))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("user get by calendar token: %w", err)
+	}
+	return u, nil
+}
+
+func (r *userRepository) SetCalendarToken(ctx context.Context, id uuid.UUID, token string) error {
+	_, err := sq.Update("users").
+		Set("calendar_token", token).
+		Where(sq.Eq{"id": id.String()}).
+		RunWith(r.db).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("user set calendar token: %w", err)
+	}
+	return nil
+}
+
+func userSelect() sq.SelectBuilder {
+	return sq.Select(
+		"id", "email", "COALESCE(name, '')", "provider", "provider_sub",
+		"COALESCE(status, '')", "calendar_token", "created_at", "updated_at",
+	).From("users")
+}
+
+func scanUser(row sq.RowScanner) (*domain.User, error) {
+	var u domain.User
+	var idStr string
+	if err := row.Scan(&idStr, &u.Email, &u.Name, &u.Provider, &u.ProviderSub, &u.Status, &u.CalendarToken, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		return nil, err
+	}
 	u.ID, _ = uuid.Parse(idStr)
 	return &u, nil
 }
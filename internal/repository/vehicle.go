@@ -18,6 +18,25 @@ func NewVehicleRepository(db *sql.DB) domain.VehicleRepository {
 	return &vehicleRepository{db: db}
 }
 
+func (r *vehicleRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Vehicle, error) {
+	var v domain.Vehicle
+	var idStr, userIDStr string
+	err := sq.Select("id", "user_id", "COALESCE(make,'')", "model", "plate_number", "seats", "created_at").
+		From("vehicles").
+		Where(sq.Eq{"id": id.String()}).
+		RunWith(r.db).QueryRowContext(ctx).
+		Scan(&idStr, &userIDStr, &v.Make, &v.Model, &v.PlateNumber, &v.Seats, &v.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vehicle get: %w", err)
+	}
+	v.ID, _ = uuid.Parse(idStr)
+	v.UserID, _ = uuid.Parse(userIDStr)
+	return &v, nil
+}
+
 func (r *vehicleRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Vehicle, error) {
 	rows, err := sq.Select("id", "user_id", "COALESCE(make,'')", "model", "plate_number", "seats", "created_at").
 		From("vehicles").
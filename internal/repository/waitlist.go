@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/nats-io/nats.go"
+)
+
+// promoteWaitlisted moves the oldest waitlisted applications of a route to
+// pending while their seats and luggage fit in what approved passengers leave
+// free, the same test Apply uses to put applications on the waitlist, and
+// queues an email for each promoted applicant.
+// The waitlist keeps its order: a booking that does not fit holds back the
+// ones behind it. It runs inside the transaction that freed the seat and
+// returns the email_log IDs to publish after commit.
+func promoteWaitlisted(ctx context.Context, tx *sql.Tx, routeID string) ([]string, error) {
+	var maxPassengers, taken, luggageTaken int
+	var luggageCapacity *int
+	err := sq.Select("r.max_passengers", "r.luggage_capacity",
+		approvedSeatsSQL, approvedLuggageSQL).
+		From("routes r").
+		Where(sq.Eq{"r.id": routeID, "r.deleted_at": nil}).
+		Where(sq.Or{sq.Eq{"r.leaving_at": nil}, sq.Expr("r.leaving_at > NOW()")}).
+		RunWith(tx).QueryRowContext(ctx).Scan(&maxPassengers, &luggageCapacity, &taken, &luggageTaken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("promote waitlisted: count seats: %w", err)
+	}
+	if taken >= maxPassengers {
+		return nil, nil
+	}
+
+	rows, err := sq.Select("p.id", "req.id", "p.seats", "p.luggage").
+		From("participants p").
+		Join("requests req ON req.participant_id = p.id").
+		Where(sq.Eq{"p.route_id": routeID, "p.status": "waitlisted", "p.deleted_at": nil}).
+		OrderBy("p.created_at ASC", "p.id ASC").
+		Limit(uint64(maxPassengers - taken)).
+		RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("promote waitlisted: list: %w", err)
+	}
+	type waiting struct {
+		participantID, requestID string
+		seats, luggage           int
+	}
+	var promoted []waiting
+	free := maxPassengers - taken
+	for rows.Next() {
+		var w waiting
+		if err := rows.Scan(&w.participantID, &w.requestID, &w.seats, &w.luggage); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("promote waitlisted: scan: %w", err)
+		}
+		if w.seats > free || (luggageCapacity != nil && luggageTaken+w.luggage > *luggageCapacity) {
+			break
+		}
+		free -= w.seats
+		luggageTaken += w.luggage
+		promoted = append(promoted, w)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("promote waitlisted: list: %w", err)
+	}
+
+	var emailLogIDs []string
+	for _, w := range promoted {
+		if _, err := sq.Update("participants").
+			Set("status", "pending").
+			Set("awaiting_since", sq.Expr("NOW()")).
+			Where(sq.Eq{"id": w.participantID}).
+			RunWith(tx).ExecContext(ctx); err != nil {
+			return nil, fmt.Errorf("promote waitlisted: update status: %w", err)
+		}
+		err := recordApplicationEvent(ctx, tx, w.participantID, applicationEvent{
+			eventType: domain.EventPromoted,
+			actor:     actorSystem,
+			oldStatus: "waitlisted",
+			newStatus: "pending",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("promote waitlisted: %w", err)
+		}
+		emailLogID := uuid.New().String()
+		if _, err := sq.Insert("email_logs").
+			Columns("id", "request_id", "type", "status").
+			Values(emailLogID, w.requestID, "waitlist_promoted", "created").
+			RunWith(tx).ExecContext(ctx); err != nil {
+			return nil, fmt.Errorf("promote waitlisted: insert email_log: %w", err)
+		}
+		emailLogIDs = append(emailLogIDs, emailLogID)
+	}
+	return emailLogIDs, nil
+}
+
+// publishPromotions publishes the emails queued by promoteWaitlisted.
+func publishPromotions(nc *nats.Conn, emailLogIDs []string) {
+	for _, id := range emailLogIDs {
+		publishEmailLog(nc, id, "waitlist_promoted")
+	}
+}
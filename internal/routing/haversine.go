@@ -0,0 +1,68 @@
+package routing
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/geo"
+)
+
+// Haversine is a domain.Router that drives in straight lines between the
+// points at a constant average speed. It needs no external service.
+type Haversine struct {
+	speedKmh float64
+}
+
+// NewHaversine returns a straight-line router travelling at speedKmh.
+func NewHaversine(speedKmh float64) *Haversine {
+	return &Haversine{speedKmh: speedKmh}
+}
+
+func (h *Haversine) Route(_ context.Context, points []domain.LatLng) (*domain.RoutePath, error) {
+	if len(points) < 2 {
+		return nil, errTooFewPoints
+	}
+	km := 0.0
+	legs := make([]time.Duration, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		leg := geo.Haversine(points[i-1].Lat, points[i-1].Lng, points[i].Lat, points[i].Lng)
+		legs[i-1] = h.duration(leg)
+		km += leg
+	}
+	geometry := make([]domain.LatLng, len(points))
+	copy(geometry, points)
+	return &domain.RoutePath{
+		DistanceKm:  km,
+		Duration:    h.duration(km),
+		Legs:        legs,
+		Geometry:    geometry,
+		Approximate: true,
+	}, nil
+}
+
+func (h *Haversine) duration(km float64) time.Duration {
+	return time.Duration(km / h.speedKmh * float64(time.Hour))
+}
+
+// Fallback is a domain.Router that asks primary first and answers from
+// secondary whenever primary fails.
+type Fallback struct {
+	primary, secondary domain.Router
+}
+
+// NewFallback returns a router that falls back to secondary on primary errors.
+func NewFallback(primary, secondary domain.Router) *Fallback {
+	return &Fallback{primary: primary, secondary: secondary}
+}
+
+func (f *Fallback) Route(ctx context.Context, points []domain.LatLng) (*domain.RoutePath, error) {
+	path, err := f.primary.Route(ctx, points)
+	if err == nil {
+		return path, nil
+	}
+	if ctx.Err() != nil {
+		return nil, err
+	}
+	return f.secondary.Route(ctx, points)
+}
@@ -0,0 +1,89 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+var errTooFewPoints = errors.New("routing: at least two points are required")
+
+// OSRM is a domain.Router backed by the HTTP API of an OSRM server
+// (http://project-osrm.org/docs/v5.24.0/api/#route-service).
+type OSRM struct {
+	baseURL string
+	profile string
+	client  *http.Client
+}
+
+// NewOSRM returns a router that queries the OSRM server at baseURL using the
+// given profile (e.g. "driving").
+func NewOSRM(baseURL, profile string, client *http.Client) *OSRM {
+	return &OSRM{baseURL: strings.TrimRight(baseURL, "/"), profile: profile, client: client}
+}
+
+type osrmResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Routes  []struct {
+		Distance float64 `json:"distance"` // metres
+		Duration float64 `json:"duration"` // seconds
+		Legs     []struct {
+			Duration float64 `json:"duration"` // seconds
+		} `json:"legs"`
+		Geometry struct {
+			Coordinates [][2]float64 `json:"coordinates"` // [lng, lat]
+		} `json:"geometry"`
+	} `json:"routes"`
+}
+
+func (o *OSRM) Route(ctx context.Context, points []domain.LatLng) (*domain.RoutePath, error) {
+	if len(points) < 2 {
+		return nil, errTooFewPoints
+	}
+	coords := make([]string, len(points))
+	for i, p := range points {
+		coords[i] = strconv.FormatFloat(p.Lng, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lat, 'f', -1, 64)
+	}
+	url := fmt.Sprintf("%s/route/v1/%s/%s?overview=full&geometries=geojson", o.baseURL, o.profile, strings.Join(coords, ";"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("osrm: build request: %w", err)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("osrm: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body osrmResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("osrm: decode response (status %d): %w", resp.StatusCode, err)
+	}
+	if body.Code != "Ok" || len(body.Routes) == 0 {
+		return nil, fmt.Errorf("osrm: %s: %s", body.Code, body.Message)
+	}
+
+	r := body.Routes[0]
+	path := &domain.RoutePath{
+		DistanceKm: r.Distance / 1000,
+		Duration:   time.Duration(r.Duration * float64(time.Second)),
+		Legs:       make([]time.Duration, len(r.Legs)),
+		Geometry:   make([]domain.LatLng, len(r.Geometry.Coordinates)),
+	}
+	for i, leg := range r.Legs {
+		path.Legs[i] = time.Duration(leg.Duration * float64(time.Second))
+	}
+	for i, c := range r.Geometry.Coordinates {
+		path.Geometry[i] = domain.LatLng{Lat: c[1], Lng: c[0]}
+	}
+	return path, nil
+}
@@ -0,0 +1,76 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+func TestOSRM_Route(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte(`{"code":"Ok","routes":[{"distance":102500,"duration":4500,"legs":[{"duration":4500}],"geometry":{"type":"LineString","coordinates":[[25.2797,54.6872],[24.5,54.8],[23.9036,54.8985]]}}]}`))
+	}))
+	defer srv.Close()
+
+	o := NewOSRM(srv.URL+"/", "driving", srv.Client())
+	path, err := o.Route(context.Background(), []domain.LatLng{{Lat: 54.6872, Lng: 25.2797}, {Lat: 54.8985, Lng: 23.9036}})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if want := "/route/v1/driving/25.2797,54.6872;23.9036,54.8985"; gotPath != want {
+		t.Errorf("request path = %q, want %q", gotPath, want)
+	}
+	if path.DistanceKm != 102.5 {
+		t.Errorf("DistanceKm = %v, want 102.5", path.DistanceKm)
+	}
+	if path.Duration != 75*time.Minute {
+		t.Errorf("Duration = %v, want 75m", path.Duration)
+	}
+	if len(path.Legs) != 1 || path.Legs[0] != 75*time.Minute {
+		t.Errorf("Legs = %v, want [75m]", path.Legs)
+	}
+	if len(path.Geometry) != 3 || path.Geometry[1] != (domain.LatLng{Lat: 54.8, Lng: 24.5}) {
+		t.Errorf("Geometry = %v", path.Geometry)
+	}
+}
+
+func TestOSRM_RouteNoRoute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"NoRoute","message":"Impossible route between points"}`))
+	}))
+	defer srv.Close()
+
+	o := NewOSRM(srv.URL, "driving", srv.Client())
+	if _, err := o.Route(context.Background(), []domain.LatLng{{}, {Lat: 1}}); err == nil {
+		t.Error("Route() error = nil, want NoRoute error")
+	}
+}
+
+type failingRouter struct{}
+
+func (failingRouter) Route(context.Context, []domain.LatLng) (*domain.RoutePath, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestFallback_Route(t *testing.T) {
+	f := NewFallback(failingRouter{}, NewHaversine(60))
+	path, err := f.Route(context.Background(), []domain.LatLng{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if math.Abs(path.DistanceKm-111.2) > 0.5 {
+		t.Errorf("DistanceKm = %v, want ~111.2", path.DistanceKm)
+	}
+	if d := path.Duration.Minutes(); math.Abs(d-111.2) > 0.5 {
+		t.Errorf("Duration = %v min, want ~111.2 at 60 km/h", d)
+	}
+}
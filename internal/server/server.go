@@ -3,27 +3,40 @@ package server
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/jmartynas/pss-backend/internal/config"
+	"github.com/jmartynas/pss-backend/internal/domain"
 	"github.com/jmartynas/pss-backend/internal/handler"
 	"github.com/jmartynas/pss-backend/internal/hub"
 	"github.com/jmartynas/pss-backend/internal/middleware"
 	"github.com/jmartynas/pss-backend/internal/repository"
+	"github.com/jmartynas/pss-backend/internal/routing"
 	"github.com/jmartynas/pss-backend/internal/service"
 	"github.com/nats-io/nats.go"
 )
 
-const oauthUserInfoTimeout = 10 * time.Second
+const (
+	oauthUserInfoTimeout = 10 * time.Second
+	routeEventTimeout    = 30 * time.Second
+)
 
 type Server struct {
 	httpServer *http.Server
 	log        *slog.Logger
 	tlsCert    string
 	tlsKey     string
+
+	schedules        *service.ScheduleService
+	scheduleInterval time.Duration
+	expiry           *service.ExpirySweeper
+	expiryInterval   time.Duration
+	jobsCtx          context.Context
+	stopJobs         context.CancelFunc
 }
 
 func New(cfg *config.Config, log *slog.Logger, db *sql.DB, nc *nats.Conn) *Server {
@@ -35,20 +48,53 @@ func New(cfg *config.Config, log *slog.Logger, db *sql.DB, nc *nats.Conn) *Serve
 	reviewRepo := repository.NewReviewRepository(db)
 	vehicleRepo := repository.NewVehicleRepository(db)
 	chatRepo := repository.NewChatRepository(db)
+	savedSearchRepo := repository.NewSavedSearchRepository(db, nc)
+	scheduleRepo := repository.NewScheduleRepository(db)
 	chatHub := hub.New()
 
+	// Routing engine (optional): OSRM with a straight-line fallback.
+	var router domain.Router
+	if cfg.Routing.OSRMURL != "" {
+		router = routing.NewFallback(
+			routing.NewOSRM(cfg.Routing.OSRMURL, cfg.Routing.OSRMProfile, &http.Client{Timeout: time.Duration(cfg.Routing.TimeoutSec) * time.Second}),
+			routing.NewHaversine(float64(cfg.Routing.AverageSpeedKmh)),
+		)
+	}
+
 	// Services
-	routeSvc := service.NewRouteService(routeRepo, reviewRepo)
-	appSvc := service.NewApplicationService(appRepo, routeRepo)
+	etas := service.NewETAEstimator(routeRepo, router, float64(cfg.Routing.AverageSpeedKmh), log)
+	fares := service.NewFareCalculator(routeRepo, router, log)
+	routeSvc := service.NewRouteService(routeRepo, reviewRepo, vehicleRepo, chatRepo, router, service.RankWeights{
+		Deviation: cfg.Ranking.WeightDeviation,
+		Departure: cfg.Ranking.WeightDeparture,
+		Price:     cfg.Ranking.WeightPrice,
+		Rating:    cfg.Ranking.WeightRating,
+		Seats:     cfg.Ranking.WeightSeats,
+	}, etas, fares)
+	journeySvc := service.NewJourneyService(routeRepo, reviewRepo, router, float64(cfg.Routing.AverageSpeedKmh))
+	appSvc := service.NewApplicationService(appRepo, routeRepo, reviewRepo, router, etas, fares)
 	userSvc := service.NewUserService(userRepo, reviewRepo)
+	calendarSvc := service.NewCalendarService(userRepo, routeRepo)
+	savedSearchSvc := service.NewSavedSearchService(savedSearchRepo, routeRepo, router)
+	scheduleSvc := service.NewScheduleService(scheduleRepo, routeRepo, vehicleRepo, time.Duration(cfg.Schedule.HorizonDays)*24*time.Hour, etas)
+	expirySweeper := service.NewExpirySweeper(appRepo,
+		time.Duration(cfg.Expiry.BeforeDepartureHours)*time.Hour,
+		time.Duration(cfg.Expiry.AfterSubmitHours)*time.Hour,
+	)
+
+	subscribeRouteEvents(nc, savedSearchSvc, log)
 
 	// Handlers
 	routeH := handler.NewRouteHandler(routeSvc, log)
+	journeyH := handler.NewJourneyHandler(journeySvc, log)
 	appH := handler.NewApplicationHandler(appSvc, log)
 	secure := cfg.Server.TLSCertFile != "" && cfg.Server.TLSKeyFile != ""
 	userH := handler.NewUserHandler(userSvc, sessionRepo, secure, log)
+	calendarH := handler.NewCalendarHandler(calendarSvc, log)
 	vehicleH := handler.NewVehicleHandler(vehicleRepo, log)
 	chatH := handler.NewChatHandler(chatRepo, chatHub, log)
+	savedSearchH := handler.NewSavedSearchHandler(savedSearchSvc, log)
+	scheduleH := handler.NewScheduleHandler(scheduleSvc, log)
 
 	mux := http.NewServeMux()
 
@@ -58,7 +104,9 @@ func New(cfg *config.Config, log *slog.Logger, db *sql.DB, nc *nats.Conn) *Serve
 	// Public routes
 	mux.HandleFunc("GET /routes/{id}", routeH.GetRoute)
 	mux.HandleFunc("POST /routes/search", routeH.SearchRoutes)
+	mux.HandleFunc("POST /journeys/search", journeyH.Search)
 	mux.HandleFunc("GET /users/{id}", userH.GetUser)
+	mux.HandleFunc("GET /calendar/{token}", calendarH.Feed)
 
 	if cfg.OAuth.BaseURL != "" && cfg.OAuth.JWTSecret != "" && len(cfg.OAuth.Providers) > 0 {
 		auth := func(h http.Handler) http.Handler {
@@ -71,11 +119,23 @@ func New(cfg *config.Config, log *slog.Logger, db *sql.DB, nc *nats.Conn) *Serve
 		mux.Handle("DELETE /routes/{id}", auth(http.HandlerFunc(routeH.DeleteRoute)))
 		mux.Handle("GET /routes/my", auth(http.HandlerFunc(routeH.GetMyRoutes)))
 		mux.Handle("GET /routes/participated", auth(http.HandlerFunc(routeH.GetMyParticipatedRoutes)))
+		mux.Handle("GET /routes/{id}/export", auth(http.HandlerFunc(routeH.ExportRoute)))
 
 		// Reviews
 		mux.Handle("GET /routes/{id}/reviews/my", auth(http.HandlerFunc(routeH.GetMyReviews)))
 		mux.Handle("POST /routes/{id}/reviews", auth(http.HandlerFunc(routeH.CreateReview)))
 
+		// Recurring routes
+		mux.Handle("GET /schedules/my", auth(http.HandlerFunc(scheduleH.ListMy)))
+		mux.Handle("POST /schedules", auth(http.HandlerFunc(scheduleH.Create)))
+		mux.Handle("PATCH /schedules/{id}", auth(http.HandlerFunc(scheduleH.Update)))
+		mux.Handle("DELETE /schedules/{id}", auth(http.HandlerFunc(scheduleH.Delete)))
+
+		// Saved searches
+		mux.Handle("GET /saved-searches/my", auth(http.HandlerFunc(savedSearchH.ListMy)))
+		mux.Handle("POST /saved-searches", auth(http.HandlerFunc(savedSearchH.Create)))
+		mux.Handle("DELETE /saved-searches/{id}", auth(http.HandlerFunc(savedSearchH.Delete)))
+
 		// Chats
 		mux.Handle("GET /chats/private", auth(http.HandlerFunc(chatH.ListPrivateChats)))
 		mux.Handle("GET /chats/group", auth(http.HandlerFunc(chatH.ListGroupChats)))
@@ -88,20 +148,25 @@ func New(cfg *config.Config, log *slog.Logger, db *sql.DB, nc *nats.Conn) *Serve
 
 		// Application management
 		mux.Handle("POST /routes/{id}/applications", auth(http.HandlerFunc(appH.Apply)))
+		mux.Handle("POST /routes/{id}/applications/preview", auth(http.HandlerFunc(appH.Preview)))
 		mux.Handle("GET /routes/{id}/applications", auth(http.HandlerFunc(appH.ListByRoute)))
 		mux.Handle("GET /routes/{id}/applications/my", auth(http.HandlerFunc(appH.GetMyForRoute)))
 		mux.Handle("PATCH /routes/{id}/applications/{appId}", auth(http.HandlerFunc(appH.ReviewApplication)))
+		mux.Handle("GET /routes/{id}/applications/{appId}/history", auth(http.HandlerFunc(appH.History)))
 		mux.Handle("PATCH /routes/{id}/applications/{appId}/stops", auth(http.HandlerFunc(appH.UpdateMyStops)))
 		mux.Handle("POST /routes/{id}/applications/{appId}/stop-change", auth(http.HandlerFunc(appH.RequestStopChange)))
 		mux.Handle("PATCH /routes/{id}/applications/{appId}/stop-change", auth(http.HandlerFunc(appH.ReviewStopChange)))
 		mux.Handle("DELETE /routes/{id}/applications/{appId}/stop-change", auth(http.HandlerFunc(appH.CancelStopChange)))
 		mux.Handle("DELETE /routes/{id}/applications/{appId}", auth(http.HandlerFunc(appH.Cancel)))
+		mux.Handle("POST /routes/{id}/applications/{appId}/leave", auth(http.HandlerFunc(appH.Leave)))
+		mux.Handle("POST /routes/{id}/participants/{userId}/remove", auth(http.HandlerFunc(appH.RemoveParticipant)))
 		mux.Handle("GET /applications/my", auth(http.HandlerFunc(appH.GetMyApplications)))
 
 		// User profile
 		mux.Handle("GET /users/me", auth(http.HandlerFunc(userH.GetMe)))
 		mux.Handle("PATCH /users/me", auth(http.HandlerFunc(userH.UpdateMe)))
 		mux.Handle("POST /users/me/disable", auth(http.HandlerFunc(userH.DisableMe)))
+		mux.Handle("POST /users/me/calendar-token", auth(http.HandlerFunc(calendarH.RotateToken)))
 
 		// Vehicles
 		mux.Handle("GET /vehicles/my", auth(http.HandlerFunc(vehicleH.ListMy)))
@@ -148,15 +213,90 @@ func New(cfg *config.Config, log *slog.Logger, db *sql.DB, nc *nats.Conn) *Serve
 		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout) * time.Second,
 	}
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
 	return &Server{
 		httpServer: srv,
 		log:        log,
 		tlsCert:    cfg.Server.TLSCertFile,
 		tlsKey:     cfg.Server.TLSKeyFile,
+
+		schedules:        scheduleSvc,
+		scheduleInterval: time.Duration(cfg.Schedule.IntervalMin) * time.Minute,
+		expiry:           expirySweeper,
+		expiryInterval:   time.Duration(cfg.Expiry.IntervalMin) * time.Minute,
+		jobsCtx:          jobsCtx,
+		stopJobs:         stopJobs,
+	}
+}
+
+// subscribeRouteEvents runs the saved search matcher for every route event.
+// The queue group hands each event to a single server instance.
+func subscribeRouteEvents(nc *nats.Conn, searches *service.SavedSearchService, log *slog.Logger) {
+	if nc == nil {
+		return
+	}
+	_, err := nc.QueueSubscribe("route", "saved-search-matcher", func(m *nats.Msg) {
+		var ev domain.RouteEvent
+		if err := json.Unmarshal(m.Data, &ev); err != nil {
+			log.Warn("invalid route event", slog.Any("error", err))
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), routeEventTimeout)
+		defer cancel()
+		if err := searches.MatchRoute(ctx, ev.RouteID); err != nil {
+			log.Error("match saved searches", slog.String("route_id", ev.RouteID.String()), slog.Any("error", err))
+		}
+	})
+	if err != nil {
+		log.Error("nats subscribe failed", slog.String("subject", "route"), slog.Any("error", err))
+	}
+}
+
+// runScheduleJob materialises recurring routes now and then every
+// scheduleInterval until the server shuts down.
+func (s *Server) runScheduleJob(ctx context.Context) {
+	ticker := time.NewTicker(s.scheduleInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.schedules.Materialize(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			s.log.Error("materialize schedules", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runExpiryJob rejects unanswered applications and stop changes now and then
+// every expiryInterval until the server shuts down.
+func (s *Server) runExpiryJob(ctx context.Context) {
+	ticker := time.NewTicker(s.expiryInterval)
+	defer ticker.Stop()
+	for {
+		n, err := s.expiry.Sweep(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			s.log.Error("expire applications", slog.Any("error", err))
+		}
+		if n > 0 {
+			s.log.Info("expired applications", slog.Int("count", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
 func (s *Server) Start() error {
+	if s.scheduleInterval > 0 {
+		go s.runScheduleJob(s.jobsCtx)
+	}
+	if s.expiryInterval > 0 {
+		go s.runExpiryJob(s.jobsCtx)
+	}
 	if s.tlsCert != "" && s.tlsKey != "" {
 		s.log.Info("server starting (HTTPS)", slog.String("addr", s.httpServer.Addr))
 		return s.httpServer.ListenAndServeTLS(s.tlsCert, s.tlsKey)
@@ -167,5 +307,6 @@ func (s *Server) Start() error {
 
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.log.Info("server shutting down")
+	s.stopJobs()
 	return s.httpServer.Shutdown(ctx)
 }
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/jmartynas/pss-backend/internal/domain"
@@ -16,13 +20,21 @@ func routeStarted(r *domain.Route) bool {
 
 // ApplicationService contains all application business logic.
 type ApplicationService struct {
-	apps   domain.ApplicationRepository
-	routes domain.RouteRepository
+	apps    domain.ApplicationRepository
+	routes  domain.RouteRepository
+	reviews domain.ReviewRepository
+	router  domain.Router
+	etas    *ETAEstimator
+	fares   *FareCalculator
 }
 
 // NewApplicationService creates an ApplicationService backed by the given repositories.
-func NewApplicationService(apps domain.ApplicationRepository, routes domain.RouteRepository) *ApplicationService {
-	return &ApplicationService{apps: apps, routes: routes}
+// reviews rate applicants to routes that auto-approve only above a minimum rating.
+// router is optional; without it detours are measured in straight lines.
+// etas and fares, when non-nil, re-time a route's stops and re-split its cost
+// whenever approval rewrites them.
+func NewApplicationService(apps domain.ApplicationRepository, routes domain.RouteRepository, reviews domain.ReviewRepository, router domain.Router, etas *ETAEstimator, fares *FareCalculator) *ApplicationService {
+	return &ApplicationService{apps: apps, routes: routes, reviews: reviews, router: router, etas: etas, fares: fares}
 }
 
 func (s *ApplicationService) GetByID(ctx context.Context, id uuid.UUID) (*domain.Application, error) {
@@ -52,32 +64,136 @@ func (s *ApplicationService) ListByUser(ctx context.Context, userID uuid.UUID) (
 	return s.apps.ListByUser(ctx, userID)
 }
 
-// Apply validates business rules then persists a new application.
-func (s *ApplicationService) Apply(ctx context.Context, userID, routeID uuid.UUID, in domain.ApplyInput) (uuid.UUID, error) {
+// Apply validates business rules then persists a new application and returns it.
+// On routes that auto-approve, an eligible applicant is approved straight away.
+func (s *ApplicationService) Apply(ctx context.Context, userID, routeID uuid.UUID, in domain.ApplyInput) (*domain.Application, error) {
 	route, err := s.routes.GetByID(ctx, routeID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("apply: load route: %w", err)
+		return nil, fmt.Errorf("apply: load route: %w", err)
 	}
 
 	if routeStarted(route) {
-		return uuid.Nil, errs.ErrRouteStarted
+		return nil, errs.ErrRouteStarted
 	}
 	if route.CreatorID == userID {
-		return uuid.Nil, errs.ErrForbidden
-	}
-	if route.AvailablePassengers == 0 {
-		return uuid.Nil, errs.ErrRouteFull
+		return nil, errs.ErrForbidden
 	}
 
 	existing, err := s.apps.GetByUserAndRoute(ctx, userID, routeID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("apply: check existing: %w", err)
+		return nil, fmt.Errorf("apply: check existing: %w", err)
 	}
 	if existing != nil {
-		return uuid.Nil, errs.ErrAlreadyApplied
+		return nil, errs.ErrAlreadyApplied
+	}
+
+	if in.Seats == 0 {
+		in.Seats = 1
+	}
+	if in.Seats > route.MaxPassengers || (route.LuggageCapacity != nil && in.Luggage > *route.LuggageCapacity) {
+		return nil, errs.ErrExceedsRoute
+	}
+	if in.Stops, err = placeStops(route, in.Stops, ""); err != nil {
+		return nil, err
+	}
+	status := "pending"
+	if !bookingFits(route, in.Seats, in.Luggage) || route.Waitlisted > 0 {
+		// Full routes take applications onto a waitlist, which keeps its order
+		// even after a seat frees up.
+		status = "waitlisted"
+	}
+
+	var id uuid.UUID
+	if status == "pending" && route.AutoApprove {
+		detour, ok, err := s.autoApproval(ctx, route, userID, in.Stops)
+		if err != nil {
+			return nil, fmt.Errorf("apply: %w", err)
+		}
+		if ok {
+			id, err = s.apps.CreateApproved(ctx, userID, routeID, in, detour)
+			switch {
+			case err == nil:
+				s.etas.Changed(ctx, routeID)
+				s.fares.Changed(ctx, routeID)
+				return s.apps.GetByID(ctx, id)
+			case errors.Is(err, errs.ErrRouteFull):
+				// Another approval took the seat since the route was loaded.
+				status = "waitlisted"
+			case errors.Is(err, errs.ErrConflict), errors.Is(err, errs.ErrDetourBudget):
+				// The route's stops or detours changed since it was loaded; the
+				// driver decides, with the stops placed again on approval.
+			default:
+				return nil, err
+			}
+		}
+	}
+	if id, err = s.apps.Create(ctx, userID, routeID, status, in); err != nil {
+		return nil, err
+	}
+	return s.apps.GetByID(ctx, id)
+}
+
+// autoApproval reports whether route approves userID's application with the
+// given stops on submit, and the detour the stops add. Applicants below the
+// route's minimum rating or completed rides, or whose stops would exceed the
+// deviation budget, wait for the driver as usual.
+func (s *ApplicationService) autoApproval(ctx context.Context, route *domain.Route, userID uuid.UUID, stops []domain.ApplicationStopInput) (float64, bool, error) {
+	if route.AutoApproveMinRating != nil {
+		ratings, err := s.reviews.GetAverageRatings(ctx, []uuid.UUID{userID})
+		if err != nil {
+			return 0, false, fmt.Errorf("load rating: %w", err)
+		}
+		if r, ok := ratings[userID]; !ok || r.Avg < *route.AutoApproveMinRating {
+			return 0, false, nil
+		}
 	}
+	if route.AutoApproveMinRides != nil {
+		n, err := s.apps.CountCompletedRides(ctx, userID)
+		if err != nil {
+			return 0, false, fmt.Errorf("count completed rides: %w", err)
+		}
+		if n < int(*route.AutoApproveMinRides) {
+			return 0, false, nil
+		}
+	}
+	detour := math.Max(0, s.stopsDetour(ctx, route, stops))
+	return detour, detour <= route.RemainingDeviation, nil
+}
 
-	return s.apps.Create(ctx, userID, routeID, in)
+// bookingFits reports whether seats and luggage fit in what route's approved
+// bookings leave free. Luggage is only limited on routes that declare a
+// capacity.
+func bookingFits(route *domain.Route, seats, luggage uint) bool {
+	return seats <= route.AvailablePassengers && (route.AvailableLuggage == nil || luggage <= *route.AvailableLuggage)
+}
+
+// Preview returns where the passenger's pickup and dropoff add the least
+// distance to the route, and the full stop order to apply with.
+func (s *ApplicationService) Preview(ctx context.Context, routeID uuid.UUID, in domain.ApplicationPreviewInput) (*domain.ApplicationPreview, error) {
+	route, err := s.routes.GetByID(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("preview: load route: %w", err)
+	}
+	var own []domain.ApplicationStopInput
+	for _, st := range []*domain.ApplicationStopInput{in.Pickup, in.Dropoff} {
+		if st != nil {
+			own = append(own, *st)
+		}
+	}
+	if len(own) == 0 {
+		return nil, fmt.Errorf("%w: pickup or dropoff is required", errs.ErrInvalidStops)
+	}
+
+	stops, positions := insertStops(route, own, "")
+	detour := math.Max(0, s.stopsDetour(ctx, route, stops))
+	p := &domain.ApplicationPreview{Stops: stops, Detour: detour, WithinBudget: detour <= route.RemainingDeviation}
+	if in.Pickup != nil {
+		p.PickupPosition = &positions[0]
+	}
+	if in.Dropoff != nil {
+		p.DropoffPosition = &positions[len(positions)-1]
+	}
+	return p, nil
 }
 
 // Review approves or rejects an application. Only the route creator may call this.
@@ -100,8 +216,31 @@ func (s *ApplicationService) Review(ctx context.Context, appID uuid.UUID, status
 	if app.Status != "pending" {
 		return errs.ErrConflict
 	}
+	var stops []domain.ApplicationStopInput
+	var detour float64
+	if status == "approved" {
+		if !bookingFits(route, app.Seats, app.Luggage) {
+			return errs.ErrRouteFull
+		}
+		// Approvals and departures since the applicant ordered their stops
+		// change the route's.
+		if stops, err = currentStops(route, app.Stops, appID.String()); err != nil {
+			return err
+		}
+		detour = math.Max(0, s.stopsDetour(ctx, route, stops))
+		if detour > route.RemainingDeviation {
+			return errs.ErrDetourBudget
+		}
+	}
 
-	return s.apps.ReviewUpdate(ctx, appID, status, app.UserID, app.RouteID)
+	if err := s.apps.ReviewUpdate(ctx, appID, status, app.UserID, app.RouteID, stops, detour); err != nil {
+		return err
+	}
+	if status == "approved" {
+		s.etas.Changed(ctx, app.RouteID)
+		s.fares.Changed(ctx, app.RouteID)
+	}
+	return nil
 }
 
 // GetMyForRoute returns the caller's own application for a route, or nil if none.
@@ -128,6 +267,9 @@ func (s *ApplicationService) UpdateStops(ctx context.Context, appID, callerID uu
 	if routeStarted(route) {
 		return errs.ErrRouteStarted
 	}
+	if stops, err = placeStops(route, stops, appID.String()); err != nil {
+		return err
+	}
 	return s.apps.UpdateStops(ctx, appID, stops, comment)
 }
 
@@ -150,6 +292,9 @@ func (s *ApplicationService) RequestStopChange(ctx context.Context, appID, calle
 	if routeStarted(route) {
 		return errs.ErrRouteStarted
 	}
+	if stops, err = placeStops(route, stops, appID.String()); err != nil {
+		return err
+	}
 	return s.apps.RequestStopChange(ctx, appID, stops, comment)
 }
 
@@ -172,11 +317,30 @@ func (s *ApplicationService) ReviewStopChange(ctx context.Context, appID, caller
 	if !app.PendingStopChange {
 		return errs.ErrConflict
 	}
-	return s.apps.ReviewStopChange(ctx, appID, app.RouteID, approve)
+	var stops []domain.ApplicationStopInput
+	var detour float64
+	if approve {
+		if stops, err = currentStops(route, app.Stops, appID.String()); err != nil {
+			return err
+		}
+		// Dropping stops gives back budget, so the change may be negative.
+		detour = s.stopsDetour(ctx, route, stops)
+		if detour > route.RemainingDeviation {
+			return errs.ErrDetourBudget
+		}
+	}
+	if err := s.apps.ReviewStopChange(ctx, appID, app.RouteID, approve, stops, detour); err != nil {
+		return err
+	}
+	if approve {
+		s.etas.Changed(ctx, app.RouteID)
+		s.fares.Changed(ctx, app.RouteID)
+	}
+	return nil
 }
 
-// Cancel withdraws a pending application. Only the applicant may call this,
-// and only while the application has not yet been accepted or rejected.
+// Cancel withdraws a pending or waitlisted application. Only the applicant may
+// call this, and only while the application has not yet been accepted or rejected.
 func (s *ApplicationService) Cancel(ctx context.Context, appID, callerID uuid.UUID) error {
 	app, err := s.apps.GetByID(ctx, appID)
 	if err != nil {
@@ -185,7 +349,7 @@ func (s *ApplicationService) Cancel(ctx context.Context, appID, callerID uuid.UU
 	if app.UserID != callerID {
 		return errs.ErrForbidden
 	}
-	if app.Status != "pending" {
+	if app.Status != "pending" && app.Status != "waitlisted" {
 		return errs.ErrConflict
 	}
 	route, err := s.routes.GetByID(ctx, app.RouteID)
@@ -219,3 +383,114 @@ func (s *ApplicationService) CancelStopChange(ctx context.Context, appID, caller
 	}
 	return s.apps.CancelStopChange(ctx, appID)
 }
+
+// History returns the recorded transitions of an application on routeID,
+// oldest first. Only the applicant and the route's driver may read it.
+func (s *ApplicationService) History(ctx context.Context, routeID, appID, callerID uuid.UUID) ([]domain.ApplicationEvent, error) {
+	events, err := s.apps.ListEvents(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("history: load events: %w", err)
+	}
+	if len(events) == 0 || events[0].RouteID != routeID {
+		return nil, errs.ErrNotFound
+	}
+	if events[0].UserID == callerID {
+		return events, nil
+	}
+	route, err := s.routes.GetByID(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("history: load route: %w", err)
+	}
+	if route.CreatorID != callerID {
+		return nil, errs.ErrForbidden
+	}
+	return events, nil
+}
+
+// maxLeaveReason is the longest reason accepted when leaving or removing.
+const maxLeaveReason = 500
+
+// Leave takes the caller, an approved passenger, off the route before it
+// departs. reason is passed on to the driver.
+func (s *ApplicationService) Leave(ctx context.Context, appID, callerID uuid.UUID, reason string) error {
+	reason, err := leaveReason(reason)
+	if err != nil {
+		return err
+	}
+	app, err := s.apps.GetByID(ctx, appID)
+	if err != nil {
+		return fmt.Errorf("leave: load application: %w", err)
+	}
+	if app.UserID != callerID {
+		return errs.ErrForbidden
+	}
+	return s.takeOff(ctx, app, "left", reason)
+}
+
+// Remove lets the route creator take an approved passenger off the route
+// before it departs. reason is passed on to the passenger.
+func (s *ApplicationService) Remove(ctx context.Context, routeID, userID, callerID uuid.UUID, reason string) error {
+	reason, err := leaveReason(reason)
+	if err != nil {
+		return err
+	}
+	route, err := s.routes.GetByID(ctx, routeID)
+	if err != nil {
+		return fmt.Errorf("remove: load route: %w", err)
+	}
+	if route.CreatorID != callerID {
+		return errs.ErrForbidden
+	}
+	app, err := s.apps.GetByUserAndRoute(ctx, userID, routeID)
+	if err != nil {
+		return fmt.Errorf("remove: load application: %w", err)
+	}
+	if app == nil {
+		return errs.ErrNotParticipant
+	}
+	return s.takeOff(ctx, app, "removed", reason)
+}
+
+// takeOff checks an approved application may still leave its route, records
+// it with status and re-times and re-prices the stops that remain.
+func (s *ApplicationService) takeOff(ctx context.Context, app *domain.Application, status, reason string) error {
+	if app.Status != "approved" {
+		return errs.ErrConflict
+	}
+	route, err := s.routes.GetByID(ctx, app.RouteID)
+	if err != nil {
+		return fmt.Errorf("leave: load route: %w", err)
+	}
+	if routeStarted(route) {
+		return errs.ErrRouteStarted
+	}
+	if err := s.apps.Leave(ctx, app.ID, status, reason); err != nil {
+		return err
+	}
+	s.etas.Changed(ctx, app.RouteID)
+	s.fares.Changed(ctx, app.RouteID)
+	return nil
+}
+
+// leaveReason trims reason and checks it is present and not too long.
+func leaveReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || utf8.RuneCountInString(reason) > maxLeaveReason {
+		return "", errs.ErrInvalidReason
+	}
+	return reason, nil
+}
+
+// stopsDetour returns the extra distance (km) the driver covers when the
+// route's stops are replaced with the proposed order. Both paths are measured
+// the same way so a router failure cannot turn into a phantom detour.
+func (s *ApplicationService) stopsDetour(ctx context.Context, route *domain.Route, proposed []domain.ApplicationStopInput) float64 {
+	points := make([]domain.LatLng, 0, len(proposed)+2)
+	points = append(points, domain.LatLng{Lat: route.StartLat, Lng: route.StartLng})
+	for _, st := range proposed {
+		points = append(points, domain.LatLng{Lat: st.Lat, Lng: st.Lng})
+	}
+	points = append(points, domain.LatLng{Lat: route.EndLat, Lng: route.EndLng})
+	kms := pathsKm(ctx, s.router, points, routePoints(route))
+	return kms[0] - kms[1]
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,7 +21,9 @@ type mockRouteRepo struct {
 	delete         func(ctx context.Context, id, creatorID uuid.UUID) error
 	listByCreator  func(ctx context.Context, creatorID uuid.UUID, filter domain.RouteFilter) ([]domain.Route, error)
 	listByParticipant func(ctx context.Context, userID uuid.UUID, filter domain.RouteFilter) ([]domain.Route, error)
-	listSearchable func(ctx context.Context) ([]domain.Route, error)
+	listSearchable func(ctx context.Context, in domain.SearchRouteInput) ([]domain.Route, error)
+	setETAs        func(ctx context.Context, routeID uuid.UUID, etas domain.RouteETAs) error
+	setPriceShares func(ctx context.Context, routeID uuid.UUID, shares map[uuid.UUID]float64) error
 }
 
 func (m *mockRouteRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Route, error) {
@@ -41,26 +44,44 @@ func (m *mockRouteRepo) ListByCreator(ctx context.Context, creatorID uuid.UUID,
 func (m *mockRouteRepo) ListByParticipant(ctx context.Context, userID uuid.UUID, filter domain.RouteFilter) ([]domain.Route, error) {
 	return m.listByParticipant(ctx, userID, filter)
 }
-func (m *mockRouteRepo) ListSearchable(ctx context.Context) ([]domain.Route, error) {
-	return m.listSearchable(ctx)
+func (m *mockRouteRepo) ListSearchable(ctx context.Context, in domain.SearchRouteInput) ([]domain.Route, error) {
+	return m.listSearchable(ctx, in)
+}
+func (m *mockRouteRepo) SetETAs(ctx context.Context, routeID uuid.UUID, etas domain.RouteETAs) error {
+	return m.setETAs(ctx, routeID, etas)
+}
+func (m *mockRouteRepo) SetPriceShares(ctx context.Context, routeID uuid.UUID, shares map[uuid.UUID]float64) error {
+	return m.setPriceShares(ctx, routeID, shares)
 }
 
 type mockAppRepo struct {
-	create              func(ctx context.Context, userID, routeID uuid.UUID, in domain.ApplyInput) (uuid.UUID, error)
+	create              func(ctx context.Context, userID, routeID uuid.UUID, status string, in domain.ApplyInput) (uuid.UUID, error)
+	createApproved      func(ctx context.Context, userID, routeID uuid.UUID, in domain.ApplyInput, detourKm float64) (uuid.UUID, error)
+	countCompletedRides func(ctx context.Context, userID uuid.UUID) (int, error)
 	getByID             func(ctx context.Context, id uuid.UUID) (*domain.Application, error)
 	getByUserAndRoute   func(ctx context.Context, userID, routeID uuid.UUID) (*domain.Application, error)
 	listByRoute         func(ctx context.Context, routeID uuid.UUID) ([]domain.Application, error)
 	listByUser          func(ctx context.Context, userID uuid.UUID) ([]domain.Application, error)
-	reviewUpdate        func(ctx context.Context, id uuid.UUID, status string, appUserID, routeID uuid.UUID) error
+	reviewUpdate        func(ctx context.Context, id uuid.UUID, status string, appUserID, routeID uuid.UUID, stops []domain.ApplicationStopInput, detourKm float64) error
 	updateStops         func(ctx context.Context, id uuid.UUID, stops []domain.ApplicationStopInput, comment *string) error
 	requestStopChange   func(ctx context.Context, id uuid.UUID, stops []domain.ApplicationStopInput, comment *string) error
-	reviewStopChange    func(ctx context.Context, id uuid.UUID, routeID uuid.UUID, approve bool) error
+	reviewStopChange    func(ctx context.Context, id uuid.UUID, routeID uuid.UUID, approve bool, stops []domain.ApplicationStopInput, detourKm float64) error
 	cancelStopChange    func(ctx context.Context, id uuid.UUID) error
 	softDelete          func(ctx context.Context, id uuid.UUID, wasApproved bool) error
+	listStale           func(ctx context.Context, leavingBefore time.Time, awaitingBefore *time.Time) ([]domain.StaleApplication, error)
+	expire              func(ctx context.Context, app domain.StaleApplication, reason string) error
+	leave               func(ctx context.Context, id uuid.UUID, status, reason string) error
+	listEvents          func(ctx context.Context, id uuid.UUID) ([]domain.ApplicationEvent, error)
 }
 
-func (m *mockAppRepo) Create(ctx context.Context, userID, routeID uuid.UUID, in domain.ApplyInput) (uuid.UUID, error) {
-	return m.create(ctx, userID, routeID, in)
+func (m *mockAppRepo) Create(ctx context.Context, userID, routeID uuid.UUID, status string, in domain.ApplyInput) (uuid.UUID, error) {
+	return m.create(ctx, userID, routeID, status, in)
+}
+func (m *mockAppRepo) CreateApproved(ctx context.Context, userID, routeID uuid.UUID, in domain.ApplyInput, detourKm float64) (uuid.UUID, error) {
+	return m.createApproved(ctx, userID, routeID, in, detourKm)
+}
+func (m *mockAppRepo) CountCompletedRides(ctx context.Context, userID uuid.UUID) (int, error) {
+	return m.countCompletedRides(ctx, userID)
 }
 func (m *mockAppRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Application, error) {
 	return m.getByID(ctx, id)
@@ -74,8 +95,8 @@ func (m *mockAppRepo) ListByRoute(ctx context.Context, routeID uuid.UUID) ([]dom
 func (m *mockAppRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Application, error) {
 	return m.listByUser(ctx, userID)
 }
-func (m *mockAppRepo) ReviewUpdate(ctx context.Context, id uuid.UUID, status string, appUserID, routeID uuid.UUID) error {
-	return m.reviewUpdate(ctx, id, status, appUserID, routeID)
+func (m *mockAppRepo) ReviewUpdate(ctx context.Context, id uuid.UUID, status string, appUserID, routeID uuid.UUID, stops []domain.ApplicationStopInput, detourKm float64) error {
+	return m.reviewUpdate(ctx, id, status, appUserID, routeID, stops, detourKm)
 }
 func (m *mockAppRepo) UpdateStops(ctx context.Context, id uuid.UUID, stops []domain.ApplicationStopInput, comment *string) error {
 	return m.updateStops(ctx, id, stops, comment)
@@ -83,8 +104,8 @@ func (m *mockAppRepo) UpdateStops(ctx context.Context, id uuid.UUID, stops []dom
 func (m *mockAppRepo) RequestStopChange(ctx context.Context, id uuid.UUID, stops []domain.ApplicationStopInput, comment *string) error {
 	return m.requestStopChange(ctx, id, stops, comment)
 }
-func (m *mockAppRepo) ReviewStopChange(ctx context.Context, id uuid.UUID, routeID uuid.UUID, approve bool) error {
-	return m.reviewStopChange(ctx, id, routeID, approve)
+func (m *mockAppRepo) ReviewStopChange(ctx context.Context, id uuid.UUID, routeID uuid.UUID, approve bool, stops []domain.ApplicationStopInput, detourKm float64) error {
+	return m.reviewStopChange(ctx, id, routeID, approve, stops, detourKm)
 }
 func (m *mockAppRepo) CancelStopChange(ctx context.Context, id uuid.UUID) error {
 	return m.cancelStopChange(ctx, id)
@@ -92,6 +113,18 @@ func (m *mockAppRepo) CancelStopChange(ctx context.Context, id uuid.UUID) error
 func (m *mockAppRepo) SoftDelete(ctx context.Context, id uuid.UUID, wasApproved bool) error {
 	return m.softDelete(ctx, id, wasApproved)
 }
+func (m *mockAppRepo) ListStale(ctx context.Context, leavingBefore time.Time, awaitingBefore *time.Time) ([]domain.StaleApplication, error) {
+	return m.listStale(ctx, leavingBefore, awaitingBefore)
+}
+func (m *mockAppRepo) Expire(ctx context.Context, app domain.StaleApplication, reason string) error {
+	return m.expire(ctx, app, reason)
+}
+func (m *mockAppRepo) Leave(ctx context.Context, id uuid.UUID, status, reason string) error {
+	return m.leave(ctx, id, status, reason)
+}
+func (m *mockAppRepo) ListEvents(ctx context.Context, id uuid.UUID) ([]domain.ApplicationEvent, error) {
+	return m.listEvents(ctx, id)
+}
 
 type mockReviewRepo struct {
 	create            func(ctx context.Context, in domain.CreateReviewInput) (uuid.UUID, error)
@@ -123,6 +156,7 @@ func activeRoute(creatorID uuid.UUID, available uint) *domain.Route {
 		ID:                  uuid.New(),
 		CreatorID:           creatorID,
 		LeavingAt:           futureTime(),
+		MaxPassengers:       4,
 		AvailablePassengers: available,
 		Stops:               []domain.Stop{},
 		Participants:        []domain.Participant{},
@@ -148,24 +182,87 @@ func TestApplicationService_Apply_CreatorForbidden(t *testing.T) {
 
 	svc := NewApplicationService(&mockAppRepo{}, &mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	})
+	}, nil, nil, nil, nil)
 	_, err := svc.Apply(context.Background(), creatorID, route.ID, domain.ApplyInput{})
 	if !errors.Is(err, errs.ErrForbidden) {
 		t.Errorf("Apply(creator) = %v, want ErrForbidden", err)
 	}
 }
 
-func TestApplicationService_Apply_RouteFull(t *testing.T) {
+func TestApplicationService_Apply_Waitlisted(t *testing.T) {
 	creatorID := uuid.New()
-	userID := uuid.New()
-	route := activeRoute(creatorID, 0)
+	full := activeRoute(creatorID, 0)
+	// A freed seat still held for the waitlist does not let newcomers skip it.
+	queued := activeRoute(creatorID, 1)
+	queued.Waitlisted = 1
+
+	for name, route := range map[string]*domain.Route{"full": full, "waitlist": queued} {
+		var status string
+		svc := NewApplicationService(&mockAppRepo{
+			getByUserAndRoute: func(_ context.Context, _, _ uuid.UUID) (*domain.Application, error) { return nil, nil },
+			create: func(_ context.Context, _, _ uuid.UUID, st string, _ domain.ApplyInput) (uuid.UUID, error) {
+				status = st
+				return uuid.New(), nil
+			},
+			getByID: func(_ context.Context, id uuid.UUID) (*domain.Application, error) { return &domain.Application{ID: id}, nil },
+		}, &mockRouteRepo{
+			getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+		}, nil, nil, nil, nil)
+		if _, err := svc.Apply(context.Background(), uuid.New(), route.ID, domain.ApplyInput{}); err != nil {
+			t.Fatalf("Apply(%s) error = %v", name, err)
+		}
+		if status != "waitlisted" {
+			t.Errorf("Apply(%s) created a %q application, want waitlisted", name, status)
+		}
+	}
+}
 
-	svc := NewApplicationService(&mockAppRepo{}, &mockRouteRepo{
-		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	})
-	_, err := svc.Apply(context.Background(), userID, route.ID, domain.ApplyInput{})
-	if !errors.Is(err, errs.ErrRouteFull) {
-		t.Errorf("Apply(full route) = %v, want ErrRouteFull", err)
+func TestApplicationService_Apply_Seats(t *testing.T) {
+	luggage, left := uint(3), uint(1)
+	tests := []struct {
+		name       string
+		in         domain.ApplyInput
+		wantStatus string
+		wantErr    error
+	}{
+		{"default one seat", domain.ApplyInput{}, "pending", nil},
+		{"seats free", domain.ApplyInput{Seats: 2, Luggage: 1}, "pending", nil},
+		{"more seats than free", domain.ApplyInput{Seats: 3}, "waitlisted", nil},
+		{"more luggage than free", domain.ApplyInput{Luggage: 2}, "waitlisted", nil},
+		{"more seats than the car", domain.ApplyInput{Seats: 5}, "", errs.ErrExceedsRoute},
+		{"more luggage than the car", domain.ApplyInput{Luggage: 4}, "", errs.ErrExceedsRoute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := activeRoute(uuid.New(), 2)
+			route.LuggageCapacity, route.AvailableLuggage = &luggage, &left
+			var created domain.ApplyInput
+			var status string
+			svc := NewApplicationService(&mockAppRepo{
+				getByUserAndRoute: func(_ context.Context, _, _ uuid.UUID) (*domain.Application, error) { return nil, nil },
+				create: func(_ context.Context, _, _ uuid.UUID, st string, in domain.ApplyInput) (uuid.UUID, error) {
+					created, status = in, st
+					return uuid.New(), nil
+				},
+				getByID: func(_ context.Context, id uuid.UUID) (*domain.Application, error) { return &domain.Application{ID: id}, nil },
+			}, &mockRouteRepo{
+				getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+			}, nil, nil, nil, nil)
+
+			_, err := svc.Apply(context.Background(), uuid.New(), route.ID, tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if status != tt.wantStatus {
+				t.Errorf("Apply() status = %q, want %q", status, tt.wantStatus)
+			}
+			if created.Seats == 0 {
+				t.Error("Apply() stored a booking without seats")
+			}
+		})
 	}
 }
 
@@ -179,7 +276,7 @@ func TestApplicationService_Apply_AlreadyApplied(t *testing.T) {
 		getByUserAndRoute: func(_ context.Context, _, _ uuid.UUID) (*domain.Application, error) { return existing, nil },
 	}, &mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	})
+	}, nil, nil, nil, nil)
 	_, err := svc.Apply(context.Background(), userID, route.ID, domain.ApplyInput{})
 	if !errors.Is(err, errs.ErrAlreadyApplied) {
 		t.Errorf("Apply(already applied) = %v, want ErrAlreadyApplied", err)
@@ -193,7 +290,7 @@ func TestApplicationService_Apply_RouteStarted(t *testing.T) {
 
 	svc := NewApplicationService(&mockAppRepo{}, &mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	})
+	}, nil, nil, nil, nil)
 	_, err := svc.Apply(context.Background(), userID, route.ID, domain.ApplyInput{})
 	if !errors.Is(err, errs.ErrRouteStarted) {
 		t.Errorf("Apply(started route) = %v, want ErrRouteStarted", err)
@@ -208,16 +305,183 @@ func TestApplicationService_Apply_Success(t *testing.T) {
 
 	svc := NewApplicationService(&mockAppRepo{
 		getByUserAndRoute: func(_ context.Context, _, _ uuid.UUID) (*domain.Application, error) { return nil, nil },
-		create:            func(_ context.Context, _, _ uuid.UUID, _ domain.ApplyInput) (uuid.UUID, error) { return newID, nil },
+		create: func(_ context.Context, _, _ uuid.UUID, status string, _ domain.ApplyInput) (uuid.UUID, error) {
+			if status != "pending" {
+				t.Errorf("Create() status = %q, want pending", status)
+			}
+			return newID, nil
+		},
+		getByID: func(_ context.Context, id uuid.UUID) (*domain.Application, error) {
+			return &domain.Application{ID: id, Status: "pending"}, nil
+		},
 	}, &mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	})
+	}, nil, nil, nil, nil)
 	got, err := svc.Apply(context.Background(), userID, route.ID, domain.ApplyInput{})
 	if err != nil {
 		t.Fatalf("Apply() error = %v", err)
 	}
-	if got != newID {
-		t.Errorf("Apply() = %v, want %v", got, newID)
+	if got.ID != newID {
+		t.Errorf("Apply() = %v, want %v", got.ID, newID)
+	}
+}
+
+func TestApplicationService_Apply_AutoApprove(t *testing.T) {
+	minRating, minRides := 4.5, uint(3)
+	tests := []struct {
+		name     string
+		rating   *domain.ReviewSummary
+		rides    int
+		approved bool
+	}{
+		{"eligible", &domain.ReviewSummary{Avg: 4.8, Count: 6}, 3, true},
+		{"low rating", &domain.ReviewSummary{Avg: 4.2, Count: 6}, 3, false},
+		{"unrated", nil, 3, false},
+		{"too few rides", &domain.ReviewSummary{Avg: 4.8, Count: 6}, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := activeRoute(uuid.New(), 2)
+			route.AutoApprove = true
+			route.AutoApproveMinRating, route.AutoApproveMinRides = &minRating, &minRides
+			userID := uuid.New()
+			var status string
+			svc := NewApplicationService(&mockAppRepo{
+				getByUserAndRoute:   func(_ context.Context, _, _ uuid.UUID) (*domain.Application, error) { return nil, nil },
+				countCompletedRides: func(_ context.Context, _ uuid.UUID) (int, error) { return tt.rides, nil },
+				create: func(_ context.Context, _, _ uuid.UUID, st string, _ domain.ApplyInput) (uuid.UUID, error) {
+					status = st
+					return uuid.New(), nil
+				},
+				createApproved: func(_ context.Context, _, _ uuid.UUID, _ domain.ApplyInput, _ float64) (uuid.UUID, error) {
+					status = "approved"
+					return uuid.New(), nil
+				},
+				getByID: func(_ context.Context, id uuid.UUID) (*domain.Application, error) {
+					return &domain.Application{ID: id, Status: status}, nil
+				},
+			}, &mockRouteRepo{
+				getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+			}, &mockReviewRepo{
+				getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
+					if tt.rating == nil {
+						return map[uuid.UUID]domain.ReviewSummary{}, nil
+					}
+					return map[uuid.UUID]domain.ReviewSummary{userID: *tt.rating}, nil
+				},
+			}, nil, nil, nil)
+
+			got, err := svc.Apply(context.Background(), userID, route.ID, domain.ApplyInput{})
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if want := map[bool]string{true: "approved", false: "pending"}[tt.approved]; got.Status != want {
+				t.Errorf("Apply() status = %q, want %q", got.Status, want)
+			}
+		})
+	}
+}
+
+func TestApplicationService_Apply_AutoApproveRaceWaitlists(t *testing.T) {
+	route := activeRoute(uuid.New(), 1)
+	route.AutoApprove = true
+	var status string
+	svc := NewApplicationService(&mockAppRepo{
+		getByUserAndRoute: func(_ context.Context, _, _ uuid.UUID) (*domain.Application, error) { return nil, nil },
+		createApproved: func(_ context.Context, _, _ uuid.UUID, _ domain.ApplyInput, _ float64) (uuid.UUID, error) {
+			return uuid.Nil, errs.ErrRouteFull
+		},
+		create: func(_ context.Context, _, _ uuid.UUID, st string, _ domain.ApplyInput) (uuid.UUID, error) {
+			status = st
+			return uuid.New(), nil
+		},
+		getByID: func(_ context.Context, id uuid.UUID) (*domain.Application, error) {
+			return &domain.Application{ID: id, Status: status}, nil
+		},
+	}, &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}, nil, nil, nil, nil)
+
+	got, err := svc.Apply(context.Background(), uuid.New(), route.ID, domain.ApplyInput{})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if got.Status != "waitlisted" {
+		t.Errorf("Apply() status = %q, want waitlisted after the seat was taken", got.Status)
+	}
+}
+
+// lineRoute runs along the equator from lng 0 to 3 with stops at lng 1 and 2.
+func lineRoute(creatorID uuid.UUID) *domain.Route {
+	route := activeRoute(creatorID, 2)
+	route.EndLng = 3
+	route.Stops = []domain.Stop{{ID: uuid.New(), Lng: 1}, {ID: uuid.New(), Position: 1, Lng: 2}}
+	return route
+}
+
+func TestApplicationService_Apply_PlacesStops(t *testing.T) {
+	route := lineRoute(uuid.New())
+	var created domain.ApplyInput
+	svc := NewApplicationService(&mockAppRepo{
+		getByUserAndRoute: func(_ context.Context, _, _ uuid.UUID) (*domain.Application, error) { return nil, nil },
+		create: func(_ context.Context, _, _ uuid.UUID, _ string, in domain.ApplyInput) (uuid.UUID, error) {
+			created = in
+			return uuid.New(), nil
+		},
+		getByID: func(_ context.Context, id uuid.UUID) (*domain.Application, error) { return &domain.Application{ID: id}, nil },
+	}, &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}, nil, nil, nil, nil)
+
+	in := domain.ApplyInput{Stops: []domain.ApplicationStopInput{{Lat: 0.01, Lng: 1.5}, {Lat: 0.01, Lng: 2.5}}}
+	if _, err := svc.Apply(context.Background(), uuid.New(), route.ID, in); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	want := []float64{1, 1.5, 2, 2.5}
+	if len(created.Stops) != len(want) {
+		t.Fatalf("Apply() stored %d stops, want %d", len(created.Stops), len(want))
+	}
+	for i, st := range created.Stops {
+		if st.Lng != want[i] || st.Position == nil || *st.Position != uint(i) {
+			t.Errorf("stop %d = lng %v position %v, want lng %v position %d", i, st.Lng, st.Position, want[i], i)
+		}
+		if (st.RouteStopID != nil) != (i%2 == 0) {
+			t.Errorf("stop %d route_stop_id = %v", i, st.RouteStopID)
+		}
+	}
+
+	pos := uint(0)
+	mixed := domain.ApplyInput{Stops: []domain.ApplicationStopInput{{Position: &pos, Lng: 1.5}, {Lng: 2.5}}}
+	if _, err := svc.Apply(context.Background(), uuid.New(), route.ID, mixed); !errors.Is(err, errs.ErrInvalidStops) {
+		t.Errorf("Apply(partial positions) = %v, want ErrInvalidStops", err)
+	}
+}
+
+func TestApplicationService_Preview(t *testing.T) {
+	route := lineRoute(uuid.New())
+	route.RemainingDeviation = 5
+	svc := NewApplicationService(&mockAppRepo{}, &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}, nil, nil, nil, nil)
+
+	got, err := svc.Preview(context.Background(), route.ID, domain.ApplicationPreviewInput{
+		Pickup: &domain.ApplicationStopInput{Lat: 0.01, Lng: 0.5},
+	})
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if got.PickupPosition == nil || *got.PickupPosition != 0 || got.DropoffPosition != nil {
+		t.Errorf("Preview() positions = %v, %v, want pickup 0 only", got.PickupPosition, got.DropoffPosition)
+	}
+	if len(got.Stops) != 3 || got.Stops[0].RouteStopID != nil {
+		t.Errorf("Preview() stops = %+v, want the pickup before both route stops", got.Stops)
+	}
+	if !got.WithinBudget || got.Detour <= 0 || got.Detour > 1 {
+		t.Errorf("Preview() detour = %v within budget = %v, want a small detour within budget", got.Detour, got.WithinBudget)
+	}
+
+	if _, err := svc.Preview(context.Background(), route.ID, domain.ApplicationPreviewInput{}); !errors.Is(err, errs.ErrInvalidStops) {
+		t.Errorf("Preview(no stops) = %v, want ErrInvalidStops", err)
 	}
 }
 
@@ -232,7 +496,7 @@ func TestApplicationService_Review_Forbidden(t *testing.T) {
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
 	}, &mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	})
+	}, nil, nil, nil, nil)
 	err := svc.Review(context.Background(), appID, "approved", callerID)
 	if !errors.Is(err, errs.ErrForbidden) {
 		t.Errorf("Review(non-creator) = %v, want ErrForbidden", err)
@@ -249,7 +513,7 @@ func TestApplicationService_Review_NotPending(t *testing.T) {
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
 	}, &mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	})
+	}, nil, nil, nil, nil)
 	err := svc.Review(context.Background(), appID, "rejected", creatorID)
 	if !errors.Is(err, errs.ErrConflict) {
 		t.Errorf("Review(already approved) = %v, want ErrConflict", err)
@@ -266,13 +530,161 @@ func TestApplicationService_Review_RouteStarted(t *testing.T) {
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
 	}, &mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	})
+	}, nil, nil, nil, nil)
 	err := svc.Review(context.Background(), appID, "approved", creatorID)
 	if !errors.Is(err, errs.ErrRouteStarted) {
 		t.Errorf("Review(started route) = %v, want ErrRouteStarted", err)
 	}
 }
 
+func TestApplicationService_Review_RouteFull(t *testing.T) {
+	creatorID := uuid.New()
+	route := activeRoute(creatorID, 1)
+	app := &domain.Application{ID: uuid.New(), RouteID: route.ID, Status: "pending", Seats: 2}
+
+	svc := NewApplicationService(&mockAppRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
+	}, &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}, nil, nil, nil, nil)
+	err := svc.Review(context.Background(), app.ID, "approved", creatorID)
+	if !errors.Is(err, errs.ErrRouteFull) {
+		t.Errorf("Review(2 seats, 1 free) = %v, want ErrRouteFull", err)
+	}
+}
+
+func TestApplicationService_Review_DetourBudget(t *testing.T) {
+	creatorID := uuid.New()
+	route := activeRoute(creatorID, 2)
+	route.StartLat, route.StartLng, route.EndLat, route.EndLng = 0, 0, 0, 2
+	route.MaxDeviation, route.RemainingDeviation = 10, 5
+
+	tests := []struct {
+		name    string
+		stop    domain.ApplicationStop
+		wantErr error
+	}{
+		{"within budget", domain.ApplicationStop{Lat: 0.01, Lng: 1}, nil},
+		{"over budget", domain.ApplicationStop{Lat: 0.3, Lng: 1}, errs.ErrDetourBudget},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &domain.Application{ID: uuid.New(), UserID: uuid.New(), RouteID: route.ID, Status: "pending", Stops: []domain.ApplicationStop{tt.stop}}
+			var stored *float64
+			svc := NewApplicationService(&mockAppRepo{
+				getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
+				reviewUpdate: func(_ context.Context, _ uuid.UUID, _ string, _, _ uuid.UUID, _ []domain.ApplicationStopInput, detourKm float64) error {
+					stored = &detourKm
+					return nil
+				},
+			}, &mockRouteRepo{
+				getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+			}, nil, nil, nil, nil)
+
+			err := svc.Review(context.Background(), app.ID, "approved", creatorID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Review() = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (stored == nil || *stored <= 0 || *stored > 1) {
+				t.Errorf("ReviewUpdate() detour = %v, want a small positive detour", stored)
+			}
+		})
+	}
+}
+
+func TestApplicationService_Review_DetourMeasuredOneWay(t *testing.T) {
+	creatorID := uuid.New()
+	route := activeRoute(creatorID, 2)
+	route.StartLat, route.StartLng, route.EndLat, route.EndLng = 0, 0, 0, 2
+	route.MaxDeviation, route.RemainingDeviation = 10, 5
+	app := &domain.Application{ID: uuid.New(), UserID: uuid.New(), RouteID: route.ID, Status: "pending",
+		Stops: []domain.ApplicationStop{{Lat: 0.01, Lng: 1}}}
+
+	// The router answers for the path through the stop but not for the
+	// route itself, so both must fall back to straight lines.
+	var stored float64
+	svc := NewApplicationService(&mockAppRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
+		reviewUpdate: func(_ context.Context, _ uuid.UUID, _ string, _, _ uuid.UUID, _ []domain.ApplicationStopInput, detourKm float64) error {
+			stored = detourKm
+			return nil
+		},
+	}, &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}, nil, stubRouter{3: 300}, nil, nil)
+
+	if err := svc.Review(context.Background(), app.ID, "approved", creatorID); err != nil {
+		t.Fatalf("Review() = %v, want nil", err)
+	}
+	if stored <= 0 || stored > 1 {
+		t.Errorf("ReviewUpdate() detour = %v, want a small straight-line detour", stored)
+	}
+}
+
+func TestApplicationService_Review_StaleStops(t *testing.T) {
+	creatorID := uuid.New()
+	route := lineRoute(creatorID)
+	route.MaxDeviation, route.RemainingDeviation = 10, 10
+	ref := func(st domain.Stop) *string { id := st.ID.String(); return &id }
+	stale := ref(domain.Stop{ID: uuid.New()})
+
+	tests := []struct {
+		name    string
+		stops   []domain.ApplicationStop
+		wantLng []float64
+		wantErr error
+	}{
+		{"current", []domain.ApplicationStop{
+			{Position: 0, Lng: 1, RouteStopID: ref(route.Stops[0])},
+			{Position: 1, Lat: 0.01, Lng: 1.5},
+			{Position: 2, Lng: 2, RouteStopID: ref(route.Stops[1])},
+			{Position: 3, Lat: 0.01, Lng: 2.5},
+		}, []float64{1, 1.5, 2, 2.5}, nil},
+		{"placed again", []domain.ApplicationStop{
+			{Position: 0, Lng: 1, RouteStopID: stale},
+			{Position: 1, Lat: 0.01, Lng: 1.5},
+			{Position: 2, Lat: 0.01, Lng: 2.5},
+		}, []float64{1, 1.5, 2, 2.5}, nil},
+		{"hand-ordered", []domain.ApplicationStop{
+			{Position: 0, Lat: 0.01, Lng: 0.5},
+			{Position: 1, Lng: 1, RouteStopID: stale},
+			{Position: 2, Lat: 0.01, Lng: 1.5},
+			{Position: 3, Lat: 0.01, Lng: 2.5},
+		}, nil, errs.ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &domain.Application{ID: uuid.New(), RouteID: route.ID, Status: "pending", Seats: 1, Stops: tt.stops}
+			var stored []domain.ApplicationStopInput
+			svc := NewApplicationService(&mockAppRepo{
+				getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
+				reviewUpdate: func(_ context.Context, _ uuid.UUID, _ string, _, _ uuid.UUID, stops []domain.ApplicationStopInput, _ float64) error {
+					stored = stops
+					return nil
+				},
+			}, &mockRouteRepo{
+				getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+			}, nil, nil, nil, nil)
+
+			err := svc.Review(context.Background(), app.ID, "approved", creatorID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Review() = %v, want %v", err, tt.wantErr)
+			}
+			if len(stored) != len(tt.wantLng) {
+				t.Fatalf("ReviewUpdate() got %d stops, want %d", len(stored), len(tt.wantLng))
+			}
+			for i, st := range stored {
+				if st.Lng != tt.wantLng[i] || st.Position == nil || *st.Position != uint(i) {
+					t.Errorf("stop %d = lng %v position %v, want lng %v position %d", i, st.Lng, st.Position, tt.wantLng[i], i)
+				}
+				if st.RouteStopID != nil && *st.RouteStopID == *stale {
+					t.Errorf("stop %d references a stop no longer on the route", i)
+				}
+			}
+		})
+	}
+}
+
 func TestApplicationService_Cancel_NotOwner(t *testing.T) {
 	ownerID := uuid.New()
 	callerID := uuid.New()
@@ -281,7 +693,7 @@ func TestApplicationService_Cancel_NotOwner(t *testing.T) {
 
 	svc := NewApplicationService(&mockAppRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
-	}, &mockRouteRepo{})
+	}, &mockRouteRepo{}, nil, nil, nil, nil)
 	err := svc.Cancel(context.Background(), appID, callerID)
 	if !errors.Is(err, errs.ErrForbidden) {
 		t.Errorf("Cancel(not owner) = %v, want ErrForbidden", err)
@@ -295,7 +707,7 @@ func TestApplicationService_Cancel_NotPending(t *testing.T) {
 
 	svc := NewApplicationService(&mockAppRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
-	}, &mockRouteRepo{})
+	}, &mockRouteRepo{}, nil, nil, nil, nil)
 	err := svc.Cancel(context.Background(), appID, ownerID)
 	if !errors.Is(err, errs.ErrConflict) {
 		t.Errorf("Cancel(not pending) = %v, want ErrConflict", err)
@@ -314,20 +726,162 @@ func TestApplicationService_Cancel_RouteStarted(t *testing.T) {
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
 	}, &mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	})
+	}, nil, nil, nil, nil)
 	err := svc.Cancel(context.Background(), appID, ownerID)
 	if !errors.Is(err, errs.ErrRouteStarted) {
 		t.Errorf("Cancel(started route) = %v, want ErrRouteStarted", err)
 	}
 }
 
+func TestApplicationService_Leave(t *testing.T) {
+	ownerID := uuid.New()
+	route := activeRoute(uuid.New(), 1)
+	app := &domain.Application{ID: uuid.New(), UserID: ownerID, RouteID: route.ID, Status: "approved"}
+
+	var gotStatus, gotReason string
+	svc := NewApplicationService(&mockAppRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
+		leave: func(_ context.Context, _ uuid.UUID, status, reason string) error {
+			gotStatus, gotReason = status, reason
+			return nil
+		},
+	}, &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}, nil, nil, nil, nil)
+
+	tests := []struct {
+		name   string
+		caller uuid.UUID
+		reason string
+		want   error
+	}{
+		{"missing reason", ownerID, "  ", errs.ErrInvalidReason},
+		{"reason too long", ownerID, strings.Repeat("a", 501), errs.ErrInvalidReason},
+		{"not owner", uuid.New(), "plans changed", errs.ErrForbidden},
+		{"left", ownerID, " plans changed ", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := svc.Leave(context.Background(), app.ID, tc.caller, tc.reason); !errors.Is(err, tc.want) {
+				t.Errorf("Leave() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+	if gotStatus != "left" || gotReason != "plans changed" {
+		t.Errorf("Leave() stored %q, %q, want \"left\", \"plans changed\"", gotStatus, gotReason)
+	}
+}
+
+func TestApplicationService_Leave_NotApproved(t *testing.T) {
+	ownerID := uuid.New()
+	app := &domain.Application{ID: uuid.New(), UserID: ownerID, Status: "pending"}
+
+	svc := NewApplicationService(&mockAppRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
+	}, &mockRouteRepo{}, nil, nil, nil, nil)
+	err := svc.Leave(context.Background(), app.ID, ownerID, "plans changed")
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("Leave(pending) = %v, want ErrConflict", err)
+	}
+}
+
+func TestApplicationService_Remove(t *testing.T) {
+	creatorID, passengerID := uuid.New(), uuid.New()
+	route := activeRoute(creatorID, 1)
+	app := &domain.Application{ID: uuid.New(), UserID: passengerID, RouteID: route.ID, Status: "approved"}
+
+	var removed uuid.UUID
+	var gotStatus string
+	svc := NewApplicationService(&mockAppRepo{
+		getByUserAndRoute: func(_ context.Context, userID, _ uuid.UUID) (*domain.Application, error) {
+			if userID != passengerID {
+				return nil, nil
+			}
+			return app, nil
+		},
+		leave: func(_ context.Context, id uuid.UUID, status, _ string) error {
+			removed, gotStatus = id, status
+			return nil
+		},
+	}, &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}, nil, nil, nil, nil)
+
+	tests := []struct {
+		name   string
+		user   uuid.UUID
+		caller uuid.UUID
+		want   error
+	}{
+		{"not the driver", passengerID, passengerID, errs.ErrForbidden},
+		{"not a passenger", uuid.New(), creatorID, errs.ErrNotParticipant},
+		{"removed", passengerID, creatorID, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := svc.Remove(context.Background(), route.ID, tc.user, tc.caller, "no show"); !errors.Is(err, tc.want) {
+				t.Errorf("Remove() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+	if removed != app.ID || gotStatus != "removed" {
+		t.Errorf("Remove() took off %v with %q, want %v with \"removed\"", removed, gotStatus, app.ID)
+	}
+}
+
+func TestApplicationService_History(t *testing.T) {
+	creatorID, applicantID := uuid.New(), uuid.New()
+	route := activeRoute(creatorID, 1)
+	appID := uuid.New()
+	events := []domain.ApplicationEvent{
+		{ApplicationID: appID, RouteID: route.ID, UserID: applicantID, Type: domain.EventSubmitted},
+		{ApplicationID: appID, RouteID: route.ID, UserID: applicantID, Type: domain.EventApproved, ActorUserID: &creatorID},
+	}
+
+	svc := NewApplicationService(&mockAppRepo{
+		listEvents: func(_ context.Context, id uuid.UUID) ([]domain.ApplicationEvent, error) {
+			if id != appID {
+				return nil, nil
+			}
+			return events, nil
+		},
+	}, &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}, nil, nil, nil, nil)
+
+	tests := []struct {
+		name    string
+		routeID uuid.UUID
+		appID   uuid.UUID
+		caller  uuid.UUID
+		want    error
+	}{
+		{"applicant", route.ID, appID, applicantID, nil},
+		{"driver", route.ID, appID, creatorID, nil},
+		{"someone else", route.ID, appID, uuid.New(), errs.ErrForbidden},
+		{"other route", uuid.New(), appID, applicantID, errs.ErrNotFound},
+		{"unknown application", route.ID, uuid.New(), creatorID, errs.ErrNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := svc.History(context.Background(), tc.routeID, tc.appID, tc.caller)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("History() error = %v, want %v", err, tc.want)
+			}
+			if err == nil && len(got) != len(events) {
+				t.Errorf("History() returned %d events, want %d", len(got), len(events))
+			}
+		})
+	}
+}
+
 // ── RouteService tests ────────────────────────────────────────────────────────
 
 func TestRouteService_CreateReview_RouteNotFinished(t *testing.T) {
 	route := activeRoute(uuid.New(), 1)
 	svc := NewRouteService(&mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	}, &mockReviewRepo{})
+	}, &mockReviewRepo{}, nil, nil, nil, DefaultRankWeights, nil, nil)
 
 	_, err := svc.CreateReview(context.Background(), route.ID, uuid.New(), 5, "", uuid.New())
 	if !errors.Is(err, errs.ErrRouteNotFinished) {
@@ -341,7 +895,7 @@ func TestRouteService_CreateReview_SelfReview(t *testing.T) {
 
 	svc := NewRouteService(&mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	}, &mockReviewRepo{})
+	}, &mockReviewRepo{}, nil, nil, nil, DefaultRankWeights, nil, nil)
 
 	_, err := svc.CreateReview(context.Background(), route.ID, userID, 5, "", userID)
 	if !errors.Is(err, errs.ErrForbidden) {
@@ -354,7 +908,7 @@ func TestRouteService_CreateReview_InvalidRating(t *testing.T) {
 
 	svc := NewRouteService(&mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	}, &mockReviewRepo{})
+	}, &mockReviewRepo{}, nil, nil, nil, DefaultRankWeights, nil, nil)
 
 	for _, rating := range []int{0, 6, -1} {
 		_, err := svc.CreateReview(context.Background(), route.ID, uuid.New(), rating, "", uuid.New())
@@ -378,7 +932,7 @@ func TestRouteService_CreateReview_AuthorNotParticipant(t *testing.T) {
 
 	svc := NewRouteService(&mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	}, &mockReviewRepo{})
+	}, &mockReviewRepo{}, nil, nil, nil, DefaultRankWeights, nil, nil)
 
 	_, err := svc.CreateReview(context.Background(), route.ID, authorID, 5, "", targetID)
 	if !errors.Is(err, errs.ErrNotParticipant) {
@@ -400,7 +954,7 @@ func TestRouteService_CreateReview_TargetNotParticipant(t *testing.T) {
 
 	svc := NewRouteService(&mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	}, &mockReviewRepo{})
+	}, &mockReviewRepo{}, nil, nil, nil, DefaultRankWeights, nil, nil)
 
 	_, err := svc.CreateReview(context.Background(), route.ID, authorID, 5, "", targetID)
 	if !errors.Is(err, errs.ErrNotParticipant) {
@@ -426,7 +980,7 @@ func TestRouteService_CreateReview_Success(t *testing.T) {
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
 	}, &mockReviewRepo{
 		create: func(_ context.Context, _ domain.CreateReviewInput) (uuid.UUID, error) { return newID, nil },
-	})
+	}, nil, nil, nil, DefaultRankWeights, nil, nil)
 
 	got, err := svc.CreateReview(context.Background(), route.ID, authorID, 4, "great ride", targetID)
 	if err != nil {
@@ -455,14 +1009,14 @@ func TestRouteService_Search_FiltersByDeviation(t *testing.T) {
 	}
 
 	svc := NewRouteService(&mockRouteRepo{
-		listSearchable: func(_ context.Context) ([]domain.Route, error) {
+		listSearchable: func(_ context.Context, _ domain.SearchRouteInput) ([]domain.Route, error) {
 			return []domain.Route{withinRoute, farRoute}, nil
 		},
 	}, &mockReviewRepo{
 		getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
 			return map[uuid.UUID]domain.ReviewSummary{}, nil
 		},
-	})
+	}, nil, nil, nil, DefaultRankWeights, nil, nil)
 
 	results, err := svc.Search(context.Background(), domain.SearchRouteInput{
 		StartLat: 0, StartLng: 0, EndLat: 1, EndLng: 1,
@@ -470,10 +1024,10 @@ func TestRouteService_Search_FiltersByDeviation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Search() error = %v", err)
 	}
-	if len(results) != 1 {
-		t.Fatalf("Search() returned %d results, want 1", len(results))
+	if len(results.Items) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results.Items))
 	}
-	if results[0].ID != withinRoute.ID {
+	if results.Items[0].ID != withinRoute.ID {
 		t.Errorf("Search() returned wrong route")
 	}
 }
@@ -496,14 +1050,14 @@ func TestRouteService_Search_SortedByDeviation(t *testing.T) {
 	}
 
 	svc := NewRouteService(&mockRouteRepo{
-		listSearchable: func(_ context.Context) ([]domain.Route, error) {
+		listSearchable: func(_ context.Context, _ domain.SearchRouteInput) ([]domain.Route, error) {
 			return []domain.Route{fartherRoute, closeRoute}, nil
 		},
 	}, &mockReviewRepo{
 		getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
 			return map[uuid.UUID]domain.ReviewSummary{}, nil
 		},
-	})
+	}, nil, nil, nil, DefaultRankWeights, nil, nil)
 
 	results, err := svc.Search(context.Background(), domain.SearchRouteInput{
 		StartLat: 0, StartLng: 0, EndLat: 1, EndLng: 1,
@@ -511,21 +1065,97 @@ func TestRouteService_Search_SortedByDeviation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Search() error = %v", err)
 	}
-	if len(results) != 2 {
-		t.Fatalf("Search() returned %d results, want 2", len(results))
+	if len(results.Items) != 2 {
+		t.Fatalf("Search() returned %d results, want 2", len(results.Items))
 	}
-	if results[0].ID != closeRoute.ID {
+	if results.Items[0].ID != closeRoute.ID {
 		t.Errorf("Search() first result should be closest route")
 	}
 }
 
+func TestRouteService_Search_Pagination(t *testing.T) {
+	creatorID := uuid.New()
+	var all []domain.Route
+	for i := 0; i < 5; i++ {
+		all = append(all, domain.Route{
+			ID: uuid.New(), CreatorID: creatorID,
+			StartLat: float64(i) * 0.01, EndLat: 1, EndLng: 1,
+			MaxDeviation: 1000,
+			Stops:        []domain.Stop{},
+			Participants: []domain.Participant{},
+		})
+	}
+	// Two routes with identical geometry must still be ordered deterministically.
+	twin := all[0]
+	twin.ID = uuid.New()
+	all = append(all, twin)
+
+	svc := NewRouteService(&mockRouteRepo{
+		listSearchable: func(_ context.Context, _ domain.SearchRouteInput) ([]domain.Route, error) {
+			return all, nil
+		},
+	}, &mockReviewRepo{
+		getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
+			return map[uuid.UUID]domain.ReviewSummary{}, nil
+		},
+	}, nil, nil, nil, DefaultRankWeights, nil, nil)
+
+	in := domain.SearchRouteInput{EndLat: 1, EndLng: 1, Limit: 4}
+	seen := make(map[uuid.UUID]bool)
+	pages := 0
+	for {
+		page, err := svc.Search(context.Background(), in)
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		pages++
+		for _, r := range page.Items {
+			if seen[r.ID] {
+				t.Fatalf("route %v returned on more than one page", r.ID)
+			}
+			seen[r.ID] = true
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		in.Cursor = *page.NextCursor
+	}
+	if pages != 2 || len(seen) != len(all) {
+		t.Errorf("paged through %d routes in %d pages, want %d in 2", len(seen), pages, len(all))
+	}
+}
+
+func TestRouteService_Search_InvalidFilters(t *testing.T) {
+	negative := -1.0
+	zero := uint(0)
+	tests := []struct {
+		name string
+		in   domain.SearchRouteInput
+	}{
+		{"window reversed", domain.SearchRouteInput{LeavingAfter: futureTime(), LeavingBefore: pastTime()}},
+		{"negative max price", domain.SearchRouteInput{MaxPrice: &negative}},
+		{"zero min seats", domain.SearchRouteInput{MinSeats: &zero}},
+		{"limit too large", domain.SearchRouteInput{Limit: maxSearchLimit + 1}},
+		{"malformed cursor", domain.SearchRouteInput{Cursor: "not-a-cursor"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewRouteService(&mockRouteRepo{}, &mockReviewRepo{}, nil, nil, nil, DefaultRankWeights, nil, nil)
+			_, err := svc.Search(context.Background(), tt.in)
+			if !errors.Is(err, errs.ErrInvalidSearch) {
+				t.Errorf("Search() = %v, want ErrInvalidSearch", err)
+			}
+		})
+	}
+}
+
 func TestRouteService_Delete_RouteStarted(t *testing.T) {
 	creatorID := uuid.New()
 	route := startedRoute(creatorID)
 
 	svc := NewRouteService(&mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	}, &mockReviewRepo{})
+	}, &mockReviewRepo{}, nil, nil, nil, DefaultRankWeights, nil, nil)
 
 	err := svc.Delete(context.Background(), route.ID, creatorID)
 	if !errors.Is(err, errs.ErrRouteStarted) {
@@ -539,7 +1169,7 @@ func TestRouteService_Update_RouteStarted(t *testing.T) {
 
 	svc := NewRouteService(&mockRouteRepo{
 		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
-	}, &mockReviewRepo{})
+	}, &mockReviewRepo{}, nil, nil, nil, DefaultRankWeights, nil, nil)
 
 	err := svc.Update(context.Background(), route.ID, creatorID, domain.UpdateRouteInput{})
 	if !errors.Is(err, errs.ErrRouteStarted) {
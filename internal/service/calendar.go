@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/export"
+)
+
+// CalendarService serves each user's rides as a private iCalendar feed.
+type CalendarService struct {
+	users  domain.UserRepository
+	routes domain.RouteRepository
+}
+
+// NewCalendarService creates a CalendarService backed by the given repositories.
+func NewCalendarService(users domain.UserRepository, routes domain.RouteRepository) *CalendarService {
+	return &CalendarService{users: users, routes: routes}
+}
+
+// Feed returns the ICS feed of the user whose token is token: every route
+// they drive or ride on, cancelled ones included, by departure.
+func (s *CalendarService) Feed(ctx context.Context, token string) ([]byte, error) {
+	user, err := s.users.GetByCalendarToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.routes.ListByCreator(ctx, user.ID, domain.RouteFilterWithCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("calendar feed: list created: %w", err)
+	}
+	joined, err := s.routes.ListByParticipant(ctx, user.ID, domain.RouteFilterWithCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("calendar feed: list participated: %w", err)
+	}
+
+	// Drivers are participants of their own routes, so both lists hold them.
+	seen := make(map[uuid.UUID]bool)
+	var routes []domain.Route
+	for _, r := range append(created, joined...) {
+		if !seen[r.ID] {
+			seen[r.ID] = true
+			routes = append(routes, r)
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		a, b := routes[i].LeavingAt, routes[j].LeavingAt
+		return a != nil && (b == nil || a.Before(*b))
+	})
+	return export.Calendar("Rides", routes, time.Now()), nil
+}
+
+// RotateToken gives the user a new feed token, invalidating the old feed URL.
+func (s *CalendarService) RotateToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("rotate calendar token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	if err := s.users.SetCalendarToken(ctx, userID, token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
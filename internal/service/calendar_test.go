@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+type mockUserRepo struct {
+	getByCalendarToken func(ctx context.Context, token string) (*domain.User, error)
+	setCalendarToken   func(ctx context.Context, id uuid.UUID, token string) error
+}
+
+func (m *mockUserRepo) Upsert(ctx context.Context, email, name, provider, providerSub string) (uuid.UUID, error) {
+	return uuid.Nil, nil
+}
+func (m *mockUserRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return nil, nil
+}
+func (m *mockUserRepo) UpdateName(ctx context.Context, id uuid.UUID, name *string) error {
+	return nil
+}
+func (m *mockUserRepo) Disable(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+func (m *mockUserRepo) GetByCalendarToken(ctx context.Context, token string) (*domain.User, error) {
+	return m.getByCalendarToken(ctx, token)
+}
+func (m *mockUserRepo) SetCalendarToken(ctx context.Context, id uuid.UUID, token string) error {
+	return m.setCalendarToken(ctx, id, token)
+}
+
+func TestCalendarService_Feed(t *testing.T) {
+	user := &domain.User{ID: uuid.New()}
+	early, late := time.Now().Add(time.Hour), time.Now().Add(48*time.Hour)
+	driven := domain.Route{ID: uuid.New(), LeavingAt: &late}
+	ridden := domain.Route{ID: uuid.New(), LeavingAt: &early, CancelledAt: &early}
+
+	var filters []domain.RouteFilter
+	svc := NewCalendarService(&mockUserRepo{
+		getByCalendarToken: func(_ context.Context, token string) (*domain.User, error) {
+			if token != "secret" {
+				t.Errorf("GetByCalendarToken(%q), want secret", token)
+			}
+			return user, nil
+		},
+	}, &mockRouteRepo{
+		listByCreator: func(_ context.Context, _ uuid.UUID, f domain.RouteFilter) ([]domain.Route, error) {
+			filters = append(filters, f)
+			return []domain.Route{driven}, nil
+		},
+		listByParticipant: func(_ context.Context, _ uuid.UUID, f domain.RouteFilter) ([]domain.Route, error) {
+			filters = append(filters, f)
+			// The driver is a participant of their own route too.
+			return []domain.Route{driven, ridden}, nil
+		},
+	})
+
+	out, err := svc.Feed(context.Background(), "secret")
+	if err != nil {
+		t.Fatalf("Feed() error = %v", err)
+	}
+	for _, f := range filters {
+		if f != domain.RouteFilterWithCancelled {
+			t.Errorf("routes listed with filter %q, want cancelled routes included", f)
+		}
+	}
+	feed := string(out)
+	if n := strings.Count(feed, "BEGIN:VEVENT"); n != 2 {
+		t.Fatalf("Feed() has %d events, want 2", n)
+	}
+	first, second := strings.Index(feed, ridden.ID.String()), strings.Index(feed, driven.ID.String())
+	if first < 0 || second < 0 || first > second {
+		t.Error("Feed() should list both routes by departure")
+	}
+	if !strings.Contains(feed, "STATUS:CANCELLED") {
+		t.Error("Feed() should mark the cancelled route")
+	}
+}
+
+func TestCalendarService_RotateToken(t *testing.T) {
+	userID := uuid.New()
+	var stored []string
+	svc := NewCalendarService(&mockUserRepo{
+		setCalendarToken: func(_ context.Context, id uuid.UUID, token string) error {
+			if id != userID {
+				t.Errorf("SetCalendarToken() user = %v, want %v", id, userID)
+			}
+			stored = append(stored, token)
+			return nil
+		},
+	}, &mockRouteRepo{})
+
+	a, err := svc.RotateToken(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("RotateToken() error = %v", err)
+	}
+	b, _ := svc.RotateToken(context.Background(), userID)
+	if len(a) != 64 || a == b || len(stored) != 2 || stored[1] != b {
+		t.Errorf("RotateToken() = %q then %q, want distinct stored 64-char tokens", a, b)
+	}
+}
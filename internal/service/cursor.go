@@ -0,0 +1,58 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/errs"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// searchCursor is the sort key of the last route on a search page.
+// Clients receive it base64-encoded and must treat it as opaque.
+type searchCursor struct {
+	// Return is set when the route's linked leg also matches the way back of
+	// a round-trip search.
+	Return  bool      `json:"r,omitempty"`
+	Score   float64   `json:"s"`
+	RouteID uuid.UUID `json:"id"`
+	// Km is the trip length per-km fares were estimated for on the first
+	// page. Later pages reuse it, so the fares, and with them the scores, do
+	// not change with the routing engine's answers.
+	Km float64 `json:"km,omitempty"`
+}
+
+// less reports whether c sorts before o: round trips first, then by
+// descending score, then by route ID.
+func (c searchCursor) less(o searchCursor) bool {
+	if c.Return != o.Return {
+		return c.Return
+	}
+	if c.Score != o.Score {
+		return c.Score > o.Score
+	}
+	return c.RouteID.String() < o.RouteID.String()
+}
+
+func (c searchCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSearchCursor(s string) (searchCursor, error) {
+	var c searchCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", errs.ErrInvalidSearch)
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.RouteID == uuid.Nil {
+		return c, fmt.Errorf("%w: malformed cursor", errs.ErrInvalidSearch)
+	}
+	return c, nil
+}
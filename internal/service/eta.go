@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/geo"
+)
+
+// ETAEstimator estimates when a route reaches each of its stops and its end,
+// and stores the estimates with the route. Services refresh them after every
+// change to a route's departure or stops; a nil *ETAEstimator does nothing.
+type ETAEstimator struct {
+	routes   domain.RouteRepository
+	router   domain.Router
+	speedKmh float64
+	log      *slog.Logger
+}
+
+// NewETAEstimator returns an estimator that times legs with router when one
+// is configured and in straight lines at speedKmh otherwise.
+func NewETAEstimator(routes domain.RouteRepository, router domain.Router, speedKmh float64, log *slog.Logger) *ETAEstimator {
+	if speedKmh <= 0 {
+		speedKmh = defaultAverageSpeedKmh
+	}
+	return &ETAEstimator{routes: routes, router: router, speedKmh: speedKmh, log: log}
+}
+
+// refreshAttempts is how many times a refresh of ETAs or fares starts over
+// before giving up.
+const refreshAttempts = 3
+
+// Refresh recomputes and stores the ETAs of a route.
+func (e *ETAEstimator) Refresh(ctx context.Context, routeID uuid.UUID) error {
+	if e == nil {
+		return nil
+	}
+	_, err := e.refresh(ctx, routeID)
+	return err
+}
+
+// RefreshCreated is Refresh for a newly created route, which may have been
+// created together with its return leg.
+func (e *ETAEstimator) RefreshCreated(ctx context.Context, routeID uuid.UUID) error {
+	if e == nil {
+		return nil
+	}
+	r, err := e.refresh(ctx, routeID)
+	if err != nil || r.ReturnRouteID == nil {
+		return err
+	}
+	_, err = e.refresh(ctx, *r.ReturnRouteID)
+	return err
+}
+
+// Changed refreshes the ETAs of a route after a change to it was committed,
+// even if the request that made it is cancelled. ETAs are best-effort: a
+// failure is logged and leaves them to the route's next change.
+func (e *ETAEstimator) Changed(ctx context.Context, routeID uuid.UUID) {
+	if err := e.Refresh(context.WithoutCancel(ctx), routeID); err != nil {
+		e.log.Error("refresh route etas", slog.String("route_id", routeID.String()), slog.Any("error", err))
+	}
+}
+
+// Created is Changed for a newly created route and its return leg.
+func (e *ETAEstimator) Created(ctx context.Context, routeID uuid.UUID) {
+	if err := e.RefreshCreated(context.WithoutCancel(ctx), routeID); err != nil {
+		e.log.Error("refresh route etas", slog.String("route_id", routeID.String()), slog.Any("error", err))
+	}
+}
+
+// refresh estimates and stores the ETAs of a route, starting over when the
+// route changed in the meantime.
+func (e *ETAEstimator) refresh(ctx context.Context, routeID uuid.UUID) (*domain.Route, error) {
+	for attempt := 1; ; attempt++ {
+		r, err := e.routes.GetByID(ctx, routeID)
+		if err != nil {
+			return nil, err
+		}
+		etas := e.estimate(ctx, r)
+		etas.Basis = r
+		err = e.routes.SetETAs(ctx, routeID, etas)
+		if !errors.Is(err, errs.ErrConflict) || attempt == refreshAttempts {
+			return r, err
+		}
+	}
+}
+
+// estimate drives r from leaving_at through its stops in order.
+func (e *ETAEstimator) estimate(ctx context.Context, r *domain.Route) domain.RouteETAs {
+	etas := domain.RouteETAs{Stops: make(map[uuid.UUID]time.Time, len(r.Stops))}
+	if r.LeavingAt == nil {
+		return etas
+	}
+	legs := e.legDurations(ctx, routePoints(r))
+	at := *r.LeavingAt
+	for i, st := range r.Stops {
+		at = at.Add(legs[i])
+		etas.Stops[st.ID] = at
+	}
+	at = at.Add(legs[len(legs)-1])
+	etas.Arrival = &at
+	return etas
+}
+
+// legDurations returns the driving time between consecutive points, falling
+// back to straight lines when the router fails.
+func (e *ETAEstimator) legDurations(ctx context.Context, points []domain.LatLng) []time.Duration {
+	if e.router != nil {
+		if p, err := e.router.Route(ctx, points); err == nil && len(p.Legs) == len(points)-1 {
+			return p.Legs
+		}
+	}
+	legs := make([]time.Duration, len(points)-1)
+	for i := range legs {
+		km := geo.Haversine(points[i].Lat, points[i].Lng, points[i+1].Lat, points[i+1].Lng)
+		legs[i] = time.Duration(km / e.speedKmh * float64(time.Hour))
+	}
+	return legs
+}
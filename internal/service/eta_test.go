@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+)
+
+type legRouter []time.Duration
+
+func (l legRouter) Route(_ context.Context, _ []domain.LatLng) (*domain.RoutePath, error) {
+	return &domain.RoutePath{Legs: l}, nil
+}
+
+func TestETAEstimator_Refresh(t *testing.T) {
+	leaving := time.Date(2030, 1, 1, 8, 0, 0, 0, time.UTC)
+	stop := domain.Stop{ID: uuid.New(), Lat: 0, Lng: 1}
+	route := &domain.Route{
+		ID: uuid.New(), StartLat: 0, StartLng: 0, EndLat: 0, EndLng: 2,
+		LeavingAt: &leaving,
+		Stops:     []domain.Stop{stop},
+	}
+	var got domain.RouteETAs
+	repo := &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+		setETAs: func(_ context.Context, _ uuid.UUID, etas domain.RouteETAs) error {
+			got = etas
+			return nil
+		},
+	}
+
+	// Without a router each ~111 km leg takes ~111 minutes at 60 km/h.
+	if err := NewETAEstimator(repo, nil, 60, nil).Refresh(context.Background(), route.ID); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if d := got.Stops[stop.ID].Sub(leaving).Minutes(); math.Abs(d-111.2) > 0.5 {
+		t.Errorf("stop ETA = %v min after departure, want ~111.2", d)
+	}
+	if got.Arrival == nil || math.Abs(got.Arrival.Sub(leaving).Minutes()-222.4) > 1 {
+		t.Errorf("Arrival = %v, want ~222.4 min after departure", got.Arrival)
+	}
+
+	// The router's leg durations take precedence.
+	if err := NewETAEstimator(repo, legRouter{time.Hour, 30 * time.Minute}, 60, nil).Refresh(context.Background(), route.ID); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if want := leaving.Add(time.Hour); !got.Stops[stop.ID].Equal(want) {
+		t.Errorf("stop ETA = %v, want %v", got.Stops[stop.ID], want)
+	}
+	if want := leaving.Add(90 * time.Minute); got.Arrival == nil || !got.Arrival.Equal(want) {
+		t.Errorf("Arrival = %v, want %v", got.Arrival, want)
+	}
+
+	// Routes without a departure time have no ETAs.
+	route.LeavingAt = nil
+	if err := NewETAEstimator(repo, nil, 60, nil).Refresh(context.Background(), route.ID); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if len(got.Stops) != 0 || got.Arrival != nil {
+		t.Errorf("Refresh(no leaving_at) = %+v, want no ETAs", got)
+	}
+}
+
+func TestETAEstimator_Refresh_StartsOverOnConflict(t *testing.T) {
+	before, after := time.Date(2030, 1, 1, 8, 0, 0, 0, time.UTC), time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	route := &domain.Route{ID: uuid.New(), EndLng: 1, LeavingAt: &before}
+	var stored []time.Time
+	repo := &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) {
+			r := *route
+			return &r, nil
+		},
+		setETAs: func(_ context.Context, _ uuid.UUID, etas domain.RouteETAs) error {
+			// The departure moves while the first estimate is being made.
+			if etas.Basis.LeavingAt.Equal(before) {
+				route.LeavingAt = &after
+				return errs.ErrConflict
+			}
+			stored = append(stored, *etas.Arrival)
+			return nil
+		},
+	}
+
+	if err := NewETAEstimator(repo, nil, 60, nil).Refresh(context.Background(), route.ID); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if len(stored) != 1 || !stored[0].After(after) {
+		t.Errorf("stored arrivals = %v, want one after the new departure", stored)
+	}
+}
+
+func TestApplicationService_Review_RefreshesETAs(t *testing.T) {
+	creatorID := uuid.New()
+	route := activeRoute(creatorID, 2)
+	app := &domain.Application{ID: uuid.New(), UserID: uuid.New(), RouteID: route.ID, Status: "pending"}
+	refreshed := false
+	routes := &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+		setETAs: func(_ context.Context, id uuid.UUID, _ domain.RouteETAs) error {
+			refreshed = id == route.ID
+			return nil
+		},
+	}
+	svc := NewApplicationService(&mockAppRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Application, error) { return app, nil },
+		reviewUpdate: func(_ context.Context, _ uuid.UUID, _ string, _, _ uuid.UUID, _ []domain.ApplicationStopInput, _ float64) error {
+			return nil
+		},
+	}, routes, nil, nil, NewETAEstimator(routes, nil, 60, nil), nil)
+
+	if err := svc.Review(context.Background(), app.ID, "approved", creatorID); err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if !refreshed {
+		t.Error("approving an application should refresh the route's ETAs")
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+// ExpirySweeper rejects applications and stop changes the driver left
+// unanswered past their deadline.
+type ExpirySweeper struct {
+	apps            domain.ApplicationRepository
+	beforeDeparture time.Duration
+	afterSubmit     time.Duration
+}
+
+// NewExpirySweeper returns a sweeper that expires requests beforeDeparture
+// ahead of their route's leaving_at and, when afterSubmit is positive,
+// afterSubmit after they started waiting for the driver.
+func NewExpirySweeper(apps domain.ApplicationRepository, beforeDeparture, afterSubmit time.Duration) *ExpirySweeper {
+	return &ExpirySweeper{apps: apps, beforeDeparture: beforeDeparture, afterSubmit: afterSubmit}
+}
+
+// Sweep expires everything past its deadline at now and returns how many
+// applications and stop changes it expired.
+func (s *ExpirySweeper) Sweep(ctx context.Context, now time.Time) (int, error) {
+	leavingBefore := now.Add(s.beforeDeparture)
+	var awaitingBefore *time.Time
+	if s.afterSubmit > 0 {
+		t := now.Add(-s.afterSubmit)
+		awaitingBefore = &t
+	}
+	stale, err := s.apps.ListStale(ctx, leavingBefore, awaitingBefore)
+	if err != nil {
+		return 0, fmt.Errorf("sweep applications: %w", err)
+	}
+	var errList []error
+	n := 0
+	for _, a := range stale {
+		if err := s.apps.Expire(ctx, a, expiryReason(a, leavingBefore)); err != nil {
+			errList = append(errList, fmt.Errorf("expire application %s: %w", a.ID, err))
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errList...)
+}
+
+// expiryReason names the deadline a passed: its route's departure when that
+// is near, the wait for the driver otherwise.
+func expiryReason(a domain.StaleApplication, leavingBefore time.Time) string {
+	if a.RouteLeavingAt != nil && a.RouteLeavingAt.Before(leavingBefore) {
+		return domain.ExpiryReasonDeparture
+	}
+	return domain.ExpiryReasonUnanswered
+}
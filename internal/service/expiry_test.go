@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+func TestExpirySweeper_Sweep(t *testing.T) {
+	now := time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+	soon, later := now.Add(30*time.Minute), now.Add(48*time.Hour)
+	departing := domain.StaleApplication{ID: uuid.New(), Status: "pending", RouteLeavingAt: &soon}
+	unanswered := domain.StaleApplication{ID: uuid.New(), Status: "approved", StopChange: true, RouteLeavingAt: &later}
+	failing := domain.StaleApplication{ID: uuid.New(), Status: "pending"}
+
+	reasons := map[uuid.UUID]string{}
+	svc := NewExpirySweeper(&mockAppRepo{
+		listStale: func(_ context.Context, leavingBefore time.Time, awaitingBefore *time.Time) ([]domain.StaleApplication, error) {
+			if !leavingBefore.Equal(now.Add(time.Hour)) {
+				t.Errorf("ListStale() leavingBefore = %v, want an hour from now", leavingBefore)
+			}
+			if awaitingBefore == nil || !awaitingBefore.Equal(now.Add(-24*time.Hour)) {
+				t.Errorf("ListStale() awaitingBefore = %v, want a day ago", awaitingBefore)
+			}
+			return []domain.StaleApplication{departing, unanswered, failing}, nil
+		},
+		expire: func(_ context.Context, app domain.StaleApplication, reason string) error {
+			if app.ID == failing.ID {
+				return errors.New("db down")
+			}
+			reasons[app.ID] = reason
+			return nil
+		},
+	}, time.Hour, 24*time.Hour)
+
+	n, err := svc.Sweep(context.Background(), now)
+	if err == nil {
+		t.Error("Sweep() error = nil, want the failed expiry reported")
+	}
+	if n != 2 {
+		t.Errorf("Sweep() = %d, want 2", n)
+	}
+	if got := reasons[departing.ID]; got != domain.ExpiryReasonDeparture {
+		t.Errorf("departing route reason = %q, want %q", got, domain.ExpiryReasonDeparture)
+	}
+	if got := reasons[unanswered.ID]; got != domain.ExpiryReasonUnanswered {
+		t.Errorf("unanswered stop change reason = %q, want %q", got, domain.ExpiryReasonUnanswered)
+	}
+}
+
+func TestExpirySweeper_Sweep_NoSubmitDeadline(t *testing.T) {
+	svc := NewExpirySweeper(&mockAppRepo{
+		listStale: func(_ context.Context, _ time.Time, awaitingBefore *time.Time) ([]domain.StaleApplication, error) {
+			if awaitingBefore != nil {
+				t.Errorf("ListStale() awaitingBefore = %v, want nil when disabled", awaitingBefore)
+			}
+			return nil, nil
+		},
+	}, 0, 0)
+	if n, err := svc.Sweep(context.Background(), time.Now()); n != 0 || err != nil {
+		t.Errorf("Sweep() = %d, %v, want 0, nil", n, err)
+	}
+}
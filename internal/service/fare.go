@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/geo"
+)
+
+// FareCalculator splits the cost of per-km routes between their passengers
+// and stores each passenger's share with the route. Services refresh shares
+// after every change to a route's stops or pricing; a nil *FareCalculator
+// does nothing.
+type FareCalculator struct {
+	routes domain.RouteRepository
+	router domain.Router
+	log    *slog.Logger
+}
+
+// NewFareCalculator returns a calculator that measures distances with router
+// when one is configured and in straight lines otherwise.
+func NewFareCalculator(routes domain.RouteRepository, router domain.Router, log *slog.Logger) *FareCalculator {
+	return &FareCalculator{routes: routes, router: router, log: log}
+}
+
+// Refresh recomputes and stores the passengers' shares of a route. Shares
+// left over from a per-km route switched to flat pricing are cleared.
+func (f *FareCalculator) Refresh(ctx context.Context, routeID uuid.UUID) error {
+	if f == nil {
+		return nil
+	}
+	r, err := f.routes.GetByID(ctx, routeID)
+	if err != nil {
+		return err
+	}
+	shares := f.shares(ctx, r)
+	if len(shares) == 0 && !hasPriceShares(r) {
+		return nil
+	}
+	return f.routes.SetPriceShares(ctx, routeID, shares)
+}
+
+// Changed refreshes the shares of a route after a change to it was committed,
+// even if the request that made it is cancelled. A failed refresh is retried
+// from a fresh read of the route, then logged.
+func (f *FareCalculator) Changed(ctx context.Context, routeID uuid.UUID) {
+	ctx = context.WithoutCancel(ctx)
+	var err error
+	for range refreshAttempts {
+		if err = f.Refresh(ctx, routeID); err == nil || errors.Is(err, errs.ErrNotFound) {
+			return
+		}
+	}
+	f.log.Error("refresh route fares", slog.String("route_id", routeID.String()), slog.Any("error", err))
+}
+
+// shares charges each approved passenger of a per-km route for the distance
+// the car drives between their first and last stop, per seat booked. A
+// passenger with a single stop is picked up there and rides to the end; one
+// without stops rides the whole route.
+func (f *FareCalculator) shares(ctx context.Context, r *domain.Route) map[uuid.UUID]float64 {
+	shares := make(map[uuid.UUID]float64)
+	if r.PricingMode != domain.PricingPerKm || r.PricePerKm == nil {
+		return shares
+	}
+	points := routePoints(r)
+	for _, p := range r.Participants {
+		if p.Status != "approved" {
+			continue
+		}
+		from, to := 0, len(points)-1
+		var own []int
+		for i, st := range r.Stops {
+			if st.ParticipantID != nil && *st.ParticipantID == p.ID.String() {
+				own = append(own, i+1) // points[0] is the start
+			}
+		}
+		if len(own) > 0 {
+			from = own[0]
+		}
+		if len(own) > 1 {
+			to = own[len(own)-1]
+		}
+		km := pathKm(ctx, f.router, points[from:to+1])
+		seats := math.Max(1, float64(p.Seats))
+		shares[p.ID] = math.Round(km**r.PricePerKm*seats*100) / 100
+	}
+	return shares
+}
+
+func hasPriceShares(r *domain.Route) bool {
+	for _, p := range r.Participants {
+		if p.PriceShare != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// tripKm returns the length of the trip a search asks for, from its start
+// through its stops to its end.
+func tripKm(ctx context.Context, router domain.Router, in domain.SearchRouteInput) float64 {
+	points := make([]domain.LatLng, 0, len(in.Stops)+2)
+	points = append(points, domain.LatLng{Lat: in.StartLat, Lng: in.StartLng})
+	for _, st := range in.Stops {
+		points = append(points, domain.LatLng{Lat: st.Lat, Lng: st.Lng})
+	}
+	points = append(points, domain.LatLng{Lat: in.EndLat, Lng: in.EndLng})
+	return pathKm(ctx, router, points)
+}
+
+// estimateFare sets the EstimatedPrice of a per-km route to what one seat
+// costs for a trip of km, rounded like the share the passenger will pay.
+func estimateFare(r *domain.Route, km float64) {
+	if r.PricingMode != domain.PricingPerKm || r.PricePerKm == nil {
+		return
+	}
+	fare := math.Round(km**r.PricePerKm*100) / 100
+	r.EstimatedPrice = &fare
+}
+
+// pathKm returns the driving distance through points, on the road network
+// when router is non-nil and in straight lines otherwise.
+func pathKm(ctx context.Context, router domain.Router, points []domain.LatLng) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+	if km, ok := roadKm(ctx, router, points); ok {
+		return km
+	}
+	return lineKm(points)
+}
+
+// pathsKm measures every path the same way: on the road network when the
+// router answers for all of them and in straight lines otherwise, so the
+// lengths can be compared with each other.
+func pathsKm(ctx context.Context, router domain.Router, paths ...[]domain.LatLng) []float64 {
+	kms := make([]float64, len(paths))
+	for i, points := range paths {
+		if len(points) < 2 {
+			continue
+		}
+		km, ok := roadKm(ctx, router, points)
+		if !ok {
+			for j, points := range paths {
+				kms[j] = lineKm(points)
+			}
+			return kms
+		}
+		kms[i] = km
+	}
+	return kms
+}
+
+// lineKm returns the straight-line distance through points.
+func lineKm(points []domain.LatLng) float64 {
+	km := 0.0
+	for i := 1; i < len(points); i++ {
+		km += geo.Haversine(points[i-1].Lat, points[i-1].Lng, points[i].Lat, points[i].Lng)
+	}
+	return km
+}
+
+// roadKm returns the driving distance through points on the road network. It
+// reports false when router is nil, fails or only measures straight lines.
+func roadKm(ctx context.Context, router domain.Router, points []domain.LatLng) (float64, bool) {
+	if router == nil {
+		return 0, false
+	}
+	p, err := router.Route(ctx, points)
+	if err != nil || p.Approximate {
+		return 0, false
+	}
+	return p.DistanceKm, true
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+)
+
+func TestFareCalculator_Refresh(t *testing.T) {
+	rate := 0.1
+	rider, through, pending := uuid.New(), uuid.New(), uuid.New()
+	riderID := rider.String()
+	// One degree of longitude on the equator is ~111.2 km.
+	route := &domain.Route{
+		ID: uuid.New(), StartLat: 0, StartLng: 0, EndLat: 0, EndLng: 3,
+		PricingMode: domain.PricingPerKm, PricePerKm: &rate,
+		Stops: []domain.Stop{
+			{ID: uuid.New(), Lat: 0, Lng: 1, ParticipantID: &riderID},
+			{ID: uuid.New(), Lat: 0, Lng: 2, ParticipantID: &riderID},
+		},
+		Participants: []domain.Participant{
+			{ID: rider, Status: "approved"},
+			{ID: through, Status: "approved", Seats: 2},
+			{ID: pending, Status: "pending"},
+		},
+	}
+	var got map[uuid.UUID]float64
+	repo := &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+		setPriceShares: func(_ context.Context, _ uuid.UUID, shares map[uuid.UUID]float64) error {
+			got = shares
+			return nil
+		},
+	}
+
+	if err := NewFareCalculator(repo, nil, nil).Refresh(context.Background(), route.ID); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Refresh() stored %d shares, want one per approved participant", len(got))
+	}
+	if math.Abs(got[rider]-11.12) > 0.01 {
+		t.Errorf("share between own stops = %v, want ~11.12", got[rider])
+	}
+	if math.Abs(got[through]-66.72) > 0.01 {
+		t.Errorf("two-seat share without stops = %v, want ~66.72 (the whole route twice)", got[through])
+	}
+
+	// Switching to flat pricing clears the stored shares.
+	share := got[rider]
+	route.PricingMode, route.PricePerKm = domain.PricingFlat, nil
+	route.Participants[0].PriceShare = &share
+	got = nil
+	if err := NewFareCalculator(repo, nil, nil).Refresh(context.Background(), route.ID); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("Refresh(flat) stored %v, want the shares cleared", got)
+	}
+}
+
+func TestFareCalculator_Changed_Retries(t *testing.T) {
+	rate := 0.1
+	route := &domain.Route{
+		ID: uuid.New(), EndLng: 1, PricingMode: domain.PricingPerKm, PricePerKm: &rate,
+		Participants: []domain.Participant{{ID: uuid.New(), Status: "approved"}},
+	}
+	calls := 0
+	repo := &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+		setPriceShares: func(_ context.Context, _ uuid.UUID, _ map[uuid.UUID]float64) error {
+			if calls++; calls == 1 {
+				return errors.New("deadlock")
+			}
+			return nil
+		},
+	}
+
+	NewFareCalculator(repo, nil, nil).Changed(context.Background(), route.ID)
+	if calls != 2 {
+		t.Errorf("SetPriceShares() called %d times, want a retry after the failure", calls)
+	}
+}
+
+func TestRouteService_Create_InvalidPricing(t *testing.T) {
+	rate, zero := 0.2, 0.0
+	tests := []struct {
+		name  string
+		mode  string
+		perKm *float64
+	}{
+		{"unknown mode", "per_seat", nil},
+		{"per_km without rate", domain.PricingPerKm, nil},
+		{"per_km with zero rate", domain.PricingPerKm, &zero},
+		{"flat with rate", domain.PricingFlat, &rate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewRouteService(&mockRouteRepo{}, nil, nil, nil, nil, DefaultRankWeights, nil, nil)
+			_, err := svc.Create(context.Background(), uuid.New(), domain.CreateRouteInput{
+				PricingMode: tt.mode, PricePerKm: tt.perKm,
+			})
+			if !errors.Is(err, errs.ErrInvalidPricing) {
+				t.Errorf("Create() error = %v, want ErrInvalidPricing", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/geo"
+)
+
+// placeStops returns stops as submitted when the client ordered them. When
+// every position is omitted, stops are the passenger's pickup and dropoff in
+// travel order, and are inserted among the route's other stops where they
+// add the least distance. Stops owned by owner (a participant ID) are left
+// out, so a stop change replaces them.
+func placeStops(route *domain.Route, stops []domain.ApplicationStopInput, owner string) ([]domain.ApplicationStopInput, error) {
+	omitted := 0
+	for _, st := range stops {
+		if st.Position == nil {
+			omitted++
+		}
+	}
+	switch {
+	case omitted == 0:
+		return stops, nil
+	case omitted < len(stops):
+		return nil, fmt.Errorf("%w: set every position or none", errs.ErrInvalidStops)
+	case len(stops) > 2:
+		return nil, fmt.Errorf("%w: only a pickup and a dropoff can be placed automatically", errs.ErrInvalidStops)
+	}
+	for _, st := range stops {
+		if st.RouteStopID != nil {
+			return nil, fmt.Errorf("%w: route_stop_id requires a position", errs.ErrInvalidStops)
+		}
+	}
+	placed, _ := insertStops(route, stops, owner)
+	return placed, nil
+}
+
+// insertStops inserts own (one or two stops, in travel order) into the
+// route's stops at the cheapest positions in straight-line distance. It
+// returns the full stop order, with the route's stops referenced by
+// route_stop_id, and the positions given to own.
+func insertStops(route *domain.Route, own []domain.ApplicationStopInput, owner string) ([]domain.ApplicationStopInput, []uint) {
+	var kept []domain.Stop
+	for _, st := range route.Stops {
+		if st.ParticipantID == nil || *st.ParticipantID != owner {
+			kept = append(kept, st)
+		}
+	}
+	path := make([]domain.LatLng, 0, len(kept)+2)
+	path = append(path, domain.LatLng{Lat: route.StartLat, Lng: route.StartLng})
+	for _, st := range kept {
+		path = append(path, domain.LatLng{Lat: st.Lat, Lng: st.Lng})
+	}
+	path = append(path, domain.LatLng{Lat: route.EndLat, Lng: route.EndLng})
+
+	// gaps[k] is the gap (between path[g] and path[g+1]) own[k] goes into.
+	gaps := make([]int, len(own))
+	best := math.Inf(1)
+	for i := 0; len(own) > 0 && i < len(path)-1; i++ {
+		if len(own) == 1 {
+			if c := insertionCost(path[i], path[i+1], own[0]); c < best {
+				best, gaps[0] = c, i
+			}
+			continue
+		}
+		for j := i; j < len(path)-1; j++ {
+			var c float64
+			if i == j {
+				c = dist(path[i], stopPoint(own[0])) + dist(stopPoint(own[0]), stopPoint(own[1])) +
+					dist(stopPoint(own[1]), path[i+1]) - dist(path[i], path[i+1])
+			} else {
+				c = insertionCost(path[i], path[i+1], own[0]) + insertionCost(path[j], path[j+1], own[1])
+			}
+			if c < best {
+				best, gaps[0], gaps[1] = c, i, j
+			}
+		}
+	}
+
+	out := make([]domain.ApplicationStopInput, 0, len(kept)+len(own))
+	positions := make([]uint, len(own))
+	k := 0
+	for g := 0; g <= len(kept); g++ {
+		if g > 0 {
+			st := kept[g-1]
+			id := st.ID.String()
+			out = append(out, domain.ApplicationStopInput{
+				Lat: st.Lat, Lng: st.Lng, PlaceID: st.PlaceID, FormattedAddress: st.FormattedAddress, RouteStopID: &id,
+			})
+		}
+		for ; k < len(own) && gaps[k] == g; k++ {
+			st := own[k]
+			st.RouteStopID = nil
+			positions[k] = uint(len(out))
+			out = append(out, st)
+		}
+	}
+	for i := range out {
+		pos := uint(i)
+		out[i].Position = &pos
+	}
+	return out, positions
+}
+
+// currentStops returns stops, an application's full stop order, ready to
+// put on the route. While they reference every route stop not owned by owner,
+// in the route's order, they are returned as they are. Otherwise the route
+// changed since the applicant ordered them: their own stops are inserted
+// afresh among the current ones, or, when there are more than a pickup and a
+// dropoff to place, errs.ErrConflict is returned.
+func currentStops(route *domain.Route, stops []domain.ApplicationStop, owner string) ([]domain.ApplicationStopInput, error) {
+	var kept []string
+	owned := make(map[string]bool)
+	for _, st := range route.Stops {
+		if st.ParticipantID != nil && *st.ParticipantID == owner {
+			owned[st.ID.String()] = true
+		} else {
+			kept = append(kept, st.ID.String())
+		}
+	}
+
+	var refs []string
+	var own []domain.ApplicationStopInput
+	out := make([]domain.ApplicationStopInput, len(stops))
+	for i, st := range stops {
+		pos := st.Position
+		out[i] = domain.ApplicationStopInput{
+			Position: &pos, Lat: st.Lat, Lng: st.Lng, PlaceID: st.PlaceID, FormattedAddress: st.FormattedAddress, RouteStopID: st.RouteStopID,
+		}
+		if st.RouteStopID != nil && !owned[*st.RouteStopID] {
+			refs = append(refs, *st.RouteStopID)
+		} else {
+			o := out[i]
+			o.Position, o.RouteStopID = nil, nil
+			own = append(own, o)
+		}
+	}
+	if slices.Equal(refs, kept) {
+		return out, nil
+	}
+	if len(own) > 2 {
+		return nil, errs.ErrConflict
+	}
+	placed, _ := insertStops(route, own, owner)
+	return placed, nil
+}
+
+// insertionCost is the extra distance of visiting st between a and b.
+func insertionCost(a, b domain.LatLng, st domain.ApplicationStopInput) float64 {
+	p := stopPoint(st)
+	return dist(a, p) + dist(p, b) - dist(a, b)
+}
+
+func stopPoint(st domain.ApplicationStopInput) domain.LatLng {
+	return domain.LatLng{Lat: st.Lat, Lng: st.Lng}
+}
+
+func dist(a, b domain.LatLng) float64 {
+	return geo.Haversine(a.Lat, a.Lng, b.Lat, b.Lng)
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/geo"
+)
+
+const (
+	defaultTransferKm   = 1.0
+	maxTransferKm       = 5.0
+	defaultTransferWait = time.Hour
+	maxTransferWait     = 6 * time.Hour
+
+	// defaultAverageSpeedKmh times legs when no usable speed is configured.
+	defaultAverageSpeedKmh = 60.0
+)
+
+// JourneyService plans journeys that may change routes once along the way.
+type JourneyService struct {
+	routes   domain.RouteRepository
+	reviews  domain.ReviewRepository
+	router   domain.Router
+	speedKmh float64
+}
+
+// NewJourneyService creates a JourneyService that matches legs the way route
+// search does. reviews rate the legs' drivers; router is optional, without it
+// detours are measured in straight lines. Where a route has no stored ETAs,
+// its times are estimated by driving between its stops at speedKmh.
+func NewJourneyService(routes domain.RouteRepository, reviews domain.ReviewRepository, router domain.Router, speedKmh float64) *JourneyService {
+	if speedKmh <= 0 {
+		speedKmh = defaultAverageSpeedKmh
+	}
+	return &JourneyService{routes: routes, reviews: reviews, router: router, speedKmh: speedKmh}
+}
+
+// journeyLeg is a route matched as a journey or one of its legs.
+type journeyLeg struct {
+	route     domain.Route
+	deviation float64
+	// times holds when a dated route is expected at each of its routePoints.
+	times []time.Time
+}
+
+// journeyTransfer is where a passenger changes routes: off the first leg at
+// its route point from, onto the second at its route point to.
+type journeyTransfer struct {
+	from, to int
+	km       float64
+	wait     time.Duration
+}
+
+// Search returns up to in.Limit itineraries from start to end: routes that
+// cover the whole way, then pairs of routes where the passenger transfers
+// from one of the first's stops or its end to the start or one of the stops
+// of the second. Within each group, itineraries are ranked by deviation plus
+// transfer distance, then by wait.
+func (s *JourneyService) Search(ctx context.Context, in domain.JourneySearchInput) ([]domain.Journey, error) {
+	transferKm, transferWait, err := validateJourneySearch(in)
+	if err != nil {
+		return nil, err
+	}
+	limit := in.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+
+	direct := domain.SearchRouteInput{
+		StartLat: in.StartLat, StartLng: in.StartLng, EndLat: in.EndLat, EndLng: in.EndLng,
+		LeavingAfter: in.LeavingAfter, LeavingBefore: in.LeavingBefore,
+		MaxPrice: in.MaxPrice, MinSeats: in.MinSeats,
+	}
+	all, err := s.routes.ListSearchable(ctx, direct)
+	if err != nil {
+		return nil, err
+	}
+	// Routing calls share one time budget per search; past it, legs keep
+	// their straight-line measurements.
+	roadCtx, cancel := context.WithTimeout(ctx, roadMatchBudget)
+	defer cancel()
+
+	var checks []searchCandidate
+	km := tripKm(roadCtx, s.router, direct)
+	for i := range all {
+		// Search lists full routes for their waitlist; journeys only use
+		// routes with a free seat.
+		if all[i].AvailablePassengers == 0 {
+			continue
+		}
+		m, ok := matchSearch(s.router, &all[i], direct)
+		if !ok {
+			continue
+		}
+		estimateFare(&all[i], km)
+		if in.MaxPrice != nil && routePrice(&all[i]) > *in.MaxPrice {
+			continue
+		}
+		checks = append(checks, searchCandidate{route: &all[i], search: direct, match: m})
+	}
+	journeys := []domain.Journey{}
+	covered := make(map[uuid.UUID]bool)
+	for _, leg := range s.measureLegs(roadCtx, checks) {
+		covered[leg.route.ID] = true
+		journeys = append(journeys, domain.Journey{
+			Legs:      []domain.Route{leg.route},
+			Deviation: leg.deviation,
+			Price:     routePrice(&leg.route),
+		})
+	}
+
+	// Routes that already cover the whole way are not split into transfers.
+	checks, err = s.firstLegs(ctx, roadCtx, in, covered)
+	if err != nil {
+		return nil, err
+	}
+	firsts := s.measureLegs(roadCtx, checks)
+	var seconds []journeyLeg
+	if len(firsts) > 0 {
+		// No first leg gets the passenger anywhere after its own arrival, so
+		// second legs leave by the latest arrival plus the longest wait.
+		latest := firsts[0].times[len(firsts[0].times)-1]
+		for _, f := range firsts[1:] {
+			if arrival := f.times[len(f.times)-1]; arrival.After(latest) {
+				latest = arrival
+			}
+		}
+		latest = latest.Add(transferWait)
+		if checks, err = s.secondLegs(ctx, roadCtx, in, &latest, covered); err != nil {
+			return nil, err
+		}
+		seconds = s.measureLegs(roadCtx, checks)
+	}
+	for _, f := range firsts {
+		for _, sc := range seconds {
+			if f.route.ID == sc.route.ID {
+				continue
+			}
+			t, ok := findTransfer(f, sc, transferKm, transferWait)
+			if !ok {
+				continue
+			}
+			first, second := f.route, sc.route
+			shortenFare(ctx, &first, 0, t.from)
+			shortenFare(ctx, &second, t.to, len(sc.times)-1)
+			price := routePrice(&first) + routePrice(&second)
+			if in.MaxPrice != nil && price > *in.MaxPrice {
+				continue
+			}
+			from, to := routePoints(&first)[t.from], routePoints(&second)[t.to]
+			walkKm := t.km
+			waitMin := int(t.wait.Round(time.Minute) / time.Minute)
+			journeys = append(journeys, domain.Journey{
+				Legs:            []domain.Route{first, second},
+				Deviation:       f.deviation + sc.deviation,
+				Price:           price,
+				TransferFrom:    &from,
+				TransferTo:      &to,
+				TransferKm:      &walkKm,
+				TransferWaitMin: &waitMin,
+			})
+		}
+	}
+
+	sort.SliceStable(journeys, func(i, j int) bool {
+		return journeyLess(journeys[i], journeys[j])
+	})
+	if len(journeys) > limit {
+		journeys = journeys[:limit]
+	}
+
+	var legs []*domain.Route
+	for i := range journeys {
+		for j := range journeys[i].Legs {
+			legs = append(legs, &journeys[i].Legs[j])
+		}
+	}
+	addCreatorRatings(ctx, s.reviews, legs)
+	return journeys, nil
+}
+
+// measureLegs measures candidate legs on the road network and returns those
+// that fit their route's max_deviation, timed when they are dated.
+func (s *JourneyService) measureLegs(ctx context.Context, candidates []searchCandidate) []journeyLeg {
+	var legs []journeyLeg
+	for i, dev := range roadDeviations(ctx, s.router, candidates) {
+		r := candidates[i].route
+		if dev > r.MaxDeviation {
+			continue
+		}
+		leg := journeyLeg{route: *r, deviation: dev}
+		if r.LeavingAt != nil {
+			leg.times = s.pointTimes(r)
+		}
+		legs = append(legs, leg)
+	}
+	return legs
+}
+
+// firstLegs returns the dated routes departing within the search window that
+// may pick the passenger up at the start. Fares are estimated within roadCtx.
+func (s *JourneyService) firstLegs(ctx, roadCtx context.Context, in domain.JourneySearchInput, skip map[uuid.UUID]bool) ([]searchCandidate, error) {
+	start := domain.LatLng{Lat: in.StartLat, Lng: in.StartLng}
+	candidates, err := s.routes.ListSearchable(ctx, pointSearch(start, in.LeavingAfter, in.LeavingBefore, in))
+	if err != nil {
+		return nil, err
+	}
+	var legs []searchCandidate
+	for i := range candidates {
+		r := &candidates[i]
+		if skip[r.ID] || r.LeavingAt == nil || r.AvailablePassengers == 0 {
+			continue
+		}
+		leg := domain.SearchRouteInput{StartLat: start.Lat, StartLng: start.Lng, EndLat: r.EndLat, EndLng: r.EndLng}
+		m, ok := matchSearch(s.router, r, leg)
+		if !ok {
+			continue
+		}
+		estimateFare(r, tripKm(roadCtx, s.router, leg))
+		legs = append(legs, searchCandidate{route: r, search: leg, match: m})
+	}
+	return legs, nil
+}
+
+// secondLegs returns the dated routes departing after the search window opens
+// and no later than before that may drop the passenger off at the end. Fares
+// are estimated within roadCtx.
+func (s *JourneyService) secondLegs(ctx, roadCtx context.Context, in domain.JourneySearchInput, before *time.Time, skip map[uuid.UUID]bool) ([]searchCandidate, error) {
+	end := domain.LatLng{Lat: in.EndLat, Lng: in.EndLng}
+	candidates, err := s.routes.ListSearchable(ctx, pointSearch(end, in.LeavingAfter, before, in))
+	if err != nil {
+		return nil, err
+	}
+	var legs []searchCandidate
+	for i := range candidates {
+		r := &candidates[i]
+		if skip[r.ID] || r.LeavingAt == nil || r.AvailablePassengers == 0 {
+			continue
+		}
+		leg := domain.SearchRouteInput{StartLat: r.StartLat, StartLng: r.StartLng, EndLat: end.Lat, EndLng: end.Lng}
+		m, ok := matchSearch(s.router, r, leg)
+		if !ok {
+			continue
+		}
+		estimateFare(r, tripKm(roadCtx, s.router, leg))
+		legs = append(legs, searchCandidate{route: r, search: leg, match: m})
+	}
+	return legs, nil
+}
+
+// findTransfer finds where a passenger can change from f to sc: from one of
+// f's stops or its end onto sc at its start or one of its stops, within maxKm
+// and a wait of at most maxWait. It prefers the shortest walk, then the
+// shortest wait.
+func findTransfer(f, sc journeyLeg, maxKm float64, maxWait time.Duration) (journeyTransfer, bool) {
+	from, to := routePoints(&f.route), routePoints(&sc.route)
+	var best journeyTransfer
+	found := false
+	for i := 1; i < len(from); i++ {
+		for j := 0; j < len(to)-1; j++ {
+			km := geo.Haversine(from[i].Lat, from[i].Lng, to[j].Lat, to[j].Lng)
+			if km > maxKm {
+				continue
+			}
+			wait := sc.times[j].Sub(f.times[i])
+			if wait < 0 || wait > maxWait {
+				continue
+			}
+			if !found || km < best.km || (km == best.km && wait < best.wait) {
+				best, found = journeyTransfer{from: i, to: j, km: km, wait: wait}, true
+			}
+		}
+	}
+	return best, found
+}
+
+// shortenFare scales the estimated fare of a per-km route down to the part of
+// it the passenger rides, between its route points from and to, by that
+// part's share of the route's length through its points.
+func shortenFare(ctx context.Context, r *domain.Route, from, to int) {
+	if r.EstimatedPrice == nil {
+		return
+	}
+	points := routePoints(r)
+	total := pathKm(ctx, nil, points)
+	if total == 0 || (from == 0 && to == len(points)-1) {
+		return
+	}
+	fare := math.Round(*r.EstimatedPrice*pathKm(ctx, nil, points[from:to+1])/total*100) / 100
+	r.EstimatedPrice = &fare
+}
+
+// pointSearch is a route search around a single point: its search area test
+// selects every route whose corridor covers p.
+func pointSearch(p domain.LatLng, after, before *time.Time, in domain.JourneySearchInput) domain.SearchRouteInput {
+	return domain.SearchRouteInput{
+		StartLat: p.Lat, StartLng: p.Lng, EndLat: p.Lat, EndLng: p.Lng,
+		LeavingAfter: after, LeavingBefore: before,
+		MaxPrice: in.MaxPrice, MinSeats: in.MinSeats,
+	}
+}
+
+// pointTimes returns when the dated route r is expected at each of its
+// routePoints: its departure, then the stored ETAs of its stops and its end.
+// Those not stored yet are estimated by driving in straight lines from the
+// previous point at the average speed.
+func (s *JourneyService) pointTimes(r *domain.Route) []time.Time {
+	points := routePoints(r)
+	times := make([]time.Time, len(points))
+	times[0] = *r.LeavingAt
+	for i := 1; i < len(points); i++ {
+		eta := r.ArrivalETA
+		if i < len(points)-1 {
+			eta = r.Stops[i-1].ETA
+		}
+		if eta != nil {
+			times[i] = *eta
+			continue
+		}
+		km := geo.Haversine(points[i-1].Lat, points[i-1].Lng, points[i].Lat, points[i].Lng)
+		times[i] = times[i-1].Add(time.Duration(km / s.speedKmh * float64(time.Hour)))
+	}
+	return times
+}
+
+// pathLengthKm returns the length of the route's stored path, or of the
+// straight lines through its stops when it has none.
+func pathLengthKm(r *domain.Route) float64 {
+	segments := pathSegments(r)
+	if segments == nil {
+		segments = buildSegmentsFromStops(r.StartLat, r.StartLng, r.EndLat, r.EndLng, r.Stops)
+	}
+	km := 0.0
+	for _, seg := range segments {
+		km += geo.Haversine(seg.aLat, seg.aLng, seg.bLat, seg.bLng)
+	}
+	return km
+}
+
+// routePrice is what a seat on r costs: the fare estimateFare set on a
+// per-km route, or its flat price.
+func routePrice(r *domain.Route) float64 {
+	switch {
+	case r.EstimatedPrice != nil:
+		return *r.EstimatedPrice
+	case r.Price != nil:
+		return *r.Price
+	}
+	return 0
+}
+
+// journeyLess orders direct journeys first, then by deviation plus transfer
+// distance, then by wait, with the leg IDs as a tie-breaker.
+func journeyLess(a, b domain.Journey) bool {
+	if len(a.Legs) != len(b.Legs) {
+		return len(a.Legs) < len(b.Legs)
+	}
+	sa, sb := a.Deviation, b.Deviation
+	if a.TransferKm != nil {
+		sa += *a.TransferKm
+	}
+	if b.TransferKm != nil {
+		sb += *b.TransferKm
+	}
+	if sa != sb {
+		return sa < sb
+	}
+	if a.TransferWaitMin != nil && b.TransferWaitMin != nil && *a.TransferWaitMin != *b.TransferWaitMin {
+		return *a.TransferWaitMin < *b.TransferWaitMin
+	}
+	for i := range a.Legs {
+		if a.Legs[i].ID != b.Legs[i].ID {
+			return a.Legs[i].ID.String() < b.Legs[i].ID.String()
+		}
+	}
+	return false
+}
+
+// validateJourneySearch checks the filters and returns the transfer limits,
+// with defaults applied.
+func validateJourneySearch(in domain.JourneySearchInput) (float64, time.Duration, error) {
+	if err := validateSearch(domain.SearchRouteInput{
+		LeavingAfter: in.LeavingAfter, LeavingBefore: in.LeavingBefore,
+		MaxPrice: in.MaxPrice, MinSeats: in.MinSeats, Limit: in.Limit,
+	}); err != nil {
+		return 0, 0, err
+	}
+	km := defaultTransferKm
+	if in.MaxTransferKm != nil {
+		km = *in.MaxTransferKm
+		if km < 0 || km > maxTransferKm {
+			return 0, 0, fmt.Errorf("%w: max_transfer_km must be between 0 and %g", errs.ErrInvalidSearch, maxTransferKm)
+		}
+	}
+	wait := defaultTransferWait
+	if in.MaxWaitMin != nil {
+		wait = time.Duration(*in.MaxWaitMin) * time.Minute
+		if wait < 0 || wait > maxTransferWait {
+			return 0, 0, fmt.Errorf("%w: max_wait_min must be between 0 and %d", errs.ErrInvalidSearch, int(maxTransferWait/time.Minute))
+		}
+	}
+	return km, wait, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+)
+
+func TestJourneyService_Search(t *testing.T) {
+	leaving := time.Now().Add(24 * time.Hour)
+	at := func(d time.Duration) *time.Time { ts := leaving.Add(d); return &ts }
+	route := func(startLng, endLng float64, leavingAt *time.Time) domain.Route {
+		return domain.Route{
+			ID: uuid.New(), CreatorID: uuid.New(),
+			StartLat: 0, StartLng: startLng, EndLat: 0, EndLng: endLng,
+			MaxDeviation: 5, MaxPassengers: 3, AvailablePassengers: 3,
+			LeavingAt: leavingAt,
+		}
+	}
+	direct := route(0, 2, at(0))
+	// full is only listed for its waitlist.
+	full := route(0, 2, at(0))
+	full.AvailablePassengers = 0
+	first := route(0, 1, at(0))
+	// through passes the transfer point at a stop on its way further on.
+	through := route(0, 3, at(0))
+	through.Stops = []domain.Stop{{ID: uuid.New(), Lat: 0, Lng: 1}}
+	// first takes ~1h51m at 60 km/h, so second leaves ~9 minutes after it arrives
+	// and late ~3 hours after.
+	second := route(1.005, 2, at(2*time.Hour))
+	late := route(1.005, 2, at(5*time.Hour))
+	undated := route(1.005, 2, nil)
+
+	var secondsBefore *time.Time
+	repo := &mockRouteRepo{
+		listSearchable: func(_ context.Context, in domain.SearchRouteInput) ([]domain.Route, error) {
+			if in.StartLng == 2 && in.EndLng == 2 {
+				secondsBefore = in.LeavingBefore
+			}
+			return []domain.Route{direct, full, first, through, second, late, undated}, nil
+		},
+	}
+	reviews := &mockReviewRepo{
+		getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
+			return map[uuid.UUID]domain.ReviewSummary{}, nil
+		},
+	}
+	svc := NewJourneyService(repo, reviews, nil, 60)
+
+	journeys, err := svc.Search(context.Background(), domain.JourneySearchInput{
+		StartLat: 0, StartLng: 0, EndLat: 0, EndLng: 2,
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(journeys) != 3 {
+		t.Fatalf("Search() returned %d journeys, want 3", len(journeys))
+	}
+	if len(journeys[0].Legs) != 1 || journeys[0].Legs[0].ID != direct.ID {
+		t.Errorf("first journey should be the direct route")
+	}
+	firstLegs := make(map[uuid.UUID]domain.Journey)
+	for _, transfer := range journeys[1:] {
+		if len(transfer.Legs) != 2 || transfer.Legs[1].ID != second.ID {
+			t.Fatalf("transfers should continue on second, got %d legs", len(transfer.Legs))
+		}
+		if transfer.TransferKm == nil || *transfer.TransferKm > 1 {
+			t.Errorf("TransferKm = %v, want about 0.56", transfer.TransferKm)
+		}
+		if transfer.TransferWaitMin == nil || *transfer.TransferWaitMin < 5 || *transfer.TransferWaitMin > 15 {
+			t.Errorf("TransferWaitMin = %v, want about 9", transfer.TransferWaitMin)
+		}
+		if transfer.TransferFrom == nil || transfer.TransferFrom.Lng != 1 {
+			t.Errorf("TransferFrom = %v, want the point at lng 1", transfer.TransferFrom)
+		}
+		firstLegs[transfer.Legs[0].ID] = transfer
+	}
+	if _, ok := firstLegs[first.ID]; !ok {
+		t.Error("a transfer should start on first, which ends at the transfer point")
+	}
+	if _, ok := firstLegs[through.ID]; !ok {
+		t.Error("a transfer should start on through, which stops at the transfer point")
+	}
+	// Second legs must leave by the latest first-leg arrival (through, after
+	// ~5h33m) plus the default wait.
+	if secondsBefore == nil || secondsBefore.Before(leaving.Add(5*time.Hour)) || secondsBefore.After(leaving.Add(7*time.Hour)) {
+		t.Errorf("second legs searched up to %v, want about 6h33m after departure", secondsBefore)
+	}
+}
+
+func TestValidateJourneySearch(t *testing.T) {
+	tooFar, negativeWait := 10.0, -1
+	tests := []struct {
+		name string
+		in   domain.JourneySearchInput
+	}{
+		{"transfer too far", domain.JourneySearchInput{MaxTransferKm: &tooFar}},
+		{"negative wait", domain.JourneySearchInput{MaxWaitMin: &negativeWait}},
+		{"limit too large", domain.JourneySearchInput{Limit: maxSearchLimit + 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := validateJourneySearch(tt.in); !errors.Is(err, errs.ErrInvalidSearch) {
+				t.Errorf("validateJourneySearch() = %v, want ErrInvalidSearch", err)
+			}
+		})
+	}
+	km, wait, err := validateJourneySearch(domain.JourneySearchInput{})
+	if err != nil || km != defaultTransferKm || wait != defaultTransferWait {
+		t.Errorf("validateJourneySearch(defaults) = %v, %v, %v", km, wait, err)
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+)
+
+// RankWeights weighs the factors of a search result's score. Each factor is
+// first scored from 0 (worst) to 1 (best), so a weight is the most that
+// factor can add to the total. Factors are scored against fixed scales rather
+// than the other matches or live bookings, so a route keeps its score, and
+// its place in paginated results, while other routes come and go.
+type RankWeights struct {
+	Deviation float64 // small detour relative to the route's max_deviation
+	Departure float64 // departure close to the requested time
+	Price     float64 // cheap relative to max_price or priceScale
+	Rating    float64 // well-rated driver, trusting more reviews more
+	Seats     float64 // many passenger seats offered, up to seatsScale
+}
+
+// DefaultRankWeights ranks mostly by detour.
+var DefaultRankWeights = RankWeights{Deviation: 0.5, Departure: 0.2, Price: 0.1, Rating: 0.15, Seats: 0.05}
+
+const (
+	// departureHorizon is how far from the requested time a departure scores 0.
+	departureHorizon = 12 * time.Hour
+
+	// priceScale is the price that scores 0 when the search sets no max_price.
+	priceScale = 50.0
+
+	// seatsScale is the number of passenger seats that scores 1.
+	seatsScale = 4.0
+
+	// Ratings are averaged with ratingPriorCount reviews of ratingPrior, so
+	// drivers with few reviews rank near the middle.
+	ratingPrior      = 3.0
+	ratingPriorCount = 5.0
+)
+
+// ranker scores the routes matched by one search.
+type ranker struct {
+	weights  RankWeights
+	target   *time.Time // requested departure; nil when the search sets no window
+	maxPrice float64    // price that scores 0; 0 when only free routes match
+	ratings  map[uuid.UUID]domain.ReviewSummary
+}
+
+func newRanker(weights RankWeights, in domain.SearchRouteInput, ratings map[uuid.UUID]domain.ReviewSummary) ranker {
+	rk := ranker{weights: weights, target: requestedDeparture(in), maxPrice: priceScale, ratings: ratings}
+	if in.MaxPrice != nil {
+		rk.maxPrice = *in.MaxPrice
+	}
+	return rk
+}
+
+// requestedDeparture is the middle of the search's departure window, or its
+// only bound when just one is set.
+func requestedDeparture(in domain.SearchRouteInput) *time.Time {
+	switch {
+	case in.LeavingAfter != nil && in.LeavingBefore != nil:
+		mid := in.LeavingAfter.Add(in.LeavingBefore.Sub(*in.LeavingAfter) / 2)
+		return &mid
+	case in.LeavingAfter != nil:
+		return in.LeavingAfter
+	default:
+		return in.LeavingBefore
+	}
+}
+
+// score returns the weighted score of r matched at the given deviation.
+func (rk ranker) score(r *domain.Route, deviation float64) domain.RouteScore {
+	s := domain.RouteScore{
+		Deviation: rk.weights.Deviation * rk.deviationFactor(r, deviation),
+		Departure: rk.weights.Departure * rk.departureFactor(r),
+		Price:     rk.weights.Price * rk.priceFactor(r),
+		Rating:    rk.weights.Rating * rk.ratingFactor(r),
+		Seats:     rk.weights.Seats * seatsFactor(r),
+	}
+	s.Total = s.Deviation + s.Departure + s.Price + s.Rating + s.Seats
+	return s
+}
+
+func (rk ranker) deviationFactor(r *domain.Route, deviation float64) float64 {
+	if r.MaxDeviation <= 0 {
+		return 1
+	}
+	return clamp01(1 - deviation/r.MaxDeviation)
+}
+
+func (rk ranker) departureFactor(r *domain.Route) float64 {
+	if rk.target == nil || r.LeavingAt == nil {
+		return 0
+	}
+	diff := r.LeavingAt.Sub(*rk.target).Abs()
+	return clamp01(1 - float64(diff)/float64(departureHorizon))
+}
+
+func (rk ranker) priceFactor(r *domain.Route) float64 {
+	if rk.maxPrice == 0 {
+		return 1
+	}
+	return clamp01(1 - routePrice(r)/rk.maxPrice)
+}
+
+func (rk ranker) ratingFactor(r *domain.Route) float64 {
+	summary := rk.ratings[r.CreatorID]
+	n := float64(summary.Count)
+	avg := (summary.Avg*n + ratingPrior*ratingPriorCount) / (n + ratingPriorCount)
+	return clamp01((avg - 1) / 4)
+}
+
+func seatsFactor(r *domain.Route) float64 {
+	return clamp01(float64(r.MaxPassengers) / seatsScale)
+}
+
+func clamp01(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
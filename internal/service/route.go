@@ -2,24 +2,38 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmartynas/pss-backend/internal/domain"
 	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/export"
+	"github.com/jmartynas/pss-backend/internal/geo"
 )
 
 // RouteService contains all route business logic.
 type RouteService struct {
-	routes  domain.RouteRepository
-	reviews domain.ReviewRepository
+	routes   domain.RouteRepository
+	reviews  domain.ReviewRepository
+	vehicles domain.VehicleRepository
+	chats    domain.ChatRepository
+	router   domain.Router
+	weights  RankWeights
+	etas     *ETAEstimator
+	fares    *FareCalculator
 }
 
-// NewRouteService creates a RouteService backed by the given repository.
-func NewRouteService(routes domain.RouteRepository, reviews domain.ReviewRepository) *RouteService {
-	return &RouteService{routes: routes, reviews: reviews}
+// NewRouteService creates a RouteService backed by the given repositories.
+// router is optional; without it search measures deviation in straight lines.
+// weights rank search results; etas and fares, when non-nil, keep stop ETAs
+// and passengers' price shares current.
+func NewRouteService(routes domain.RouteRepository, reviews domain.ReviewRepository, vehicles domain.VehicleRepository, chats domain.ChatRepository, router domain.Router, weights RankWeights, etas *ETAEstimator, fares *FareCalculator) *RouteService {
+	return &RouteService{routes: routes, reviews: reviews, vehicles: vehicles, chats: chats, router: router, weights: weights, etas: etas, fares: fares}
 }
 
 func (s *RouteService) GetByID(ctx context.Context, id uuid.UUID) (*domain.Route, error) {
@@ -27,7 +41,133 @@ func (s *RouteService) GetByID(ctx context.Context, id uuid.UUID) (*domain.Route
 }
 
 func (s *RouteService) Create(ctx context.Context, creatorID uuid.UUID, in domain.CreateRouteInput) (uuid.UUID, error) {
-	return s.routes.Create(ctx, creatorID, in)
+	if in.Polyline != nil && *in.Polyline == "" {
+		in.Polyline = nil
+	}
+	if in.Polyline != nil {
+		start := domain.LatLng{Lat: in.StartLat, Lng: in.StartLng}
+		end := domain.LatLng{Lat: in.EndLat, Lng: in.EndLng}
+		if err := validatePolyline(*in.Polyline, start, end); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	if err := validatePricing(in.PricingMode, in.PricePerKm); err != nil {
+		return uuid.Nil, err
+	}
+	if err := validateAutoApprove(in.AutoApproveMinRating); err != nil {
+		return uuid.Nil, err
+	}
+	if err := checkVehicle(ctx, s.vehicles, creatorID, in); err != nil {
+		return uuid.Nil, err
+	}
+	if in.Return != nil {
+		if err := validateReturnLeg(&in); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	id, err := s.routes.Create(ctx, creatorID, in)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	s.etas.Created(ctx, id)
+	return id, nil
+}
+
+// checkCapacity checks that a new max_passengers for route fits in the
+// route's vehicle. The repository checks it against the seats approved
+// passengers booked, under the route's lock.
+func (s *RouteService) checkCapacity(ctx context.Context, route *domain.Route, maxPassengers uint) error {
+	if route.VehicleID == nil {
+		return nil
+	}
+	v, err := s.vehicles.GetByID(ctx, *route.VehicleID)
+	if errors.Is(err, errs.ErrNotFound) {
+		// The vehicle was deleted after the route was created.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return checkVehicleSeats(v, maxPassengers)
+}
+
+// checkVehicle checks the vehicle of a new route, if it names one: it must
+// belong to creatorID and seat the route's passengers.
+func checkVehicle(ctx context.Context, vehicles domain.VehicleRepository, creatorID uuid.UUID, in domain.CreateRouteInput) error {
+	if in.VehicleID == nil {
+		return nil
+	}
+	v, err := vehicles.GetByID(ctx, *in.VehicleID)
+	if errors.Is(err, errs.ErrNotFound) || (err == nil && v.UserID != creatorID) {
+		return errs.ErrVehicleNotOwned
+	}
+	if err != nil {
+		return err
+	}
+	return checkVehicleSeats(v, in.MaxPassengers)
+}
+
+// checkVehicleSeats checks that maxPassengers fit in v next to the driver.
+func checkVehicleSeats(v *domain.Vehicle, maxPassengers uint) error {
+	if v.Seats < 1 || maxPassengers > v.Seats-1 {
+		return fmt.Errorf("%w: the vehicle seats %d passengers", errs.ErrExceedsVehicle, max(int(v.Seats)-1, 0))
+	}
+	return nil
+}
+
+// validatePricing checks a route's pricing: the mode must be known, and a
+// per-km rate is required by per-km pricing and rejected by flat pricing.
+func validatePricing(mode string, perKm *float64) error {
+	switch mode {
+	case "", domain.PricingFlat:
+		if perKm != nil {
+			return fmt.Errorf("%w: price_per_km requires pricing_mode %q", errs.ErrInvalidPricing, domain.PricingPerKm)
+		}
+	case domain.PricingPerKm:
+		if perKm == nil || *perKm <= 0 {
+			return fmt.Errorf("%w: price_per_km must be positive", errs.ErrInvalidPricing)
+		}
+	default:
+		return fmt.Errorf("%w: unknown pricing_mode %q", errs.ErrInvalidPricing, mode)
+	}
+	return nil
+}
+
+// validateAutoApprove checks that a minimum rating for auto-approval is on
+// the 1–5 review scale, or zero for none.
+func validateAutoApprove(minRating *float64) error {
+	if minRating != nil && (*minRating < 0 || *minRating > 5) {
+		return fmt.Errorf("%w: auto_approve_min_rating must be between 0 and 5", errs.ErrAutoApproveRule)
+	}
+	return nil
+}
+
+// validateReturnLeg checks a route's return leg: it must depart after the
+// outbound route, and its polyline must run from the route's end to its start.
+// An empty return polyline is cleared.
+func validateReturnLeg(in *domain.CreateRouteInput) error {
+	ret := *in.Return
+	if ret.LeavingAt == nil {
+		return fmt.Errorf("%w: leaving_at is required", errs.ErrInvalidReturnLeg)
+	}
+	if in.LeavingAt != nil && !ret.LeavingAt.After(*in.LeavingAt) {
+		return fmt.Errorf("%w: must leave after the outbound route", errs.ErrInvalidReturnLeg)
+	}
+	if in.LeavingAt == nil && !ret.LeavingAt.After(time.Now()) {
+		return fmt.Errorf("%w: departure time must be in the future", errs.ErrInvalidReturnLeg)
+	}
+	if ret.Polyline != nil && *ret.Polyline == "" {
+		ret.Polyline = nil
+	}
+	if ret.Polyline != nil {
+		start := domain.LatLng{Lat: in.EndLat, Lng: in.EndLng}
+		end := domain.LatLng{Lat: in.StartLat, Lng: in.StartLng}
+		if err := validatePolyline(*ret.Polyline, start, end); err != nil {
+			return err
+		}
+	}
+	in.Return = &ret
+	return nil
 }
 
 func (s *RouteService) Update(ctx context.Context, id, creatorID uuid.UUID, in domain.UpdateRouteInput) error {
@@ -35,10 +175,115 @@ func (s *RouteService) Update(ctx context.Context, id, creatorID uuid.UUID, in d
 	if err != nil {
 		return err
 	}
+	if route.CreatorID != creatorID {
+		return errs.ErrForbidden
+	}
 	if routeStarted(route) {
 		return errs.ErrRouteStarted
 	}
-	return s.routes.Update(ctx, id, creatorID, in)
+	if in.LeavingAt != nil {
+		if err := s.checkLinkedLeg(ctx, route, *in.LeavingAt); err != nil {
+			return err
+		}
+	}
+	if in.MaxPassengers != nil {
+		if err := s.checkCapacity(ctx, route, *in.MaxPassengers); err != nil {
+			return err
+		}
+	}
+	if in.Polyline != nil && *in.Polyline != "" {
+		start := domain.LatLng{Lat: route.StartLat, Lng: route.StartLng}
+		if in.StartLat != nil && in.StartLng != nil {
+			start = domain.LatLng{Lat: *in.StartLat, Lng: *in.StartLng}
+		}
+		end := domain.LatLng{Lat: route.EndLat, Lng: route.EndLng}
+		if in.EndLat != nil && in.EndLng != nil {
+			end = domain.LatLng{Lat: *in.EndLat, Lng: *in.EndLng}
+		}
+		if err := validatePolyline(*in.Polyline, start, end); err != nil {
+			return err
+		}
+	}
+	if in.PricingMode != nil || in.PricePerKm != nil {
+		mode, perKm := route.PricingMode, in.PricePerKm
+		if in.PricingMode != nil {
+			mode = *in.PricingMode
+		}
+		if perKm == nil && mode == route.PricingMode {
+			perKm = route.PricePerKm
+		}
+		if err := validatePricing(mode, perKm); err != nil {
+			return err
+		}
+	}
+	if err := validateAutoApprove(in.AutoApproveMinRating); err != nil {
+		return err
+	}
+	if err := s.routes.Update(ctx, id, creatorID, in); err != nil {
+		return err
+	}
+	if in.LeavingAt != nil || in.StartLat != nil || in.EndLat != nil || in.Stops != nil {
+		s.etas.Changed(ctx, id)
+	}
+	if in.StartLat != nil || in.EndLat != nil || in.Stops != nil || in.PricingMode != nil || in.PricePerKm != nil {
+		s.fares.Changed(ctx, id)
+	}
+	return nil
+}
+
+// checkLinkedLeg applies the rule of validateReturnLeg to a route moved to
+// leave at leavingAt: it must still leave before its return leg, or after its
+// outbound route.
+func (s *RouteService) checkLinkedLeg(ctx context.Context, route *domain.Route, leavingAt time.Time) error {
+	linkedID, outbound := route.ReturnRouteID, true
+	if linkedID == nil {
+		linkedID, outbound = route.ReturnOfRouteID, false
+	}
+	if linkedID == nil {
+		return nil
+	}
+	linked, err := s.routes.GetByID(ctx, *linkedID)
+	if errors.Is(err, errs.ErrNotFound) {
+		// The linked leg was cancelled.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if linked.LeavingAt == nil {
+		return nil
+	}
+	if outbound && !linked.LeavingAt.After(leavingAt) {
+		return fmt.Errorf("%w: must leave before the return leg", errs.ErrInvalidReturnLeg)
+	}
+	if !outbound && !leavingAt.After(*linked.LeavingAt) {
+		return fmt.Errorf("%w: must leave after the outbound route", errs.ErrInvalidReturnLeg)
+	}
+	return nil
+}
+
+// polylineEndpointToleranceKm is how far a route polyline may begin or end
+// from the route's start and end points, which are often geocoded addresses
+// slightly off the road.
+const polylineEndpointToleranceKm = 1.0
+
+// validatePolyline checks that polyline decodes to a path from start to end.
+func validatePolyline(polyline string, start, end domain.LatLng) error {
+	path, err := geo.DecodePolyline(polyline)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrInvalidPolyline, err)
+	}
+	if len(path) < 2 {
+		return fmt.Errorf("%w: at least two points are required", errs.ErrInvalidPolyline)
+	}
+	first, last := path[0], path[len(path)-1]
+	if geo.Haversine(first.Lat, first.Lng, start.Lat, start.Lng) > polylineEndpointToleranceKm {
+		return fmt.Errorf("%w: must begin at the route start", errs.ErrInvalidPolyline)
+	}
+	if geo.Haversine(last.Lat, last.Lng, end.Lat, end.Lng) > polylineEndpointToleranceKm {
+		return fmt.Errorf("%w: must end at the route end", errs.ErrInvalidPolyline)
+	}
+	return nil
 }
 
 func (s *RouteService) Delete(ctx context.Context, id, creatorID uuid.UUID) error {
@@ -46,6 +291,9 @@ func (s *RouteService) Delete(ctx context.Context, id, creatorID uuid.UUID) erro
 	if err != nil {
 		return err
 	}
+	if route.CreatorID != creatorID {
+		return errs.ErrForbidden
+	}
 	if routeStarted(route) {
 		return errs.ErrRouteStarted
 	}
@@ -65,6 +313,36 @@ func (s *RouteService) GetMyReviewsForRoute(ctx context.Context, routeID, author
 	return s.reviews.GetByAuthorAndRoute(ctx, authorID, routeID)
 }
 
+// Export renders a route as GeoJSON or GPX. Like the route's group chat, it
+// is open to the driver and approved passengers only; stops are labelled with
+// the approved passenger they belong to.
+func (s *RouteService) Export(ctx context.Context, routeID, callerID uuid.UUID, format string) ([]byte, error) {
+	route, err := s.routes.GetByID(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("export: load route: %w", err)
+	}
+	allowed, err := s.chats.CanAccessGroupChat(ctx, routeID, callerID)
+	if err != nil {
+		return nil, fmt.Errorf("export: check access: %w", err)
+	}
+	if !allowed {
+		return nil, errs.ErrForbidden
+	}
+	names := make(map[string]string)
+	for _, p := range route.Participants {
+		if p.Status == "approved" {
+			names[p.ID.String()] = p.Name
+		}
+	}
+	passengers := make(map[uuid.UUID]string)
+	for _, st := range route.Stops {
+		if st.ParticipantID != nil && names[*st.ParticipantID] != "" {
+			passengers[st.ID] = names[*st.ParticipantID]
+		}
+	}
+	return export.Route(route, passengers, format)
+}
+
 // CreateReview lets a route participant leave a rating for another participant
 // after the route has started. One review per (author, target, route).
 func (s *RouteService) CreateReview(ctx context.Context, routeID, authorID uuid.UUID, rating int, comment string, targetID uuid.UUID) (uuid.UUID, error) {
@@ -108,87 +386,452 @@ func (s *RouteService) CreateReview(ctx context.Context, routeID, authorID uuid.
 	})
 }
 
-// Search returns routes that match the search criteria, sorted by deviation (ascending).
-func (s *RouteService) Search(ctx context.Context, in domain.SearchRouteInput) ([]domain.Route, error) {
-	all, err := s.routes.ListSearchable(ctx)
+// Search returns one page of routes that match the search criteria, sorted by
+// score (descending) with the route ID as a tie-breaker so pages are stable.
+// Round trips rank first when the search has a return window.
+func (s *RouteService) Search(ctx context.Context, in domain.SearchRouteInput) (*domain.SearchRoutePage, error) {
+	if err := validateSearch(in); err != nil {
+		return nil, err
+	}
+	limit := in.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	var after *searchCursor
+	if in.Cursor != "" {
+		c, err := decodeSearchCursor(in.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = &c
+	}
+
+	all, err := s.routes.ListSearchable(ctx, in)
 	if err != nil {
 		return nil, err
 	}
+	var returns map[uuid.UUID]uuid.UUID
+	if in.ReturnLeavingAfter != nil || in.ReturnLeavingBefore != nil {
+		if returns, err = s.matchReturnLegs(ctx, in); err != nil {
+			return nil, err
+		}
+	}
 
-	type entry struct {
-		route domain.Route
-		dev   float64
+	// Routing calls share one time budget per search; past it, matches keep
+	// their straight-line measurements.
+	roadCtx, cancel := context.WithTimeout(ctx, roadMatchBudget)
+	defer cancel()
+
+	matched := make([]*domain.Route, 0, len(all))
+	matches := make(map[uuid.UUID]routeMatch, len(all))
+	var km float64
+	if after != nil && after.Km > 0 {
+		km = after.Km
+	} else {
+		km = tripKm(roadCtx, s.router, in)
 	}
-	candidates := make([]entry, 0, len(all))
 	for i := range all {
-		dev := calculateDeviation(&all[i], in)
-		if dev <= all[i].MaxDeviation {
-			candidates = append(candidates, entry{route: all[i], dev: dev})
+		m, ok := matchSearch(s.router, &all[i], in)
+		if !ok {
+			continue
+		}
+		estimateFare(&all[i], km)
+		if in.MaxPrice != nil && routePrice(&all[i]) > *in.MaxPrice {
+			continue
+		}
+		all[i].WaitlistOnly = all[i].AvailablePassengers == 0 || all[i].Waitlisted > 0
+		all[i].MatchedSegments = m.segments
+		matched = append(matched, &all[i])
+		matches[all[i].ID] = m
+	}
+	ratings := addCreatorRatings(ctx, s.reviews, matched)
+	rk := newRanker(s.weights, in, ratings)
+
+	// Routes are ranked by their straight-line deviation, which does not
+	// depend on the routing engine answering, so pages stay stable.
+	type entry struct {
+		route *domain.Route
+		key   searchCursor
+	}
+	candidates := make([]entry, 0, len(matched))
+	for _, r := range matched {
+		score := rk.score(r, matches[r.ID].deviation)
+		r.Score = &score
+		key := searchCursor{Score: score.Total, RouteID: r.ID, Km: km}
+		if returnID, ok := returns[r.ID]; ok {
+			key.Return = true
+			r.MatchedReturnRouteID = &returnID
 		}
+		if after != nil && !after.less(key) {
+			continue
+		}
+		candidates = append(candidates, entry{route: r, key: key})
 	}
 
 	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].dev < candidates[j].dev
+		return candidates[i].key.less(candidates[j].key)
 	})
 
-	out := make([]domain.Route, len(candidates))
-	for i, c := range candidates {
-		out[i] = c.route
+	// Only the routes about to fill the page are measured on the road
+	// network, a batch of what the page still lacks at a time. Those that
+	// turn out too far off their route drop out and the next ones move up.
+	fits := make([]entry, 0, min(len(candidates), limit+1))
+	for next := 0; next < len(candidates) && len(fits) <= limit; {
+		batch := candidates[next:min(len(candidates), next+limit+1-len(fits))]
+		next += len(batch)
+		checks := make([]searchCandidate, len(batch))
+		for i, c := range batch {
+			checks[i] = searchCandidate{route: c.route, search: in, match: matches[c.route.ID]}
+		}
+		for i, dev := range roadDeviations(roadCtx, s.router, checks) {
+			if dev <= batch[i].route.MaxDeviation {
+				fits = append(fits, batch[i])
+			}
+		}
+	}
+
+	page := &domain.SearchRoutePage{}
+	if len(fits) > limit {
+		next := fits[limit-1].key.encode()
+		page.NextCursor = &next
+		fits = fits[:limit]
 	}
 
-	// Enrich with driver ratings.
-	creatorIDs := make([]uuid.UUID, 0, len(out))
+	out := make([]domain.Route, len(fits))
+	for i, c := range fits {
+		out[i] = *c.route
+	}
+	page.Items = out
+	return page, nil
+}
+
+// addCreatorRatings enriches routes with their drivers' ratings and returns
+// the ratings by driver. Ratings are best-effort: the routes are left as they
+// are, and nil is returned, when the lookup fails.
+func addCreatorRatings(ctx context.Context, reviews domain.ReviewRepository, routes []*domain.Route) map[uuid.UUID]domain.ReviewSummary {
+	creatorIDs := make([]uuid.UUID, 0, len(routes))
 	seen := make(map[uuid.UUID]bool)
-	for _, r := range out {
+	for _, r := range routes {
 		if !seen[r.CreatorID] {
 			creatorIDs = append(creatorIDs, r.CreatorID)
 			seen[r.CreatorID] = true
 		}
 	}
-	if len(creatorIDs) > 0 {
-		ratings, err := s.reviews.GetAverageRatings(ctx, creatorIDs)
-		if err == nil {
-			for i := range out {
-				if summary, ok := ratings[out[i].CreatorID]; ok {
-					out[i].CreatorReviewCount = summary.Count
-					if summary.Count >= 5 {
-						avg := summary.Avg
-						out[i].CreatorRating = &avg
-					}
-				}
+	if len(creatorIDs) == 0 {
+		return nil
+	}
+	ratings, err := reviews.GetAverageRatings(ctx, creatorIDs)
+	if err != nil {
+		return nil
+	}
+	for _, r := range routes {
+		if summary, ok := ratings[r.CreatorID]; ok {
+			r.CreatorReviewCount = summary.Count
+			if summary.Count >= 5 {
+				avg := summary.Avg
+				r.CreatorRating = &avg
 			}
 		}
 	}
+	return ratings
+}
+
+// Road measurement of search matches: how many routes are measured at once,
+// and how long one search may spend on it.
+const (
+	roadMatchConcurrency = 8
+	roadMatchBudget      = 2 * time.Second
+)
+
+// matchSearch matches r against the search in straight lines and reports
+// whether it may serve the search within its max_deviation. Without a router
+// that is final; with one, the straight-line distance between the endpoints
+// is a lower bound and roadDeviations settles it.
+func matchSearch(router domain.Router, r *domain.Route, in domain.SearchRouteInput) (routeMatch, bool) {
+	m := matchRoute(r, in)
+	if !m.inOrder {
+		return m, false
+	}
+	if router != nil {
+		return m, endpointDistance(r, in) <= r.MaxDeviation
+	}
+	return m, m.deviation <= r.MaxDeviation
+}
+
+// searchCandidate is a route that matchSearch let through for a search.
+type searchCandidate struct {
+	route  *domain.Route
+	search domain.SearchRouteInput
+	match  routeMatch
+}
+
+// roadDeviations measures candidates on the road network, up to
+// roadMatchConcurrency at a time, and returns their deviations. Without a
+// router, and for candidates it cannot measure (for instance once ctx is
+// done), the straight-line deviation stands.
+func roadDeviations(ctx context.Context, router domain.Router, candidates []searchCandidate) []float64 {
+	devs := make([]float64, len(candidates))
+	if router == nil {
+		for i, c := range candidates {
+			devs[i] = c.match.deviation
+		}
+		return devs
+	}
+	sem := make(chan struct{}, roadMatchConcurrency)
+	var wg sync.WaitGroup
+	for i, c := range candidates {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			devs[i] = roadDeviation(ctx, router, c.route, c.search, c.match)
+		}()
+	}
+	wg.Wait()
+	return devs
+}
 
+// matchReturnLegs searches for the way back of a round-trip search and maps
+// each route linked to a matching leg (either its return leg or its outbound
+// route) to that leg's ID. Return legs are matched in straight lines: they
+// decide where outbound routes sort, before any page is cut.
+func (s *RouteService) matchReturnLegs(ctx context.Context, in domain.SearchRouteInput) (map[uuid.UUID]uuid.UUID, error) {
+	back := reverseSearch(in)
+	legs, err := s.routes.ListSearchable(ctx, back)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]uuid.UUID)
+	km := tripKm(ctx, nil, back)
+	for i := range legs {
+		if _, ok := matchSearch(nil, &legs[i], back); !ok {
+			continue
+		}
+		estimateFare(&legs[i], km)
+		if back.MaxPrice != nil && routePrice(&legs[i]) > *back.MaxPrice {
+			continue
+		}
+		if legs[i].ReturnOfRouteID != nil {
+			out[*legs[i].ReturnOfRouteID] = legs[i].ID
+		}
+		if legs[i].ReturnRouteID != nil {
+			out[*legs[i].ReturnRouteID] = legs[i].ID
+		}
+	}
 	return out, nil
 }
 
+// reverseSearch returns the way back of a round-trip search: from end to
+// start with the stops reversed, departing within the return window.
+func reverseSearch(in domain.SearchRouteInput) domain.SearchRouteInput {
+	stops := make([]domain.SearchStop, len(in.Stops))
+	for i, st := range in.Stops {
+		stops[len(stops)-1-i] = st
+	}
+	return domain.SearchRouteInput{
+		StartLat:      in.EndLat,
+		StartLng:      in.EndLng,
+		EndLat:        in.StartLat,
+		EndLng:        in.StartLng,
+		Stops:         stops,
+		LeavingAfter:  in.ReturnLeavingAfter,
+		LeavingBefore: in.ReturnLeavingBefore,
+		MaxPrice:      in.MaxPrice,
+		MinSeats:      in.MinSeats,
+	}
+}
+
+// validateSearch rejects filter combinations that can never match.
+func validateSearch(in domain.SearchRouteInput) error {
+	if in.LeavingAfter != nil && in.LeavingBefore != nil && in.LeavingAfter.After(*in.LeavingBefore) {
+		return fmt.Errorf("%w: leaving_after must not be later than leaving_before", errs.ErrInvalidSearch)
+	}
+	if in.ReturnLeavingAfter != nil && in.ReturnLeavingBefore != nil && in.ReturnLeavingAfter.After(*in.ReturnLeavingBefore) {
+		return fmt.Errorf("%w: return_leaving_after must not be later than return_leaving_before", errs.ErrInvalidSearch)
+	}
+	if in.MaxPrice != nil && *in.MaxPrice < 0 {
+		return fmt.Errorf("%w: max_price must not be negative", errs.ErrInvalidSearch)
+	}
+	if in.MinSeats != nil && *in.MinSeats == 0 {
+		return fmt.Errorf("%w: min_seats must be at least 1", errs.ErrInvalidSearch)
+	}
+	if in.Limit < 0 || in.Limit > maxSearchLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", errs.ErrInvalidSearch, maxSearchLimit)
+	}
+	return nil
+}
+
 // ── Geo calculations (moved from route/calculations.go) ──────────────────────
 
-// Haversine returns the great-circle distance in kilometres between two points.
-func Haversine(lat1, lng1, lat2, lng2 float64) float64 {
-	const earthRadiusKm = 6371
-	dLat := (lat2 - lat1) * math.Pi / 180
-	dLng := (lng2 - lng1) * math.Pi / 180
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*
-			math.Sin(dLng/2)*math.Sin(dLng/2)
-	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+// routeMatch describes how a search query fits onto a route.
+type routeMatch struct {
+	deviation float64
+	// segments holds, per search stop, the index of the route segment it
+	// projects onto (0 = start → first stop).
+	segments []int
+	// inOrder is false when a later search stop projects onto the route
+	// before an earlier one, i.e. the passenger travels against the route.
+	inOrder bool
 }
 
+func matchRoute(r *domain.Route, search domain.SearchRouteInput) routeMatch {
+	var dev float64
+	var segments []int
+	var inOrder bool
+	if path := pathSegments(r); path != nil {
+		dev, segments, inOrder = matchPath(path, r.Stops, search)
+	} else {
+		dev, segments, inOrder = matchStops(r.StartLat, r.StartLng, r.EndLat, r.EndLng, r.Stops, search)
+	}
+	return routeMatch{
+		deviation: geo.Haversine(search.StartLat, search.StartLng, r.StartLat, r.StartLng) +
+			geo.Haversine(search.EndLat, search.EndLng, r.EndLat, r.EndLng) + dev,
+		segments: segments,
+		inOrder:  inOrder,
+	}
+}
+
+// endpointDistance is the straight-line distance between the search's and the
+// route's start plus that between their ends. Road distances are never
+// shorter, so it is a lower bound for roadDeviation.
+func endpointDistance(r *domain.Route, search domain.SearchRouteInput) float64 {
+	return geo.Haversine(search.StartLat, search.StartLng, r.StartLat, r.StartLng) +
+		geo.Haversine(search.EndLat, search.EndLng, r.EndLat, r.EndLng)
+}
+
+// roadDeviation re-measures a match on the road network: the drive between the
+// search's and the route's start and end points, plus the extra distance the
+// driver covers by visiting the search stops on their matched segments. The
+// route itself is measured along its stored path when it has one. It keeps
+// the straight-line deviation when any part can only be measured in straight
+// lines, so road and straight-line distances are never mixed.
+func roadDeviation(ctx context.Context, router domain.Router, r *domain.Route, search domain.SearchRouteInput, m routeMatch) float64 {
+	path := routePoints(r)
+	legs := [][]domain.LatLng{
+		{{Lat: search.StartLat, Lng: search.StartLng}, path[0]},
+		{path[len(path)-1], {Lat: search.EndLat, Lng: search.EndLng}},
+	}
+	stored := pathSegments(r) != nil
+	if len(search.Stops) > 0 {
+		legs = append(legs, insertSearchStops(path, search.Stops, m.segments))
+		if !stored {
+			legs = append(legs, path)
+		}
+	}
+
+	km := make([]float64, len(legs))
+	for i, leg := range legs {
+		d, ok := roadKm(ctx, router, leg)
+		if !ok {
+			return m.deviation
+		}
+		km[i] = d
+	}
+	dev := km[0] + km[1]
+	if len(km) > 2 {
+		routeKm := pathLengthKm(r)
+		if !stored {
+			routeKm = km[3]
+		}
+		dev += math.Max(0, km[2]-routeKm)
+	}
+	return dev
+}
+
+// routePoints returns the route's start, stops and end in driving order.
+func routePoints(r *domain.Route) []domain.LatLng {
+	points := make([]domain.LatLng, 0, len(r.Stops)+2)
+	points = append(points, domain.LatLng{Lat: r.StartLat, Lng: r.StartLng})
+	for _, st := range r.Stops {
+		points = append(points, domain.LatLng{Lat: st.Lat, Lng: st.Lng})
+	}
+	return append(points, domain.LatLng{Lat: r.EndLat, Lng: r.EndLng})
+}
+
+// insertSearchStops returns path with each search stop placed on the segment
+// it was matched to. segments must be non-decreasing.
+func insertSearchStops(path []domain.LatLng, stops []domain.SearchStop, segments []int) []domain.LatLng {
+	out := make([]domain.LatLng, 0, len(path)+len(stops))
+	j := 0
+	for i, p := range path {
+		out = append(out, p)
+		for j < len(stops) && segments[j] == i {
+			out = append(out, domain.LatLng{Lat: stops[j].Lat, Lng: stops[j].Lng})
+			j++
+		}
+	}
+	return out
+}
+
+// calculateDeviation returns the detour (km) a route needs to serve the search,
+// or +Inf when the search stops run against the route's direction.
 func calculateDeviation(r *domain.Route, search domain.SearchRouteInput) float64 {
-	return Haversine(search.StartLat, search.StartLng, r.StartLat, r.StartLng) +
-		Haversine(search.EndLat, search.EndLng, r.EndLat, r.EndLng) +
-		deviationForStops(r.StartLat, r.StartLng, r.EndLat, r.EndLng, r.Stops, search)
+	m := matchRoute(r, search)
+	if !m.inOrder {
+		return math.Inf(1)
+	}
+	return m.deviation
 }
 
 func deviationForStops(startLat, startLng, endLat, endLng float64, stops []domain.Stop, search domain.SearchRouteInput) float64 {
+	dev, _, inOrder := matchStops(startLat, startLng, endLat, endLng, stops, search)
+	if !inOrder {
+		return math.Inf(1)
+	}
+	return dev
+}
+
+// matchStops matches the search stops against the straight segments between
+// the route's start, stops and end.
+func matchStops(startLat, startLng, endLat, endLng float64, stops []domain.Stop, search domain.SearchRouteInput) (float64, []int, bool) {
 	segments := buildSegmentsFromStops(startLat, startLng, endLat, endLng, stops)
+	projections, dev, inOrder := projectSearchStops(segments, search.Stops)
+	matched := make([]int, len(projections))
+	for i, p := range projections {
+		matched[i] = p.segment
+	}
+	return dev, matched, inOrder
+}
+
+// matchPath is matchStops against the route's stored driving path. Path
+// segments are finer than stop segments, so each search stop is assigned the
+// stop segment that holds its position along the path: the number of route
+// stops that project onto the path before it.
+func matchPath(path []routeSegment, stops []domain.Stop, search domain.SearchRouteInput) (float64, []int, bool) {
+	projections, dev, inOrder := projectSearchStops(path, search.Stops)
+	stopAlong := make([]float64, len(stops))
+	for i, st := range stops {
+		stopAlong[i] = projectOntoSegments(st.Lat, st.Lng, path).along()
+	}
+	matched := make([]int, len(projections))
+	for i, p := range projections {
+		for _, a := range stopAlong {
+			if a < p.along() {
+				matched[i]++
+			}
+		}
+	}
+	return dev, matched, inOrder
+}
+
+// projectSearchStops projects each search stop onto segments and sums their
+// distances to it. Search stops are pickup/dropoff points in travel order, so
+// their projections must not move backwards along the path.
+func projectSearchStops(segments []routeSegment, stops []domain.SearchStop) ([]segmentProjection, float64, bool) {
+	projections := make([]segmentProjection, len(stops))
 	dev := 0.0
-	for _, us := range search.Stops {
-		dev += minDistanceToSegment(us.Lat, us.Lng, segments)
+	inOrder := true
+	last := math.Inf(-1)
+	for i, us := range stops {
+		p := projectOntoSegments(us.Lat, us.Lng, segments)
+		projections[i] = p
+		dev += p.distance
+		if p.along() < last {
+			inOrder = false
+		}
+		last = p.along()
 	}
-	return dev
+	return projections, dev, inOrder
 }
 
 type routeSegment struct{ aLat, aLng, bLat, bLng float64 }
@@ -196,16 +839,33 @@ type routeSegment struct{ aLat, aLng, bLat, bLng float64 }
 // buildSegmentsFromStops returns the actual path segments of a route:
 // start→stop1, stop1→stop2, …, stopN→end.
 func buildSegmentsFromStops(startLat, startLng, endLat, endLng float64, stops []domain.Stop) []routeSegment {
-	points := make([][2]float64, 0, len(stops)+2)
-	points = append(points, [2]float64{startLat, startLng})
+	points := make([]domain.LatLng, 0, len(stops)+2)
+	points = append(points, domain.LatLng{Lat: startLat, Lng: startLng})
 	for _, s := range stops {
-		points = append(points, [2]float64{s.Lat, s.Lng})
+		points = append(points, domain.LatLng{Lat: s.Lat, Lng: s.Lng})
 	}
-	points = append(points, [2]float64{endLat, endLng})
+	points = append(points, domain.LatLng{Lat: endLat, Lng: endLng})
+	return buildSegments(points)
+}
 
+// pathSegments returns the segments of the route's stored polyline, or nil
+// when it has none (or it cannot be decoded).
+func pathSegments(r *domain.Route) []routeSegment {
+	if r.Polyline == nil {
+		return nil
+	}
+	points, err := geo.DecodePolyline(*r.Polyline)
+	if err != nil || len(points) < 2 {
+		return nil
+	}
+	return buildSegments(points)
+}
+
+// buildSegments returns the segments joining consecutive points.
+func buildSegments(points []domain.LatLng) []routeSegment {
 	segs := make([]routeSegment, 0, len(points)-1)
 	for i := 0; i < len(points)-1; i++ {
-		segs = append(segs, routeSegment{points[i][0], points[i][1], points[i+1][0], points[i+1][1]})
+		segs = append(segs, routeSegment{points[i].Lat, points[i].Lng, points[i+1].Lat, points[i+1].Lng})
 	}
 	return segs
 }
@@ -213,25 +873,51 @@ func buildSegmentsFromStops(startLat, startLng, endLat, endLng float64, stops []
 // minDistanceToSegment returns the minimum great-circle distance (km) from
 // point (lat, lng) to any of the given route segments.
 func minDistanceToSegment(lat, lng float64, segments []routeSegment) float64 {
-	min := math.MaxFloat64
-	for _, seg := range segments {
-		if d := pointToSegmentDistance(lat, lng, seg.aLat, seg.aLng, seg.bLat, seg.bLng); d < min {
-			min = d
+	return projectOntoSegments(lat, lng, segments).distance
+}
+
+// segmentProjection is the closest point on a route path to some point.
+type segmentProjection struct {
+	segment  int     // index of the closest segment
+	t        float64 // position along that segment, 0 (start) to 1 (end)
+	distance float64 // great-circle distance (km) to the closest point
+}
+
+// along returns the projection's position along the whole path, in segments.
+func (p segmentProjection) along() float64 {
+	return float64(p.segment) + p.t
+}
+
+// projectOntoSegments returns the projection of (lat, lng) onto the closest
+// of the given segments. The first segment wins ties.
+func projectOntoSegments(lat, lng float64, segments []routeSegment) segmentProjection {
+	best := segmentProjection{distance: math.MaxFloat64}
+	for i, seg := range segments {
+		t, d := projectOntoSegment(lat, lng, seg.aLat, seg.aLng, seg.bLat, seg.bLng)
+		if d < best.distance {
+			best = segmentProjection{segment: i, t: t, distance: d}
 		}
 	}
-	return min
+	return best
 }
 
 // pointToSegmentDistance returns the great-circle distance (km) from point P
-// to the closest point on segment AB. It projects P onto the line through A
-// and B in lat/lng space (valid approximation for segments < ~500 km) and
-// clamps the projection to [0,1] so it never extends beyond the endpoints.
+// to the closest point on segment AB.
 func pointToSegmentDistance(pLat, pLng, aLat, aLng, bLat, bLng float64) float64 {
+	_, d := projectOntoSegment(pLat, pLng, aLat, aLng, bLat, bLng)
+	return d
+}
+
+// projectOntoSegment projects P onto the line through A and B in lat/lng space
+// (valid approximation for segments < ~500 km) and clamps the projection to
+// [0,1] so it never extends beyond the endpoints. It returns the clamped
+// parameter t and the great-circle distance (km) from P to the projection.
+func projectOntoSegment(pLat, pLng, aLat, aLng, bLat, bLng float64) (float64, float64) {
 	abLat := bLat - aLat
 	abLng := bLng - aLng
 	len2 := abLat*abLat + abLng*abLng
 	if len2 == 0 {
-		return Haversine(pLat, pLng, aLat, aLng)
+		return 0, geo.Haversine(pLat, pLng, aLat, aLng)
 	}
 	t := ((pLat-aLat)*abLat + (pLng-aLng)*abLng) / len2
 	if t < 0 {
@@ -239,5 +925,5 @@ func pointToSegmentDistance(pLat, pLng, aLat, aLng, bLat, bLng float64) float64
 	} else if t > 1 {
 		t = 1
 	}
-	return Haversine(pLat, pLng, aLat+t*abLat, aLng+t*abLng)
+	return t, geo.Haversine(pLat, pLng, aLat+t*abLat, aLng+t*abLng)
 }
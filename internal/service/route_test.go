@@ -1,39 +1,23 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"math"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/geo"
+	"github.com/jmartynas/pss-backend/internal/routing"
 )
 
 func strPtr(s string) *string { return &s }
 
-func TestHaversine(t *testing.T) {
-	tests := []struct {
-		name      string
-		lat1      float64
-		lng1      float64
-		lat2      float64
-		lng2      float64
-		want      float64
-		tolerance float64
-	}{
-		{"same point", 0.0, 0.0, 0.0, 0.0, 0.0, 0.001},
-		{"New York to Los Angeles", 40.7128, -74.0060, 34.0522, -118.2437, 3944.0, 50.0},
-		{"London to Paris", 51.5074, -0.1278, 48.8566, 2.3522, 344.0, 10.0},
-		{"short distance", 0.0, 0.0, 0.1, 0.1, 15.7, 1.0},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := Haversine(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
-			if math.Abs(got-tt.want) > tt.tolerance {
-				t.Errorf("Haversine() = %v, want %v (tolerance: %v)", got, tt.want, tt.tolerance)
-			}
-		})
-	}
-}
-
 func TestBuildSegmentsFromStops(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -187,3 +171,646 @@ func TestCalculateDeviation(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchRoute_Direction(t *testing.T) {
+	// Route A(0,0) → stop(0,1) → B(0,2).
+	route := &domain.Route{
+		EndLat: 0, EndLng: 2,
+		Stops: []domain.Stop{{Lat: 0, Lng: 1}},
+	}
+	tests := []struct {
+		name         string
+		stops        []domain.SearchStop
+		wantInOrder  bool
+		wantSegments []int
+	}{
+		{"same direction", []domain.SearchStop{{Lat: 0, Lng: 0.5}, {Lat: 0, Lng: 1.5}}, true, []int{0, 1}},
+		{"same segment", []domain.SearchStop{{Lat: 0, Lng: 0.2}, {Lat: 0, Lng: 0.8}}, true, []int{0, 0}},
+		{"opposite direction", []domain.SearchStop{{Lat: 0, Lng: 1.5}, {Lat: 0, Lng: 0.5}}, false, []int{1, 0}},
+		{"opposite direction on one segment", []domain.SearchStop{{Lat: 0, Lng: 0.8}, {Lat: 0, Lng: 0.2}}, false, []int{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := matchRoute(route, domain.SearchRouteInput{EndLng: 2, Stops: tt.stops})
+			if m.inOrder != tt.wantInOrder {
+				t.Errorf("matchRoute().inOrder = %v, want %v", m.inOrder, tt.wantInOrder)
+			}
+			if len(m.segments) != len(tt.wantSegments) {
+				t.Fatalf("matchRoute().segments = %v, want %v", m.segments, tt.wantSegments)
+			}
+			for i := range m.segments {
+				if m.segments[i] != tt.wantSegments[i] {
+					t.Errorf("matchRoute().segments = %v, want %v", m.segments, tt.wantSegments)
+					break
+				}
+			}
+			if dev := calculateDeviation(route, domain.SearchRouteInput{EndLng: 2, Stops: tt.stops}); math.IsInf(dev, 1) == tt.wantInOrder {
+				t.Errorf("calculateDeviation() = %v, want finite = %v", dev, tt.wantInOrder)
+			}
+		})
+	}
+}
+
+// stubRouter reports a fixed distance keyed by the number of points routed.
+type stubRouter map[int]float64
+
+func (s stubRouter) Route(_ context.Context, points []domain.LatLng) (*domain.RoutePath, error) {
+	km, ok := s[len(points)]
+	if !ok {
+		return nil, errors.New("no route")
+	}
+	return &domain.RoutePath{DistanceKm: km}, nil
+}
+
+func TestRouteService_RoadDeviation(t *testing.T) {
+	route := &domain.Route{EndLat: 0, EndLng: 1, Stops: []domain.Stop{}}
+	search := domain.SearchRouteInput{EndLng: 1, Stops: []domain.SearchStop{{Lat: 0.01, Lng: 0.5}}}
+	m := matchRoute(route, search)
+
+	// Start/end legs are 2 points long, the route 2 points and the route with
+	// the passenger stop 3 points: a 5 km detour over a river plus 1 km at each end.
+	got := roadDeviation(context.Background(), stubRouter{2: 1, 3: 6}, route, search, m)
+	if want := 1 + 1 + (6 - 1.0); got != want {
+		t.Errorf("roadDeviation() = %v, want %v", got, want)
+	}
+
+	// A stored path is measured as it is: about 111 km here.
+	polyline := geo.EncodePolyline([]domain.LatLng{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}})
+	withPath := *route
+	withPath.Polyline = &polyline
+	got = roadDeviation(context.Background(), stubRouter{2: 1, 3: 120}, &withPath, search, m)
+	if want := 1 + 1 + (120 - pathLengthKm(&withPath)); math.Abs(got-want) > 1e-9 {
+		t.Errorf("roadDeviation(stored path) = %v, want %v", got, want)
+	}
+
+	// Router failures, and straight-line answers of a fallback router, keep
+	// the straight-line deviation.
+	for name, router := range map[string]domain.Router{"failing": stubRouter{}, "straight-line": routing.NewHaversine(60)} {
+		if got := roadDeviation(context.Background(), router, route, search, m); got != m.deviation {
+			t.Errorf("roadDeviation(%s router) = %v, want %v", name, got, m.deviation)
+		}
+	}
+}
+
+// countingRouter is a stubRouter that counts the calls it answers.
+type countingRouter struct {
+	stubRouter
+	calls atomic.Int32
+}
+
+func (c *countingRouter) Route(ctx context.Context, points []domain.LatLng) (*domain.RoutePath, error) {
+	c.calls.Add(1)
+	return c.stubRouter.Route(ctx, points)
+}
+
+func TestRouteService_Search_MeasuresPageOnRoads(t *testing.T) {
+	var all []domain.Route
+	for i := range 10 {
+		all = append(all, domain.Route{
+			ID: uuid.New(), CreatorID: uuid.New(),
+			StartLat: float64(i) * 0.001, EndLng: 1,
+			MaxDeviation: 50, MaxPassengers: 4, AvailablePassengers: 4,
+		})
+	}
+	router := &countingRouter{stubRouter: stubRouter{2: 0.5}}
+	svc := NewRouteService(&mockRouteRepo{
+		listSearchable: func(_ context.Context, _ domain.SearchRouteInput) ([]domain.Route, error) {
+			return all, nil
+		},
+	}, &mockReviewRepo{
+		getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
+			return map[uuid.UUID]domain.ReviewSummary{}, nil
+		},
+	}, nil, nil, router, DefaultRankWeights, nil, nil)
+
+	page, err := svc.Search(context.Background(), domain.SearchRouteInput{EndLng: 1, Limit: 2})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(page.Items) != 2 || page.NextCursor == nil {
+		t.Fatalf("Search() returned %d results, want 2 and a next page", len(page.Items))
+	}
+	// The trip, then start and end legs of the page and one route beyond it.
+	if got := router.calls.Load(); got != 1+3*2 {
+		t.Errorf("Search() made %d routing calls, want %d", got, 1+3*2)
+	}
+}
+
+func TestMatchRoute_Polyline(t *testing.T) {
+	// Route A(0,0) → stop(0,1) → B(0,2), driven via a northern loop before the stop.
+	polyline := geo.EncodePolyline([]domain.LatLng{
+		{Lat: 0, Lng: 0}, {Lat: 0.5, Lng: 0}, {Lat: 0.5, Lng: 1}, {Lat: 0, Lng: 1}, {Lat: 0, Lng: 2},
+	})
+	route := &domain.Route{
+		EndLat: 0, EndLng: 2,
+		Stops:    []domain.Stop{{Lat: 0, Lng: 1}},
+		Polyline: &polyline,
+	}
+	search := domain.SearchRouteInput{EndLng: 2, Stops: []domain.SearchStop{{Lat: 0.5, Lng: 0.5}, {Lat: 0, Lng: 1.5}}}
+
+	m := matchRoute(route, search)
+	if !m.inOrder {
+		t.Fatalf("matchRoute().inOrder = false, want true")
+	}
+	if m.deviation > 0.1 {
+		t.Errorf("matchRoute().deviation = %v, want ~0 (stops lie on the path)", m.deviation)
+	}
+	if len(m.segments) != 2 || m.segments[0] != 0 || m.segments[1] != 1 {
+		t.Errorf("matchRoute().segments = %v, want [0 1]", m.segments)
+	}
+
+	route.Polyline = nil
+	if dev := matchRoute(route, search).deviation; dev < 50 {
+		t.Errorf("straight-line deviation = %v, want > 50", dev)
+	}
+}
+
+func TestValidatePolyline(t *testing.T) {
+	start, end := domain.LatLng{Lat: 54.6872, Lng: 25.2797}, domain.LatLng{Lat: 54.8985, Lng: 23.9036}
+	tests := []struct {
+		name     string
+		polyline string
+		wantErr  bool
+	}{
+		{"valid", geo.EncodePolyline([]domain.LatLng{start, {Lat: 54.8, Lng: 24.5}, end}), false},
+		{"endpoints within tolerance", geo.EncodePolyline([]domain.LatLng{{Lat: 54.6880, Lng: 25.2790}, end}), false},
+		{"malformed", "_p~iF~ps|", true},
+		{"single point", geo.EncodePolyline([]domain.LatLng{start}), true},
+		{"starts elsewhere", geo.EncodePolyline([]domain.LatLng{{Lat: 54.8, Lng: 24.5}, end}), true},
+		{"ends elsewhere", geo.EncodePolyline([]domain.LatLng{start, {Lat: 54.8, Lng: 24.5}}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePolyline(tt.polyline, start, end)
+			if tt.wantErr && !errors.Is(err, errs.ErrInvalidPolyline) {
+				t.Errorf("validatePolyline() = %v, want ErrInvalidPolyline", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("validatePolyline() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestValidateReturnLeg(t *testing.T) {
+	leaving := time.Now().Add(24 * time.Hour)
+	later, earlier := leaving.Add(8*time.Hour), leaving.Add(-time.Hour)
+	start, end := domain.LatLng{Lat: 54.6872, Lng: 25.2797}, domain.LatLng{Lat: 54.8985, Lng: 23.9036}
+	tests := []struct {
+		name    string
+		ret     domain.ReturnLegInput
+		wantErr bool
+	}{
+		{"valid", domain.ReturnLegInput{LeavingAt: &later}, false},
+		{"reversed polyline", domain.ReturnLegInput{LeavingAt: &later, Polyline: strPtr(geo.EncodePolyline([]domain.LatLng{end, start}))}, false},
+		{"empty polyline", domain.ReturnLegInput{LeavingAt: &later, Polyline: strPtr("")}, false},
+		{"missing departure", domain.ReturnLegInput{}, true},
+		{"leaves before outbound", domain.ReturnLegInput{LeavingAt: &earlier}, true},
+		{"outbound polyline", domain.ReturnLegInput{LeavingAt: &later, Polyline: strPtr(geo.EncodePolyline([]domain.LatLng{start, end}))}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ret := tt.ret
+			in := domain.CreateRouteInput{
+				StartLat: start.Lat, StartLng: start.Lng, EndLat: end.Lat, EndLng: end.Lng,
+				LeavingAt: &leaving,
+				Return:    &ret,
+			}
+			err := validateReturnLeg(&in)
+			if tt.wantErr {
+				if !errors.Is(err, errs.ErrInvalidReturnLeg) && !errors.Is(err, errs.ErrInvalidPolyline) {
+					t.Errorf("validateReturnLeg() = %v, want ErrInvalidReturnLeg or ErrInvalidPolyline", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateReturnLeg() = %v, want nil", err)
+			}
+			if in.Return.Polyline != nil && *in.Return.Polyline == "" {
+				t.Error("validateReturnLeg() should clear an empty polyline")
+			}
+		})
+	}
+}
+
+func TestRouteService_Search_PrefersRoundTrips(t *testing.T) {
+	outboundAfter := time.Now().Add(time.Hour)
+	returnAfter := outboundAfter.Add(8 * time.Hour)
+	oneWay := domain.Route{
+		ID: uuid.New(), CreatorID: uuid.New(),
+		StartLat: 0, StartLng: 0, EndLat: 1, EndLng: 1,
+		MaxDeviation: 1000,
+	}
+	roundTrip := domain.Route{
+		ID: uuid.New(), CreatorID: uuid.New(),
+		StartLat: 0.1, StartLng: 0.1, EndLat: 1.1, EndLng: 1.1,
+		MaxDeviation: 1000,
+	}
+	back := domain.Route{
+		ID: uuid.New(), CreatorID: roundTrip.CreatorID,
+		StartLat: 1.1, StartLng: 1.1, EndLat: 0.1, EndLng: 0.1,
+		MaxDeviation:    1000,
+		ReturnOfRouteID: &roundTrip.ID,
+	}
+	roundTrip.ReturnRouteID = &back.ID
+
+	svc := NewRouteService(&mockRouteRepo{
+		listSearchable: func(_ context.Context, in domain.SearchRouteInput) ([]domain.Route, error) {
+			if in.LeavingAfter == &returnAfter {
+				if in.StartLat != 1 || in.EndLat != 0 {
+					t.Errorf("return search runs from %v to %v, want reversed", in.StartLat, in.EndLat)
+				}
+				return []domain.Route{back}, nil
+			}
+			return []domain.Route{oneWay, roundTrip}, nil
+		},
+	}, &mockReviewRepo{
+		getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
+			return map[uuid.UUID]domain.ReviewSummary{}, nil
+		},
+	}, nil, nil, nil, DefaultRankWeights, nil, nil)
+
+	results, err := svc.Search(context.Background(), domain.SearchRouteInput{
+		StartLat: 0, StartLng: 0, EndLat: 1, EndLng: 1,
+		LeavingAfter:       &outboundAfter,
+		ReturnLeavingAfter: &returnAfter,
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results.Items) != 2 {
+		t.Fatalf("Search() returned %d results, want 2", len(results.Items))
+	}
+	first := results.Items[0]
+	if first.ID != roundTrip.ID {
+		t.Fatalf("Search() first result = %v, want the round trip despite its larger deviation", first.ID)
+	}
+	if first.MatchedReturnRouteID == nil || *first.MatchedReturnRouteID != back.ID {
+		t.Errorf("MatchedReturnRouteID = %v, want %v", first.MatchedReturnRouteID, back.ID)
+	}
+	if results.Items[1].MatchedReturnRouteID != nil {
+		t.Error("one-way route should have no matched return leg")
+	}
+}
+
+func TestRouteService_Search_RanksByScore(t *testing.T) {
+	wanted := time.Now().Add(24 * time.Hour)
+	onTime, late := wanted.Add(15*time.Minute), wanted.Add(6*time.Hour)
+	cheap, pricey := 5.0, 20.0
+	// closest departs late, costs more and has a poorly rated driver; it
+	// matches exactly but still ranks below a small detour that is better on
+	// every other factor.
+	closest := domain.Route{
+		ID: uuid.New(), CreatorID: uuid.New(),
+		StartLat: 0, StartLng: 0, EndLat: 1, EndLng: 1,
+		MaxDeviation: 50, MaxPassengers: 4, AvailablePassengers: 1,
+		LeavingAt: &late, Price: &pricey,
+	}
+	better := domain.Route{
+		ID: uuid.New(), CreatorID: uuid.New(),
+		StartLat: 0.01, StartLng: 0, EndLat: 1, EndLng: 1,
+		MaxDeviation: 50, MaxPassengers: 4, AvailablePassengers: 4,
+		LeavingAt: &onTime, Price: &cheap,
+	}
+
+	svc := NewRouteService(&mockRouteRepo{
+		listSearchable: func(_ context.Context, _ domain.SearchRouteInput) ([]domain.Route, error) {
+			return []domain.Route{closest, better}, nil
+		},
+	}, &mockReviewRepo{
+		getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
+			return map[uuid.UUID]domain.ReviewSummary{
+				closest.CreatorID: {Avg: 2, Count: 20},
+				better.CreatorID:  {Avg: 4.8, Count: 20},
+			}, nil
+		},
+	}, nil, nil, nil, DefaultRankWeights, nil, nil)
+
+	results, err := svc.Search(context.Background(), domain.SearchRouteInput{
+		StartLat: 0, StartLng: 0, EndLat: 1, EndLng: 1,
+		LeavingAfter: &wanted,
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results.Items) != 2 {
+		t.Fatalf("Search() returned %d results, want 2", len(results.Items))
+	}
+	first, second := results.Items[0], results.Items[1]
+	if first.ID != better.ID {
+		t.Fatalf("Search() first result should be the better scored route")
+	}
+	if first.Score == nil || second.Score == nil {
+		t.Fatal("search results should carry a score")
+	}
+	if second.Score.Deviation <= first.Score.Deviation {
+		t.Errorf("exact match should score higher on deviation: %+v vs %+v", second.Score, first.Score)
+	}
+	s := first.Score
+	if sum := s.Deviation + s.Departure + s.Price + s.Rating + s.Seats; math.Abs(sum-s.Total) > 1e-9 {
+		t.Errorf("score breakdown sums to %v, want total %v", sum, s.Total)
+	}
+}
+
+func TestRouteService_Search_PerKmFares(t *testing.T) {
+	cheapRate, priceyRate := 0.05, 0.5
+	perKm := func(rate *float64) domain.Route {
+		return domain.Route{
+			ID: uuid.New(), CreatorID: uuid.New(),
+			StartLat: 0, StartLng: 0, EndLat: 0, EndLng: 1,
+			MaxDeviation: 50, MaxPassengers: 4, AvailablePassengers: 4,
+			PricingMode: domain.PricingPerKm, PricePerKm: rate,
+		}
+	}
+	cheap, pricey := perKm(&cheapRate), perKm(&priceyRate)
+	svc := NewRouteService(&mockRouteRepo{
+		listSearchable: func(_ context.Context, _ domain.SearchRouteInput) ([]domain.Route, error) {
+			return []domain.Route{cheap, pricey}, nil
+		},
+	}, &mockReviewRepo{
+		getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
+			return map[uuid.UUID]domain.ReviewSummary{}, nil
+		},
+	}, nil, nil, nil, DefaultRankWeights, nil, nil)
+
+	// The trip is about 111 km: about 5.56 on cheap and 55.6 on pricey.
+	in := domain.SearchRouteInput{EndLng: 1}
+	results, err := svc.Search(context.Background(), in)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results.Items) != 2 || results.Items[0].ID != cheap.ID {
+		t.Fatalf("Search() should rank the cheaper per-km route first")
+	}
+	if est := results.Items[0].EstimatedPrice; est == nil || math.Abs(*est-5.56) > 0.01 {
+		t.Errorf("EstimatedPrice = %v, want about 5.56", est)
+	}
+	if results.Items[1].Score.Price >= results.Items[0].Score.Price {
+		t.Errorf("pricey route should score lower on price: %+v", results.Items[1].Score)
+	}
+
+	maxPrice := 10.0
+	in.MaxPrice = &maxPrice
+	results, err = svc.Search(context.Background(), in)
+	if err != nil {
+		t.Fatalf("Search(max_price) error = %v", err)
+	}
+	if len(results.Items) != 1 || results.Items[0].ID != cheap.ID {
+		t.Errorf("Search(max_price 10) returned %d results, want only the cheap route", len(results.Items))
+	}
+}
+
+func TestRouteService_Search_FlagsWaitlistOnly(t *testing.T) {
+	route := func(available, waitlisted uint) domain.Route {
+		return domain.Route{
+			ID: uuid.New(), CreatorID: uuid.New(), EndLng: 1,
+			MaxDeviation: 50, MaxPassengers: 2, AvailablePassengers: available, Waitlisted: waitlisted,
+		}
+	}
+	free, full, queued := route(2, 0), route(0, 1), route(1, 1)
+	svc := NewRouteService(&mockRouteRepo{
+		listSearchable: func(_ context.Context, _ domain.SearchRouteInput) ([]domain.Route, error) {
+			return []domain.Route{free, full, queued}, nil
+		},
+	}, &mockReviewRepo{
+		getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
+			return map[uuid.UUID]domain.ReviewSummary{}, nil
+		},
+	}, nil, nil, nil, DefaultRankWeights, nil, nil)
+
+	results, err := svc.Search(context.Background(), domain.SearchRouteInput{EndLng: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	want := map[uuid.UUID]bool{free.ID: false, full.ID: true, queued.ID: true}
+	if len(results.Items) != len(want) {
+		t.Fatalf("Search() returned %d results, want %d", len(results.Items), len(want))
+	}
+	for _, r := range results.Items {
+		if r.WaitlistOnly != want[r.ID] {
+			t.Errorf("route with %d free seats and %d waitlisted: WaitlistOnly = %v", r.AvailablePassengers, r.Waitlisted, r.WaitlistOnly)
+		}
+	}
+}
+
+func TestRouteService_Export_ParticipantsOnly(t *testing.T) {
+	driver, passenger, outsider := uuid.New(), uuid.New(), uuid.New()
+	passengerRecord := uuid.New()
+	owner := passengerRecord.String()
+	route := &domain.Route{
+		ID:     uuid.New(),
+		Stops:  []domain.Stop{{ID: uuid.New(), Lat: 0, Lng: 1, ParticipantID: &owner}},
+		EndLng: 2,
+		Participants: []domain.Participant{
+			{ID: uuid.New(), UserID: driver, Name: "Driver", Status: "driver"},
+			{ID: passengerRecord, UserID: passenger, Name: "Ona", Status: "approved"},
+		},
+	}
+	svc := NewRouteService(&mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}, nil, nil, &mockChatRepo{
+		canAccessGroupChat: func(_ context.Context, _, userID uuid.UUID) (bool, error) { return userID != outsider, nil },
+	}, nil, DefaultRankWeights, nil, nil)
+
+	if _, err := svc.Export(context.Background(), route.ID, outsider, "geojson"); !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("Export(outsider) = %v, want ErrForbidden", err)
+	}
+	out, err := svc.Export(context.Background(), route.ID, passenger, "gpx")
+	if err != nil {
+		t.Fatalf("Export(passenger) error = %v", err)
+	}
+	if !strings.Contains(string(out), "<name>Ona</name>") {
+		t.Errorf("Export() should label the passenger's stop:\n%s", out)
+	}
+}
+
+type mockChatRepo struct {
+	canAccessGroupChat func(ctx context.Context, routeID, userID uuid.UUID) (bool, error)
+}
+
+func (m *mockChatRepo) ListPrivateChats(ctx context.Context, userID uuid.UUID) ([]domain.PrivateChat, error) {
+	return nil, nil
+}
+func (m *mockChatRepo) ListGroupChats(ctx context.Context, userID uuid.UUID) ([]domain.GroupChat, error) {
+	return nil, nil
+}
+func (m *mockChatRepo) GetPrivateMessages(ctx context.Context, chatID uuid.UUID) ([]domain.ChatMessage, error) {
+	return nil, nil
+}
+func (m *mockChatRepo) GetGroupMessages(ctx context.Context, routeID uuid.UUID) ([]domain.ChatMessage, error) {
+	return nil, nil
+}
+func (m *mockChatRepo) SendPrivateMessage(ctx context.Context, chatID, senderUserID uuid.UUID, message string) (uuid.UUID, error) {
+	return uuid.Nil, nil
+}
+func (m *mockChatRepo) SendGroupMessage(ctx context.Context, routeID, senderUserID uuid.UUID, message string) (uuid.UUID, error) {
+	return uuid.Nil, nil
+}
+func (m *mockChatRepo) CanAccessPrivateChat(ctx context.Context, chatID, userID uuid.UUID) (bool, error) {
+	return false, nil
+}
+func (m *mockChatRepo) CanAccessGroupChat(ctx context.Context, routeID, userID uuid.UUID) (bool, error) {
+	return m.canAccessGroupChat(ctx, routeID, userID)
+}
+
+type mockVehicleRepo struct {
+	getByID func(ctx context.Context, id uuid.UUID) (*domain.Vehicle, error)
+}
+
+func (m *mockVehicleRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Vehicle, error) {
+	return m.getByID(ctx, id)
+}
+func (m *mockVehicleRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Vehicle, error) {
+	return nil, nil
+}
+func (m *mockVehicleRepo) Create(ctx context.Context, userID uuid.UUID, in domain.CreateVehicleInput) (uuid.UUID, error) {
+	return uuid.Nil, nil
+}
+func (m *mockVehicleRepo) Update(ctx context.Context, id, userID uuid.UUID, in domain.UpdateVehicleInput) error {
+	return nil
+}
+func (m *mockVehicleRepo) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	return nil
+}
+
+func TestRouteService_Create_ValidatesVehicle(t *testing.T) {
+	driverID := uuid.New()
+	car := &domain.Vehicle{ID: uuid.New(), UserID: driverID, Seats: 5}
+	vehicles := &mockVehicleRepo{
+		getByID: func(_ context.Context, id uuid.UUID) (*domain.Vehicle, error) {
+			if id != car.ID {
+				return nil, errs.ErrNotFound
+			}
+			return car, nil
+		},
+	}
+	routes := &mockRouteRepo{
+		create: func(_ context.Context, _ uuid.UUID, _ domain.CreateRouteInput) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+	other := uuid.New()
+	tests := []struct {
+		name      string
+		creatorID uuid.UUID
+		vehicleID uuid.UUID
+		seats     uint
+		want      error
+	}{
+		{"fits", driverID, car.ID, 4, nil},
+		{"more than the free seats", driverID, car.ID, 5, errs.ErrExceedsVehicle},
+		{"another user's vehicle", uuid.New(), car.ID, 2, errs.ErrVehicleNotOwned},
+		{"unknown vehicle", driverID, other, 2, errs.ErrVehicleNotOwned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewRouteService(routes, nil, vehicles, nil, nil, DefaultRankWeights, nil, nil)
+			_, err := svc.Create(context.Background(), tt.creatorID, domain.CreateRouteInput{
+				VehicleID: &tt.vehicleID, MaxPassengers: tt.seats,
+			})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Create() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteService_Update_ValidatesCapacity(t *testing.T) {
+	driverID := uuid.New()
+	car := &domain.Vehicle{ID: uuid.New(), UserID: driverID, Seats: 4}
+	route := activeRoute(driverID, 3)
+	route.VehicleID = &car.ID
+	svc := NewRouteService(&mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+		update:  func(_ context.Context, _, _ uuid.UUID, _ domain.UpdateRouteInput) error { return nil },
+	}, nil, &mockVehicleRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Vehicle, error) { return car, nil },
+	}, nil, nil, DefaultRankWeights, nil, nil)
+
+	for n, want := range map[uint]error{2: nil, 3: nil, 4: errs.ErrExceedsVehicle} {
+		err := svc.Update(context.Background(), route.ID, driverID, domain.UpdateRouteInput{MaxPassengers: &n})
+		if !errors.Is(err, want) {
+			t.Errorf("Update(max_passengers=%d) error = %v, want %v", n, err, want)
+		}
+	}
+
+	// Someone else learns nothing about the route's vehicle.
+	n := uint(4)
+	if err := svc.Update(context.Background(), route.ID, uuid.New(), domain.UpdateRouteInput{MaxPassengers: &n}); !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("Update(not the creator) error = %v, want ErrForbidden", err)
+	}
+}
+
+func TestRouteService_Update_KeepsReturnLegAfterOutbound(t *testing.T) {
+	driverID := uuid.New()
+	outbound, back := activeRoute(driverID, 3), activeRoute(driverID, 3)
+	outboundAt, backAt := time.Now().Add(24*time.Hour), time.Now().Add(32*time.Hour)
+	outbound.LeavingAt, back.LeavingAt = &outboundAt, &backAt
+	outbound.ReturnRouteID, back.ReturnOfRouteID = &back.ID, &outbound.ID
+	routes := map[uuid.UUID]*domain.Route{outbound.ID: outbound, back.ID: back}
+	svc := NewRouteService(&mockRouteRepo{
+		getByID: func(_ context.Context, id uuid.UUID) (*domain.Route, error) { return routes[id], nil },
+		update:  func(_ context.Context, _, _ uuid.UUID, _ domain.UpdateRouteInput) error { return nil },
+	}, nil, nil, nil, nil, DefaultRankWeights, nil, nil)
+
+	tests := []struct {
+		name      string
+		route     *domain.Route
+		leavingAt time.Time
+		wantErr   bool
+	}{
+		{"outbound earlier", outbound, outboundAt.Add(-time.Hour), false},
+		{"outbound after the return leg", outbound, backAt.Add(time.Hour), true},
+		{"return leg later", back, backAt.Add(time.Hour), false},
+		{"return leg before the outbound route", back, outboundAt.Add(-time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.Update(context.Background(), tt.route.ID, driverID, domain.UpdateRouteInput{LeavingAt: &tt.leavingAt})
+			if tt.wantErr != errors.Is(err, errs.ErrInvalidReturnLeg) || (!tt.wantErr && err != nil) {
+				t.Errorf("Update() error = %v, want ErrInvalidReturnLeg: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRouteService_Search_StablePages(t *testing.T) {
+	cheap, dearer, dearest := 5.0, 10.0, 100.0
+	route := func(price *float64) domain.Route {
+		return domain.Route{
+			ID: uuid.New(), CreatorID: uuid.New(), EndLng: 1,
+			MaxDeviation: 50, MaxPassengers: 4, AvailablePassengers: 4, Price: price,
+		}
+	}
+	a, b := route(&cheap), route(&dearer)
+	listed := []domain.Route{a, b}
+	svc := NewRouteService(&mockRouteRepo{
+		listSearchable: func(_ context.Context, _ domain.SearchRouteInput) ([]domain.Route, error) {
+			return listed, nil
+		},
+	}, &mockReviewRepo{
+		getAverageRatings: func(_ context.Context, _ []uuid.UUID) (map[uuid.UUID]domain.ReviewSummary, error) {
+			return map[uuid.UUID]domain.ReviewSummary{}, nil
+		},
+	}, nil, nil, nil, DefaultRankWeights, nil, nil)
+
+	in := domain.SearchRouteInput{EndLng: 1, Limit: 1}
+	page, err := svc.Search(context.Background(), in)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != a.ID || page.NextCursor == nil {
+		t.Fatalf("first page should hold only the cheaper route")
+	}
+
+	// Between pages a much pricier route is published and b takes bookings.
+	b.AvailablePassengers = 1
+	listed = []domain.Route{a, b, route(&dearest)}
+	in.Cursor = *page.NextCursor
+	if page, err = svc.Search(context.Background(), in); err != nil {
+		t.Fatalf("Search(next page) error = %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != b.ID {
+		t.Errorf("second page should continue with the dearer route")
+	}
+}
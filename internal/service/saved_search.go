@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+)
+
+// SavedSearchService manages saved searches and matches them against newly
+// published or updated routes.
+type SavedSearchService struct {
+	searches domain.SavedSearchRepository
+	routes   domain.RouteRepository
+	router   domain.Router
+}
+
+// NewSavedSearchService creates a SavedSearchService backed by the given repositories.
+// router is optional; like route search, matching measures deviation on the
+// road network when one is configured.
+func NewSavedSearchService(searches domain.SavedSearchRepository, routes domain.RouteRepository, router domain.Router) *SavedSearchService {
+	return &SavedSearchService{searches: searches, routes: routes, router: router}
+}
+
+// Create saves a search. Its departure window must end in the future, since
+// that is when the search stops matching.
+func (s *SavedSearchService) Create(ctx context.Context, userID uuid.UUID, query domain.SearchRouteInput) (uuid.UUID, error) {
+	if err := validateSearch(query); err != nil {
+		return uuid.Nil, err
+	}
+	if query.LeavingBefore == nil || !query.LeavingBefore.After(time.Now()) {
+		return uuid.Nil, fmt.Errorf("%w: leaving_before must be set and in the future", errs.ErrInvalidSearch)
+	}
+	return s.searches.Create(ctx, userID, query)
+}
+
+func (s *SavedSearchService) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.SavedSearch, error) {
+	return s.searches.ListByUser(ctx, userID)
+}
+
+func (s *SavedSearchService) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	return s.searches.Delete(ctx, id, userID)
+}
+
+// MatchRoute evaluates the saved searches against a route and queues an alert
+// for every search it newly matches. It is driven by route events, so a route
+// that has since been deleted, started or filled up is skipped.
+func (s *SavedSearchService) MatchRoute(ctx context.Context, routeID uuid.UUID) error {
+	route, err := s.routes.GetByID(ctx, routeID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("match route: load route: %w", err)
+	}
+	if routeStarted(route) || route.AvailablePassengers == 0 {
+		return nil
+	}
+
+	candidates, err := s.searches.ListMatchCandidates(ctx, route)
+	if err != nil {
+		return fmt.Errorf("match route: %w", err)
+	}
+	var matched []uuid.UUID
+	var checks []searchCandidate
+	for _, c := range candidates {
+		m, ok := savedSearchMatches(ctx, s.router, route, c)
+		if !ok {
+			continue
+		}
+		matched = append(matched, c.ID)
+		checks = append(checks, searchCandidate{route: route, search: c.Query, match: m})
+	}
+	for i, dev := range roadDeviations(ctx, s.router, checks) {
+		if dev > route.MaxDeviation {
+			continue
+		}
+		if err := s.searches.RecordMatch(ctx, matched[i], route.ID); err != nil {
+			return fmt.Errorf("match route: %w", err)
+		}
+	}
+	return nil
+}
+
+// savedSearchMatches applies the checks ListMatchCandidates leaves to the
+// service: participation, price, seats and, as route search does, matchSearch.
+// The deviation is then settled by roadDeviations.
+func savedSearchMatches(ctx context.Context, router domain.Router, r *domain.Route, ss domain.SavedSearch) (routeMatch, bool) {
+	for _, p := range r.Participants {
+		if p.UserID == ss.UserID {
+			return routeMatch{}, false
+		}
+	}
+	q := ss.Query
+	if q.MaxPrice != nil {
+		estimateFare(r, tripKm(ctx, router, q))
+		if routePrice(r) > *q.MaxPrice {
+			return routeMatch{}, false
+		}
+	}
+	if q.MinSeats != nil && r.AvailablePassengers < *q.MinSeats {
+		return routeMatch{}, false
+	}
+	return matchSearch(router, r, q)
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+)
+
+type mockSavedSearchRepo struct {
+	create              func(ctx context.Context, userID uuid.UUID, query domain.SearchRouteInput) (uuid.UUID, error)
+	listByUser          func(ctx context.Context, userID uuid.UUID) ([]domain.SavedSearch, error)
+	delete              func(ctx context.Context, id, userID uuid.UUID) error
+	listMatchCandidates func(ctx context.Context, route *domain.Route) ([]domain.SavedSearch, error)
+	recordMatch         func(ctx context.Context, savedSearchID, routeID uuid.UUID) error
+}
+
+func (m *mockSavedSearchRepo) Create(ctx context.Context, userID uuid.UUID, query domain.SearchRouteInput) (uuid.UUID, error) {
+	return m.create(ctx, userID, query)
+}
+func (m *mockSavedSearchRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.SavedSearch, error) {
+	return m.listByUser(ctx, userID)
+}
+func (m *mockSavedSearchRepo) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	return m.delete(ctx, id, userID)
+}
+func (m *mockSavedSearchRepo) ListMatchCandidates(ctx context.Context, route *domain.Route) ([]domain.SavedSearch, error) {
+	return m.listMatchCandidates(ctx, route)
+}
+func (m *mockSavedSearchRepo) RecordMatch(ctx context.Context, savedSearchID, routeID uuid.UUID) error {
+	return m.recordMatch(ctx, savedSearchID, routeID)
+}
+
+func TestSavedSearchService_Create_RequiresWindow(t *testing.T) {
+	repo := &mockSavedSearchRepo{
+		create: func(_ context.Context, _ uuid.UUID, _ domain.SearchRouteInput) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+	svc := NewSavedSearchService(repo, &mockRouteRepo{}, nil)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		query   domain.SearchRouteInput
+		wantErr bool
+	}{
+		{"no window end", domain.SearchRouteInput{}, true},
+		{"window already over", domain.SearchRouteInput{LeavingBefore: &past}, true},
+		{"invalid filters", domain.SearchRouteInput{LeavingAfter: futureTime(), LeavingBefore: &past}, true},
+		{"valid", domain.SearchRouteInput{LeavingBefore: futureTime()}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Create(context.Background(), uuid.New(), tt.query)
+			if tt.wantErr && !errors.Is(err, errs.ErrInvalidSearch) {
+				t.Errorf("Create() = %v, want ErrInvalidSearch", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Create() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestSavedSearchService_MatchRoute(t *testing.T) {
+	driverID, passengerID := uuid.New(), uuid.New()
+	price := 10.0
+	cheap := 5.0
+	route := activeRoute(driverID, 2)
+	route.EndLat, route.EndLng = 0, 1
+	route.MaxDeviation = 5
+	route.Price = &price
+
+	near := domain.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Query: domain.SearchRouteInput{EndLng: 1}}
+	far := domain.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Query: domain.SearchRouteInput{EndLat: 1, EndLng: 1}}
+	tooCheap := domain.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Query: domain.SearchRouteInput{EndLng: 1, MaxPrice: &cheap}}
+	joined := domain.SavedSearch{ID: uuid.New(), UserID: passengerID, Query: domain.SearchRouteInput{EndLng: 1}}
+	route.Participants = []domain.Participant{{UserID: passengerID, Status: "approved"}}
+
+	var recorded []uuid.UUID
+	repo := &mockSavedSearchRepo{
+		listMatchCandidates: func(_ context.Context, _ *domain.Route) ([]domain.SavedSearch, error) {
+			return []domain.SavedSearch{near, far, tooCheap, joined}, nil
+		},
+		recordMatch: func(_ context.Context, savedSearchID, routeID uuid.UUID) error {
+			if routeID != route.ID {
+				t.Errorf("RecordMatch() route = %v, want %v", routeID, route.ID)
+			}
+			recorded = append(recorded, savedSearchID)
+			return nil
+		},
+	}
+	routes := &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}
+
+	if err := NewSavedSearchService(repo, routes, nil).MatchRoute(context.Background(), route.ID); err != nil {
+		t.Fatalf("MatchRoute() error = %v", err)
+	}
+	if len(recorded) != 1 || recorded[0] != near.ID {
+		t.Errorf("MatchRoute() recorded %v, want only %v", recorded, near.ID)
+	}
+}
+
+func TestSavedSearchService_MatchRoute_RoadDeviation(t *testing.T) {
+	route := activeRoute(uuid.New(), 2)
+	route.EndLng, route.MaxDeviation = 1, 5
+
+	// Both searches follow the route in straight lines, but the stop is 50 km
+	// away by road.
+	onRoute := domain.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Query: domain.SearchRouteInput{EndLng: 1}}
+	acrossRiver := domain.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Query: domain.SearchRouteInput{
+		EndLng: 1, Stops: []domain.SearchStop{{Lat: 0.001, Lng: 0.5}},
+	}}
+	var recorded []uuid.UUID
+	repo := &mockSavedSearchRepo{
+		listMatchCandidates: func(_ context.Context, _ *domain.Route) ([]domain.SavedSearch, error) {
+			return []domain.SavedSearch{onRoute, acrossRiver}, nil
+		},
+		recordMatch: func(_ context.Context, savedSearchID, _ uuid.UUID) error {
+			recorded = append(recorded, savedSearchID)
+			return nil
+		},
+	}
+	routes := &mockRouteRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return route, nil },
+	}
+
+	if err := NewSavedSearchService(repo, routes, stubRouter{2: 0, 3: 50}).MatchRoute(context.Background(), route.ID); err != nil {
+		t.Fatalf("MatchRoute() error = %v", err)
+	}
+	if len(recorded) != 1 || recorded[0] != onRoute.ID {
+		t.Errorf("MatchRoute() recorded %v, want only %v", recorded, onRoute.ID)
+	}
+}
+
+func TestSavedSearchMatches_PerKmFare(t *testing.T) {
+	rate := 0.05
+	route := activeRoute(uuid.New(), 2)
+	route.EndLng, route.MaxDeviation = 1, 5
+	route.PricingMode, route.PricePerKm = domain.PricingPerKm, &rate
+
+	// The trip is about 111 km, so about 5.56.
+	for _, tt := range []struct {
+		maxPrice float64
+		want     bool
+	}{{10, true}, {5, false}} {
+		ss := domain.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Query: domain.SearchRouteInput{EndLng: 1, MaxPrice: &tt.maxPrice}}
+		if _, got := savedSearchMatches(context.Background(), nil, route, ss); got != tt.want {
+			t.Errorf("savedSearchMatches(max_price %v) = %v, want %v", tt.maxPrice, got, tt.want)
+		}
+	}
+}
+
+func TestSavedSearchService_MatchRoute_SkipsUnavailableRoutes(t *testing.T) {
+	repo := &mockSavedSearchRepo{
+		listMatchCandidates: func(_ context.Context, _ *domain.Route) ([]domain.SavedSearch, error) {
+			t.Fatal("ListMatchCandidates should not be called")
+			return nil, nil
+		},
+	}
+	tests := []struct {
+		name  string
+		route *domain.Route
+		err   error
+	}{
+		{"deleted", nil, errs.ErrNotFound},
+		{"started", startedRoute(uuid.New()), nil},
+		{"full", activeRoute(uuid.New(), 0), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := &mockRouteRepo{
+				getByID: func(_ context.Context, _ uuid.UUID) (*domain.Route, error) { return tt.route, tt.err },
+			}
+			if err := NewSavedSearchService(repo, routes, nil).MatchRoute(context.Background(), uuid.New()); err != nil {
+				t.Errorf("MatchRoute() error = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+	"github.com/jmartynas/pss-backend/internal/recurrence"
+)
+
+// ScheduleService manages recurring routes and materialises them into
+// concrete routes.
+type ScheduleService struct {
+	schedules domain.ScheduleRepository
+	routes    domain.RouteRepository
+	vehicles  domain.VehicleRepository
+	horizon   time.Duration
+	etas      *ETAEstimator
+}
+
+// NewScheduleService creates a ScheduleService that keeps instances
+// materialised horizon ahead of now. Templates are checked against vehicles
+// like new routes; etas, when non-nil, times their stops.
+func NewScheduleService(schedules domain.ScheduleRepository, routes domain.RouteRepository, vehicles domain.VehicleRepository, horizon time.Duration, etas *ETAEstimator) *ScheduleService {
+	return &ScheduleService{schedules: schedules, routes: routes, vehicles: vehicles, horizon: horizon, etas: etas}
+}
+
+func (s *ScheduleService) GetByID(ctx context.Context, id uuid.UUID) (*domain.RouteSchedule, error) {
+	return s.schedules.GetByID(ctx, id)
+}
+
+func (s *ScheduleService) ListByCreator(ctx context.Context, creatorID uuid.UUID) ([]domain.RouteSchedule, error) {
+	return s.schedules.ListByCreator(ctx, creatorID)
+}
+
+// Create stores a schedule and materialises its first instances straight
+// away. The first departure (template.leaving_at) must be in the future.
+func (s *ScheduleService) Create(ctx context.Context, creatorID uuid.UUID, in domain.CreateScheduleInput) (uuid.UUID, error) {
+	if in.Timezone == "" {
+		in.Timezone = "UTC"
+	}
+	if in.Template.Polyline != nil && *in.Template.Polyline == "" {
+		in.Template.Polyline = nil
+	}
+	if err := validateSchedule(&in); err != nil {
+		return uuid.Nil, err
+	}
+	if !in.Template.LeavingAt.After(time.Now()) {
+		return uuid.Nil, fmt.Errorf("%w: the first departure must be in the future", errs.ErrInvalidSchedule)
+	}
+	if err := checkVehicle(ctx, s.vehicles, creatorID, in.Template); err != nil {
+		return uuid.Nil, err
+	}
+	id, err := s.schedules.Create(ctx, creatorID, in)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	// A failure here is retried by the background job.
+	if sc, err := s.schedules.GetByID(ctx, id); err == nil {
+		_ = s.materialize(ctx, sc, time.Now())
+	}
+	return id, nil
+}
+
+// Update changes a schedule. Future instances nobody has applied to are
+// cancelled and regenerated from the new definition; booked ones are kept.
+func (s *ScheduleService) Update(ctx context.Context, id, creatorID uuid.UUID, in domain.UpdateScheduleInput) error {
+	sc, err := s.schedules.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sc.CreatorID != creatorID {
+		return errs.ErrForbidden
+	}
+
+	merged := domain.CreateScheduleInput{
+		Recurrence: sc.Recurrence,
+		Timezone:   sc.Timezone,
+		Template:   sc.Template,
+		EndsAt:     sc.EndsAt,
+	}
+	if in.Recurrence != nil {
+		merged.Recurrence = *in.Recurrence
+	}
+	if in.Timezone != nil {
+		merged.Timezone = *in.Timezone
+	}
+	if in.EndsAt != nil {
+		merged.EndsAt = *in.EndsAt
+	}
+	if in.Template != nil {
+		merged.Template = *in.Template
+		if merged.Template.LeavingAt == nil {
+			merged.Template.LeavingAt = sc.Template.LeavingAt
+		}
+		if merged.Template.Polyline != nil && *merged.Template.Polyline == "" {
+			merged.Template.Polyline = nil
+		}
+	}
+	if err := validateSchedule(&merged); err != nil {
+		return err
+	}
+	if err := checkVehicle(ctx, s.vehicles, creatorID, merged.Template); err != nil {
+		return err
+	}
+
+	if err := s.schedules.Update(ctx, id, creatorID, merged); err != nil {
+		return err
+	}
+	// A failure here is retried by the background job.
+	if sc, err := s.schedules.GetByID(ctx, id); err == nil {
+		_ = s.materialize(ctx, sc, time.Now())
+	}
+	return nil
+}
+
+// Delete cancels a schedule and its future instances nobody has applied to.
+func (s *ScheduleService) Delete(ctx context.Context, id, creatorID uuid.UUID) error {
+	return s.schedules.Delete(ctx, id, creatorID)
+}
+
+// Materialize creates the missing instances of every schedule up to the
+// horizon. It keeps going when one schedule fails and returns all errors.
+func (s *ScheduleService) Materialize(ctx context.Context, now time.Time) error {
+	due, err := s.schedules.ListDue(ctx, now.Add(s.horizon))
+	if err != nil {
+		return fmt.Errorf("materialize schedules: %w", err)
+	}
+	var errList []error
+	for i := range due {
+		if err := s.materialize(ctx, &due[i], now); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	return errors.Join(errList...)
+}
+
+func (s *ScheduleService) materialize(ctx context.Context, sc *domain.RouteSchedule, now time.Time) error {
+	rule, err := recurrence.Parse(sc.Recurrence)
+	if err != nil {
+		return fmt.Errorf("materialize schedule %s: %w", sc.ID, err)
+	}
+	loc, err := time.LoadLocation(sc.Timezone)
+	if err != nil {
+		return fmt.Errorf("materialize schedule %s: %w", sc.ID, err)
+	}
+	if sc.Template.LeavingAt == nil {
+		return fmt.Errorf("materialize schedule %s: template has no leaving_at", sc.ID)
+	}
+
+	horizon := now.Add(s.horizon)
+	after := now
+	if sc.MaterializedUntil != nil && sc.MaterializedUntil.After(now) {
+		after = *sc.MaterializedUntil
+	}
+	until := horizon
+	if sc.EndsAt.Before(until) {
+		until = sc.EndsAt
+	}
+	for _, at := range rule.Between(sc.Template.LeavingAt.In(loc), after, until) {
+		in := sc.Template
+		leavingAt := at
+		in.LeavingAt = &leavingAt
+		in.ScheduleID = &sc.ID
+		if in.Return != nil && in.Return.LeavingAt != nil {
+			ret := *in.Return
+			back := returnDeparture(*sc.Template.LeavingAt, *ret.LeavingAt, at, loc)
+			ret.LeavingAt = &back
+			in.Return = &ret
+		}
+		// ErrConflict: the instance exists already, or the driver cancelled it.
+		id, err := s.routes.Create(ctx, sc.CreatorID, in)
+		if errors.Is(err, errs.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("materialize schedule %s: %w", sc.ID, err)
+		}
+		s.etas.Created(ctx, id)
+	}
+	if err := s.schedules.SetMaterializedUntil(ctx, sc.ID, horizon); err != nil {
+		return fmt.Errorf("materialize schedule %s: %w", sc.ID, err)
+	}
+	return nil
+}
+
+// returnDeparture is when the return leg of the instance leaving at departs:
+// as many days after it, at the same wall-clock time in loc, as the template's
+// return leg departs after the template. Adding the template's offset as a
+// duration would move it by an hour across a DST change.
+func returnDeparture(template, templateReturn, at time.Time, loc *time.Location) time.Time {
+	template, templateReturn, at = template.In(loc), templateReturn.In(loc), at.In(loc)
+	days := calendarDay(templateReturn).Sub(calendarDay(template)) / (24 * time.Hour)
+	return time.Date(at.Year(), at.Month(), at.Day()+int(days),
+		templateReturn.Hour(), templateReturn.Minute(), templateReturn.Second(), templateReturn.Nanosecond(), loc)
+}
+
+// calendarDay returns t's date as midnight UTC, so dates subtract in whole days.
+func calendarDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// validateSchedule checks a complete schedule definition and normalises its
+// template's return leg.
+func validateSchedule(in *domain.CreateScheduleInput) error {
+	if _, err := recurrence.Parse(in.Recurrence); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrInvalidSchedule, err)
+	}
+	if _, err := time.LoadLocation(in.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", errs.ErrInvalidSchedule, in.Timezone)
+	}
+	if in.Template.LeavingAt == nil {
+		return fmt.Errorf("%w: template.leaving_at (the first departure) is required", errs.ErrInvalidSchedule)
+	}
+	if !in.EndsAt.After(*in.Template.LeavingAt) {
+		return fmt.Errorf("%w: ends_at must be after the first departure", errs.ErrInvalidSchedule)
+	}
+	if in.Template.Polyline != nil {
+		start := domain.LatLng{Lat: in.Template.StartLat, Lng: in.Template.StartLng}
+		end := domain.LatLng{Lat: in.Template.EndLat, Lng: in.Template.EndLng}
+		if err := validatePolyline(*in.Template.Polyline, start, end); err != nil {
+			return err
+		}
+	}
+	if err := validatePricing(in.Template.PricingMode, in.Template.PricePerKm); err != nil {
+		return err
+	}
+	if err := validateAutoApprove(in.Template.AutoApproveMinRating); err != nil {
+		return err
+	}
+	if in.Template.Return != nil {
+		return validateReturnLeg(&in.Template)
+	}
+	return nil
+}
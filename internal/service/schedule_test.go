@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmartynas/pss-backend/internal/domain"
+	"github.com/jmartynas/pss-backend/internal/errs"
+)
+
+type mockScheduleRepo struct {
+	getByID              func(ctx context.Context, id uuid.UUID) (*domain.RouteSchedule, error)
+	create               func(ctx context.Context, creatorID uuid.UUID, in domain.CreateScheduleInput) (uuid.UUID, error)
+	update               func(ctx context.Context, id, creatorID uuid.UUID, in domain.CreateScheduleInput) error
+	delete               func(ctx context.Context, id, creatorID uuid.UUID) error
+	listByCreator        func(ctx context.Context, creatorID uuid.UUID) ([]domain.RouteSchedule, error)
+	listDue              func(ctx context.Context, horizon time.Time) ([]domain.RouteSchedule, error)
+	setMaterializedUntil func(ctx context.Context, id uuid.UUID, until time.Time) error
+}
+
+func (m *mockScheduleRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.RouteSchedule, error) {
+	return m.getByID(ctx, id)
+}
+func (m *mockScheduleRepo) Create(ctx context.Context, creatorID uuid.UUID, in domain.CreateScheduleInput) (uuid.UUID, error) {
+	return m.create(ctx, creatorID, in)
+}
+func (m *mockScheduleRepo) Update(ctx context.Context, id, creatorID uuid.UUID, in domain.CreateScheduleInput) error {
+	return m.update(ctx, id, creatorID, in)
+}
+func (m *mockScheduleRepo) Delete(ctx context.Context, id, creatorID uuid.UUID) error {
+	return m.delete(ctx, id, creatorID)
+}
+func (m *mockScheduleRepo) ListByCreator(ctx context.Context, creatorID uuid.UUID) ([]domain.RouteSchedule, error) {
+	return m.listByCreator(ctx, creatorID)
+}
+func (m *mockScheduleRepo) ListDue(ctx context.Context, horizon time.Time) ([]domain.RouteSchedule, error) {
+	return m.listDue(ctx, horizon)
+}
+func (m *mockScheduleRepo) SetMaterializedUntil(ctx context.Context, id uuid.UUID, until time.Time) error {
+	return m.setMaterializedUntil(ctx, id, until)
+}
+
+func TestValidateSchedule(t *testing.T) {
+	first := time.Now().Add(time.Hour)
+	valid := domain.CreateScheduleInput{
+		Recurrence: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		Timezone:   "UTC",
+		Template:   domain.CreateRouteInput{LeavingAt: &first},
+		EndsAt:     first.AddDate(0, 1, 0),
+	}
+	tests := []struct {
+		name   string
+		modify func(in *domain.CreateScheduleInput)
+	}{
+		{"bad recurrence", func(in *domain.CreateScheduleInput) { in.Recurrence = "FREQ=YEARLY" }},
+		{"unknown timezone", func(in *domain.CreateScheduleInput) { in.Timezone = "Mars/Olympus" }},
+		{"no first departure", func(in *domain.CreateScheduleInput) { in.Template.LeavingAt = nil }},
+		{"ends before first departure", func(in *domain.CreateScheduleInput) { in.EndsAt = first.Add(-time.Minute) }},
+	}
+	if err := validateSchedule(&valid); err != nil {
+		t.Fatalf("validateSchedule(valid) = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := valid
+			tt.modify(&in)
+			if err := validateSchedule(&in); !errors.Is(err, errs.ErrInvalidSchedule) {
+				t.Errorf("validateSchedule() = %v, want ErrInvalidSchedule", err)
+			}
+		})
+	}
+}
+
+func TestValidateSchedule_ClearsEmptyReturnPolyline(t *testing.T) {
+	first := time.Now().Add(time.Hour)
+	back := first.Add(8 * time.Hour)
+	in := domain.CreateScheduleInput{
+		Recurrence: "FREQ=DAILY",
+		Timezone:   "UTC",
+		Template: domain.CreateRouteInput{
+			LeavingAt: &first,
+			Return:    &domain.ReturnLegInput{LeavingAt: &back, Polyline: strPtr("")},
+		},
+		EndsAt: first.AddDate(0, 1, 0),
+	}
+	if err := validateSchedule(&in); err != nil {
+		t.Fatalf("validateSchedule() = %v", err)
+	}
+	if in.Template.Return.Polyline != nil {
+		t.Errorf("return polyline = %q, want nil", *in.Template.Return.Polyline)
+	}
+}
+
+func TestReturnDeparture_KeepsWallClockAcrossDST(t *testing.T) {
+	vilnius, err := time.LoadLocation("Europe/Vilnius")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	// DST starts on Sunday 2026-03-29.
+	template := time.Date(2026, 3, 26, 7, 30, 0, 0, vilnius)
+	templateReturn := time.Date(2026, 3, 27, 17, 0, 0, 0, vilnius)
+	at := time.Date(2026, 3, 30, 7, 30, 0, 0, vilnius)
+
+	got := returnDeparture(template, templateReturn, at, vilnius)
+	if want := time.Date(2026, 3, 31, 17, 0, 0, 0, vilnius); !got.Equal(want) {
+		t.Errorf("returnDeparture() = %v, want %v", got, want)
+	}
+}
+
+func TestScheduleService_Materialize(t *testing.T) {
+	now := time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)   // Monday
+	first := time.Date(2026, 5, 1, 7, 30, 0, 0, time.UTC) // Friday
+	until := now.Add(48 * time.Hour)
+	sc := domain.RouteSchedule{
+		ID:                uuid.New(),
+		CreatorID:         uuid.New(),
+		Recurrence:        "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		Timezone:          "UTC",
+		Template:          domain.CreateRouteInput{LeavingAt: &first, MaxPassengers: 3},
+		EndsAt:            first.AddDate(1, 0, 0),
+		MaterializedUntil: nil,
+	}
+
+	var created []time.Time
+	var materializedUntil time.Time
+	repo := &mockScheduleRepo{
+		listDue: func(_ context.Context, horizon time.Time) ([]domain.RouteSchedule, error) {
+			if !horizon.Equal(until) {
+				t.Errorf("ListDue() horizon = %v, want %v", horizon, until)
+			}
+			return []domain.RouteSchedule{sc}, nil
+		},
+		setMaterializedUntil: func(_ context.Context, id uuid.UUID, at time.Time) error {
+			materializedUntil = at
+			return nil
+		},
+	}
+	routes := &mockRouteRepo{
+		create: func(_ context.Context, creatorID uuid.UUID, in domain.CreateRouteInput) (uuid.UUID, error) {
+			if creatorID != sc.CreatorID || in.ScheduleID == nil || *in.ScheduleID != sc.ID || in.MaxPassengers != 3 {
+				t.Errorf("Create() got creator %v, input %+v", creatorID, in)
+			}
+			created = append(created, *in.LeavingAt)
+			if in.LeavingAt.Weekday() == time.Wednesday {
+				return uuid.Nil, errs.ErrConflict // already materialised
+			}
+			return uuid.New(), nil
+		},
+	}
+
+	svc := NewScheduleService(repo, routes, nil, 48*time.Hour, nil)
+	if err := svc.Materialize(context.Background(), now); err != nil {
+		t.Fatalf("Materialize() error = %v", err)
+	}
+	// Monday 07:30 is already past; Tuesday and Wednesday fall in the horizon.
+	want := []time.Time{
+		time.Date(2026, 5, 5, 7, 30, 0, 0, time.UTC),
+		time.Date(2026, 5, 6, 7, 30, 0, 0, time.UTC),
+	}
+	if len(created) != len(want) {
+		t.Fatalf("Materialize() created %v, want %v", created, want)
+	}
+	for i := range want {
+		if !created[i].Equal(want[i]) {
+			t.Errorf("Materialize() created %v, want %v", created, want)
+		}
+	}
+	if !materializedUntil.Equal(until) {
+		t.Errorf("materialized_until = %v, want %v", materializedUntil, until)
+	}
+}
+
+func TestScheduleService_Update_KeepsFirstDeparture(t *testing.T) {
+	creatorID := uuid.New()
+	first := time.Now().Add(-24 * time.Hour)
+	sc := &domain.RouteSchedule{
+		ID:         uuid.New(),
+		CreatorID:  creatorID,
+		Recurrence: "FREQ=DAILY",
+		Timezone:   "UTC",
+		Template:   domain.CreateRouteInput{LeavingAt: &first, MaxPassengers: 2},
+		EndsAt:     first.AddDate(0, 1, 0),
+	}
+	var stored domain.CreateScheduleInput
+	repo := &mockScheduleRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.RouteSchedule, error) { return sc, nil },
+		update: func(_ context.Context, _, _ uuid.UUID, in domain.CreateScheduleInput) error {
+			stored = in
+			return nil
+		},
+		setMaterializedUntil: func(_ context.Context, _ uuid.UUID, _ time.Time) error { return nil },
+	}
+	routes := &mockRouteRepo{
+		create: func(_ context.Context, _ uuid.UUID, _ domain.CreateRouteInput) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+	svc := NewScheduleService(repo, routes, nil, 0, nil)
+
+	if err := svc.Update(context.Background(), sc.ID, uuid.New(), domain.UpdateScheduleInput{}); !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("Update() by another user = %v, want ErrForbidden", err)
+	}
+
+	template := domain.CreateRouteInput{MaxPassengers: 4}
+	if err := svc.Update(context.Background(), sc.ID, creatorID, domain.UpdateScheduleInput{Template: &template}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if stored.Template.MaxPassengers != 4 || stored.Template.LeavingAt == nil || !stored.Template.LeavingAt.Equal(first) {
+		t.Errorf("Update() stored template %+v, want max_passengers 4 and the original first departure", stored.Template)
+	}
+	if stored.Recurrence != "FREQ=DAILY" {
+		t.Errorf("Update() stored recurrence %q, want unchanged", stored.Recurrence)
+	}
+}
+
+func TestScheduleService_ValidatesVehicle(t *testing.T) {
+	driverID := uuid.New()
+	car := &domain.Vehicle{ID: uuid.New(), UserID: driverID, Seats: 5}
+	other := &domain.Vehicle{ID: uuid.New(), UserID: uuid.New(), Seats: 5}
+	vehicles := &mockVehicleRepo{
+		getByID: func(_ context.Context, id uuid.UUID) (*domain.Vehicle, error) {
+			if id == car.ID {
+				return car, nil
+			}
+			return other, nil
+		},
+	}
+	first := time.Now().Add(time.Hour)
+	sc := &domain.RouteSchedule{
+		ID: uuid.New(), CreatorID: driverID,
+		Recurrence: "FREQ=DAILY", Timezone: "UTC",
+		Template: domain.CreateRouteInput{LeavingAt: &first, MaxPassengers: 2, VehicleID: &car.ID},
+		EndsAt:   first.AddDate(0, 1, 0),
+	}
+	repo := &mockScheduleRepo{
+		getByID: func(_ context.Context, _ uuid.UUID) (*domain.RouteSchedule, error) { return sc, nil },
+		create: func(_ context.Context, _ uuid.UUID, _ domain.CreateScheduleInput) (uuid.UUID, error) {
+			t.Error("Create() should not store an invalid template")
+			return uuid.Nil, nil
+		},
+		update: func(_ context.Context, _, _ uuid.UUID, _ domain.CreateScheduleInput) error {
+			t.Error("Update() should not store an invalid template")
+			return nil
+		},
+	}
+	svc := NewScheduleService(repo, &mockRouteRepo{}, vehicles, 0, nil)
+
+	tests := []struct {
+		name     string
+		template domain.CreateRouteInput
+		wantErr  error
+	}{
+		{"another user's vehicle", domain.CreateRouteInput{LeavingAt: &first, MaxPassengers: 2, VehicleID: &other.ID}, errs.ErrVehicleNotOwned},
+		{"more passengers than seats", domain.CreateRouteInput{LeavingAt: &first, MaxPassengers: 5, VehicleID: &car.ID}, errs.ErrExceedsVehicle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := domain.CreateScheduleInput{Recurrence: sc.Recurrence, Timezone: sc.Timezone, Template: tt.template, EndsAt: sc.EndsAt}
+			if _, err := svc.Create(context.Background(), driverID, in); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Create() = %v, want %v", err, tt.wantErr)
+			}
+			template := tt.template
+			if err := svc.Update(context.Background(), sc.ID, driverID, domain.UpdateScheduleInput{Template: &template}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Update() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}